@@ -0,0 +1,258 @@
+// Package activitypub implements the parts of ActivityPub, WebFinger and
+// HTTP Signatures Chirpy needs to federate with other fediverse servers.
+package activitypub
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentType is the media type of ActivityPub documents.
+	ContentType = "application/activity+json"
+	// ldContentType is the other media type servers send and accept for
+	// ActivityPub documents.
+	ldContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+	// JRDContentType is the media type of WebFinger responses.
+	JRDContentType = "application/jrd+json"
+
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+var (
+	ErrNotActor  = errors.New("activitypub: document isn't an actor")
+	ErrNoKey     = errors.New("activitypub: actor has no public key")
+	ErrKeyOwner  = errors.New("activitypub: key doesn't belong to actor")
+	ErrBadScheme = errors.New("activitypub: only https URLs are allowed")
+	// ErrPrivateAddress is returned for servers on loopback, private or
+	// link-local addresses.
+	ErrPrivateAddress   = errors.New("activitypub: only public addresses are allowed")
+	ErrTooManyRedirects = errors.New("activitypub: too many redirects")
+)
+
+// Context is the JSON-LD context of every document Chirpy serves.
+var Context = []any{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	Summary           string     `json:"summary,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	PublicKey         *PublicKey `json:"publicKey,omitempty"`
+	Published         *time.Time `json:"published,omitempty"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// SharedInbox returns the actor's shared inbox, if it has one, so one
+// delivery reaches all of its server's recipients.
+func (a Actor) SharedInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+// Activity is an incoming or outgoing activity. Object is kept raw because
+// it may be a link or an embedded object.
+type Activity struct {
+	Context any             `json:"@context,omitempty"`
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Actor   string          `json:"actor"`
+	Object  json.RawMessage `json:"object,omitempty"`
+	To      Audience        `json:"to,omitempty"`
+	Cc      Audience        `json:"cc,omitempty"`
+}
+
+// ObjectID returns the ID of the activity's object, whether the object is
+// a link or embedded.
+func (a Activity) ObjectID() string {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(a.Object, &object)
+	return object.ID
+}
+
+// ObjectType returns the type of an embedded object, or "" for a link.
+func (a Activity) ObjectType() string {
+	var object struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(a.Object, &object)
+	return object.Type
+}
+
+type Note struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	AttributedTo string     `json:"attributedTo"`
+	Content      string     `json:"content"`
+	URL          string     `json:"url,omitempty"`
+	InReplyTo    string     `json:"inReplyTo,omitempty"`
+	Published    time.Time  `json:"published"`
+	Updated      *time.Time `json:"updated,omitempty"`
+	To           Audience   `json:"to,omitempty"`
+	Cc           Audience   `json:"cc,omitempty"`
+}
+
+// Audience is a list of recipients, which other servers may send as a
+// single string.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var list []string
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+type Tombstone struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int64  `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// JRD is a WebFinger response.
+type JRD struct {
+	Subject string    `json:"subject"`
+	Aliases []string  `json:"aliases,omitempty"`
+	Links   []JRDLink `json:"links"`
+}
+
+type JRDLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// HTMLContent turns a plain text chirp into the HTML content of a Note.
+func HTMLContent(text string) string {
+	paragraphs := strings.Split(html.EscapeString(text), "\n\n")
+	for i, paragraph := range paragraphs {
+		paragraphs[i] = "<p>" + strings.ReplaceAll(paragraph, "\n", "<br>") + "</p>"
+	}
+	return strings.Join(paragraphs, "")
+}
+
+var (
+	lineBreakTags = regexp.MustCompile(`(?i)<br\s*/?>|</p>\s*<p[^>]*>`)
+	tags          = regexp.MustCompile(`<[^>]*>`)
+)
+
+// PlainText turns the HTML content of a remote Note into plain text, so it
+// can be stored and shown like a chirp without trusting its markup.
+func PlainText(content string) string {
+	content = lineBreakTags.ReplaceAllStringFunc(content, func(tag string) string {
+		if strings.HasPrefix(strings.ToLower(tag), "<br") {
+			return "\n"
+		}
+		return "\n\n"
+	})
+	content = tags.ReplaceAllString(content, "")
+	return strings.TrimSpace(html.UnescapeString(content))
+}
+
+// GenerateKey creates the RSA key pair an actor signs its requests with,
+// PEM encoded.
+func GenerateKey() (privateKeyPEM, publicKeyPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privateKeyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	publicKeyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	return privateKeyPEM, publicKeyPEM, nil
+}
+
+func ParsePrivateKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("activitypub: invalid private key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("activitypub: private key isn't RSA")
+	}
+	return rsaKey, nil
+}
+
+// ParsePublicKey reads an actor's publicKeyPem, which other servers encode
+// either as PKIX or PKCS #1.
+func ParsePublicKey(publicKeyPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, errors.New("activitypub: invalid public key PEM")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("activitypub: public key isn't RSA")
+	}
+	return rsaKey, nil
+}
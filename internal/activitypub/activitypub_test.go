@@ -0,0 +1,79 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHTMLContent(t *testing.T) {
+	got := HTMLContent("first <line>\nsecond\n\nnext & last")
+	want := "<p>first &lt;line&gt;<br>second</p><p>next &amp; last</p>"
+	if got != want {
+		t.Errorf("HTMLContent() = %q, want %q", got, want)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "Paragraphs", content: "<p>hello</p><p>world</p>", want: "hello\n\nworld"},
+		{name: "Line breaks", content: "<p>one<br>two<br />three</p>", want: "one\ntwo\nthree"},
+		{name: "Mention", content: `<p><span class="h-card"><a href="https://chirpy.example/users/x">@<span>x</span></a></span> hi</p>`, want: "@x hi"},
+		{name: "Entities", content: "<p>fish &amp; chips &lt;3</p>", want: "fish & chips <3"},
+		{name: "Script", content: `<script>alert(1)</script>ok`, want: "alert(1)ok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(tt.content); got != tt.want {
+				t.Errorf("PlainText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActivityObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		activity string
+		wantID   string
+		wantType string
+	}{
+		{name: "Link", activity: `{"object":"https://remote.example/notes/1"}`, wantID: "https://remote.example/notes/1", wantType: ""},
+		{name: "Embedded", activity: `{"object":{"id":"https://remote.example/notes/1","type":"Note"}}`, wantID: "https://remote.example/notes/1", wantType: "Note"},
+		{name: "Missing", activity: `{}`, wantID: "", wantType: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activity := Activity{}
+			err := json.Unmarshal([]byte(tt.activity), &activity)
+			if err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if got := activity.ObjectID(); got != tt.wantID {
+				t.Errorf("ObjectID() = %q, want %q", got, tt.wantID)
+			}
+			if got := activity.ObjectType(); got != tt.wantType {
+				t.Errorf("ObjectType() = %q, want %q", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestAudience(t *testing.T) {
+	note := Note{}
+	err := json.Unmarshal([]byte(`{"to":"https://chirpy.example/users/a","cc":["https://www.w3.org/ns/activitystreams#Public"]}`), &note)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(note.To) != 1 || note.To[0] != "https://chirpy.example/users/a" {
+		t.Errorf("To = %v, want the single string recipient", note.To)
+	}
+	if len(note.Cc) != 1 || note.Cc[0] != Public {
+		t.Errorf("Cc = %v, want [%s]", note.Cc, Public)
+	}
+}
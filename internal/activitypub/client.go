@@ -0,0 +1,225 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	// maxDocumentSize bounds the documents read from other servers.
+	maxDocumentSize = 1 << 20
+	maxRedirects    = 5
+)
+
+// sharedAddressSpace is carrier-grade NAT space, which isn't covered by
+// netip.Addr.IsPrivate but isn't reachable from the internet either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicTransport refuses to connect to addresses that aren't public. The
+// check runs on the address actually dialed, after DNS resolution and on
+// every redirect, so a host name resolving to an internal address doesn't
+// get through. It doesn't use a proxy, which would do the dialing itself.
+var publicTransport = func() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, conn syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}()
+
+// publicAddress reports whether addr is a unicast address on the
+// internet, rather than loopback, private, link-local or otherwise
+// internal.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// StatusError is returned when another server answers with a non-2xx
+// status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("activitypub: %s responded with %d", e.URL, e.StatusCode)
+}
+
+// Permanent reports whether retrying the request is pointless, as it is
+// for client errors other than rate limiting.
+func (e *StatusError) Permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 && e.StatusCode != http.StatusTooManyRequests
+}
+
+// Client fetches documents from and delivers activities to other servers.
+// The URLs it's given come from other servers, or from anyone sending a
+// signed request, so it only talks to public https servers unless told
+// otherwise.
+type Client struct {
+	// AllowHTTP permits plain http URLs, for local development and tests.
+	AllowHTTP bool
+	// AllowPrivateAddresses permits servers on loopback, private and
+	// link-local addresses, for local development and tests.
+	AllowPrivateAddresses bool
+	UserAgent             string
+}
+
+// FetchActor fetches the actor document at id.
+func (c *Client) FetchActor(ctx context.Context, id string) (Actor, error) {
+	actor := Actor{}
+	err := c.getJSON(ctx, id, &actor)
+	if err != nil {
+		return Actor{}, err
+	}
+	return actor, actor.check(id)
+}
+
+// FetchKeyOwner fetches the actor that signs with keyID. Most servers
+// serve the key inside the actor document, but some give keys their own
+// document pointing at the owner.
+func (c *Client) FetchKeyOwner(ctx context.Context, keyID string) (Actor, error) {
+	documentID := Signature{KeyID: keyID}.ActorID()
+	var document struct {
+		Actor
+		Owner string `json:"owner"`
+	}
+	err := c.getJSON(ctx, documentID, &document)
+	if err != nil {
+		return Actor{}, err
+	}
+
+	actor := document.Actor
+	if document.Owner != "" {
+		actor, err = c.FetchActor(ctx, document.Owner)
+	} else {
+		err = actor.check(documentID)
+	}
+	if err != nil {
+		return Actor{}, err
+	}
+	if actor.PublicKey.ID != keyID {
+		return Actor{}, ErrKeyOwner
+	}
+	return actor, nil
+}
+
+// check makes sure a fetched document is the actor it was fetched as, so a
+// server can't pass off another server's actor.
+func (a Actor) check(id string) error {
+	if a.ID != id || a.Inbox == "" {
+		return ErrNotActor
+	}
+	if a.PublicKey == nil || a.PublicKey.PublicKeyPem == "" {
+		return ErrNoKey
+	}
+	return nil
+}
+
+// Deliver posts activity to inbox, signed with the sending actor's key.
+func (c *Client) Deliver(ctx context.Context, inbox string, activity []byte, keyID string, key *rsa.PrivateKey) error {
+	err := c.checkURL(inbox)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(activity))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("Accept", ContentType)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	err = Sign(req, activity, keyID, key)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDocumentSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: inbox, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+func (c *Client) getJSON(ctx context.Context, target string, v any) error {
+	err := c.checkURL(target)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", ContentType+", "+ldContentType)
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{URL: target, StatusCode: resp.StatusCode}
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(v)
+}
+
+func (c *Client) checkURL(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "https" || (c.AllowHTTP && u.Scheme == "http") {
+		return nil
+	}
+	return ErrBadScheme
+}
+
+func (c *Client) httpClient() *http.Client {
+	transport := http.DefaultTransport
+	if !c.AllowPrivateAddresses {
+		transport = publicTransport
+	}
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyRedirects
+			}
+			return c.checkURL(req.URL.String())
+		},
+	}
+}
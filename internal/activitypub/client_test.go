@@ -0,0 +1,249 @@
+package activitypub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// peer is a minimal in-process fediverse server with a single actor. Its
+// inbox verifies signatures by fetching the sender's actor, the way Chirpy
+// and other servers do, and records the activities it accepts.
+type peer struct {
+	t      *testing.T
+	server *httptest.Server
+	client *Client
+
+	actor      Actor
+	privateKey string
+	received   []Activity
+}
+
+func newPeer(t *testing.T, name string) *peer {
+	t.Helper()
+	p := &peer{t: t, client: &Client{AllowHTTP: true, AllowPrivateAddresses: true}}
+
+	privateKeyPEM, publicKeyPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	p.privateKey = privateKeyPEM
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/"+name, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(p.actor)
+	})
+	mux.HandleFunc("POST /users/"+name+"/inbox", p.inbox)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	actorID := p.server.URL + "/users/" + name
+	p.actor = Actor{
+		Context:           Context,
+		ID:                actorID,
+		Type:              "Person",
+		PreferredUsername: name,
+		Inbox:             actorID + "/inbox",
+		PublicKey: &PublicKey{
+			ID:           actorID + "#main-key",
+			Owner:        actorID,
+			PublicKeyPem: publicKeyPEM,
+		},
+	}
+	return p
+}
+
+func (p *peer) inbox(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	activity := Activity{}
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sig, err := ParseSignature(r.Header)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	sender, err := p.client.FetchKeyOwner(r.Context(), sig.KeyID)
+	if err != nil || sender.ID != activity.Actor {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	publicKey, err := ParsePublicKey(sender.PublicKey.PublicKeyPem)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	err = sig.Verify(r, body, publicKey)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	p.received = append(p.received, activity)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// send delivers activity from p to the inbox of to.
+func (p *peer) send(to *peer, activity Activity) error {
+	p.t.Helper()
+	privateKey, err := ParsePrivateKey(p.privateKey)
+	if err != nil {
+		p.t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+	raw, err := json.Marshal(activity)
+	if err != nil {
+		p.t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return p.client.Deliver(context.Background(), to.actor.Inbox, raw, p.actor.PublicKey.ID, privateKey)
+}
+
+func TestFollowBetweenPeers(t *testing.T) {
+	alice := newPeer(t, "alice")
+	bob := newPeer(t, "bob")
+
+	object, _ := json.Marshal(bob.actor.ID)
+	follow := Activity{ID: alice.actor.ID + "#follows/1", Type: "Follow", Actor: alice.actor.ID, Object: object}
+	err := alice.send(bob, follow)
+	if err != nil {
+		t.Fatalf("Deliver() Follow error = %v", err)
+	}
+	if len(bob.received) != 1 || bob.received[0].ObjectID() != bob.actor.ID {
+		t.Fatalf("bob received %+v, want alice's Follow", bob.received)
+	}
+
+	embedded, _ := json.Marshal(follow)
+	accept := Activity{ID: bob.actor.ID + "#accepts/1", Type: "Accept", Actor: bob.actor.ID, Object: embedded}
+	err = bob.send(alice, accept)
+	if err != nil {
+		t.Fatalf("Deliver() Accept error = %v", err)
+	}
+	if len(alice.received) != 1 || alice.received[0].ObjectID() != follow.ID || alice.received[0].ObjectType() != "Follow" {
+		t.Fatalf("alice received %+v, want bob's Accept of her Follow", alice.received)
+	}
+}
+
+func TestDeliverRejectsImpersonation(t *testing.T) {
+	alice := newPeer(t, "alice")
+	bob := newPeer(t, "bob")
+	mallory := newPeer(t, "mallory")
+
+	// Mallory signs with her own key but claims alice's key ID.
+	mallory.actor.PublicKey.ID = alice.actor.PublicKey.ID
+	object, _ := json.Marshal(bob.actor.ID)
+	err := mallory.send(bob, Activity{ID: "forged", Type: "Follow", Actor: alice.actor.ID, Object: object})
+
+	statusErr := &StatusError{}
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Deliver() error = %v, want a 401", err)
+	}
+	if !statusErr.Permanent() {
+		t.Errorf("Permanent() = false for a 401, want true")
+	}
+	if len(bob.received) != 0 {
+		t.Errorf("bob accepted %+v from an impersonator", bob.received)
+	}
+}
+
+func TestFetchActorErrors(t *testing.T) {
+	alice := newPeer(t, "alice")
+
+	_, err := (&Client{}).FetchActor(context.Background(), alice.actor.ID)
+	if !errors.Is(err, ErrBadScheme) {
+		t.Errorf("FetchActor() over http error = %v, want %v", err, ErrBadScheme)
+	}
+
+	// A server can't serve another server's actor as its own.
+	alice.actor.ID = "https://elsewhere.example/users/alice"
+	_, err = alice.client.FetchActor(context.Background(), alice.server.URL+"/users/alice")
+	if !errors.Is(err, ErrNotActor) {
+		t.Errorf("FetchActor() error = %v, want %v", err, ErrNotActor)
+	}
+
+	_, err = alice.client.FetchKeyOwner(context.Background(), alice.server.URL+"/users/alice#other-key")
+	if !errors.Is(err, ErrNotActor) {
+		t.Errorf("FetchKeyOwner() error = %v, want %v", err, ErrNotActor)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.215.14", want: true},
+		{addr: "2606:2800:21f:cb07:6820:80da:af6b:8b2c", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "100.64.0.1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "fd00::1"},
+		{addr: "0.0.0.0"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "::ffff:169.254.169.254"},
+		{addr: "224.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	alice := newPeer(t, "alice")
+	client := &Client{AllowHTTP: true}
+
+	_, err := client.FetchActor(context.Background(), alice.actor.ID)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("FetchActor() on loopback error = %v, want %v", err, ErrPrivateAddress)
+	}
+	// The host name is resolved before the address is checked.
+	_, err = client.FetchActor(context.Background(), strings.Replace(alice.actor.ID, "127.0.0.1", "localhost", 1))
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("FetchActor() on localhost error = %v, want %v", err, ErrPrivateAddress)
+	}
+}
+
+func TestClientChecksRedirects(t *testing.T) {
+	redirects := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/scheme":
+			http.Redirect(w, r, "gopher://internal.example/users/alice", http.StatusFound)
+		case "/loop":
+			redirects++
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := &Client{AllowPrivateAddresses: true, AllowHTTP: true}
+
+	_, err := client.FetchActor(context.Background(), server.URL+"/scheme")
+	if !errors.Is(err, ErrBadScheme) {
+		t.Errorf("FetchActor() error = %v, want %v", err, ErrBadScheme)
+	}
+	_, err = client.FetchActor(context.Background(), server.URL+"/loop")
+	if !errors.Is(err, ErrTooManyRedirects) || redirects != maxRedirects {
+		t.Errorf("FetchActor() error = %v after %d redirects, want %v after %d", err, redirects, ErrTooManyRedirects, maxRedirects)
+	}
+}
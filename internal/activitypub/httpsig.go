@@ -0,0 +1,164 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxClockSkew is how far a signed request's Date may be from now. It
+// bounds how long a captured request can be replayed.
+const maxClockSkew = time.Hour
+
+var (
+	ErrMissingSignature = errors.New("activitypub: request isn't signed")
+	ErrBadSignature     = errors.New("activitypub: signature doesn't match")
+	ErrStaleSignature   = errors.New("activitypub: signed date is too far from now")
+	ErrDigestMismatch   = errors.New("activitypub: body doesn't match digest")
+)
+
+// Signature is a parsed Signature header, as in draft-cavage-http-signatures,
+// which is what the fediverse uses.
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+}
+
+// Sign adds Date, Digest and Signature headers to req. body must be the
+// request body, or nil for requests without one.
+func Sign(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", digest(body))
+		headers = append(headers, "digest")
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(req, req.URL.RequestURI(), requestHost(req), headers)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(
+		`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID,
+		strings.Join(headers, " "),
+		base64.StdEncoding.EncodeToString(signature),
+	))
+	return nil
+}
+
+// ParseSignature reads the Signature header of an incoming request.
+func ParseSignature(header http.Header) (Signature, error) {
+	value := header.Get("Signature")
+	if value == "" {
+		return Signature{}, ErrMissingSignature
+	}
+
+	sig := Signature{Headers: []string{"date"}}
+	for _, param := range strings.Split(value, ",") {
+		name, quoted, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			return Signature{}, fmt.Errorf("activitypub: malformed signature parameter %q", param)
+		}
+		value := strings.Trim(quoted, `"`)
+		switch name {
+		case "keyId":
+			sig.KeyID = value
+		case "algorithm":
+			sig.Algorithm = value
+		case "headers":
+			sig.Headers = strings.Fields(strings.ToLower(value))
+		case "signature":
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return Signature{}, fmt.Errorf("activitypub: malformed signature: %w", err)
+			}
+			sig.Signature = decoded
+		}
+	}
+	if sig.KeyID == "" || sig.Signature == nil {
+		return Signature{}, ErrMissingSignature
+	}
+	// hs2019 leaves the algorithm to the key, and fediverse keys are RSA.
+	if sig.Algorithm != "" && sig.Algorithm != "rsa-sha256" && sig.Algorithm != "hs2019" {
+		return Signature{}, fmt.Errorf("activitypub: unsupported signature algorithm %q", sig.Algorithm)
+	}
+	return sig, nil
+}
+
+// Verify checks that sig, read from r, was made with key over r's method,
+// path, host and date, and over body's digest when there is a body.
+func (sig Signature) Verify(r *http.Request, body []byte, key *rsa.PublicKey) error {
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for _, name := range required {
+		if !slices.Contains(sig.Headers, name) {
+			return fmt.Errorf("activitypub: signature doesn't cover %s", name)
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("activitypub: invalid date: %w", err)
+	}
+	if skew := time.Since(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return ErrStaleSignature
+	}
+	if len(body) > 0 && r.Header.Get("Digest") != digest(body) {
+		return ErrDigestMismatch
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(r, r.URL.RequestURI(), r.Host, sig.Headers)))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig.Signature)
+	if err != nil {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// ActorID returns the actor a key ID belongs to, which by convention is the
+// key ID without its fragment.
+func (sig Signature) ActorID() string {
+	actorID, _, _ := strings.Cut(sig.KeyID, "#")
+	return actorID
+}
+
+func signingString(r *http.Request, requestURI, host string, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, name := range headers {
+		switch name {
+		case "(request-target)":
+			lines = append(lines, "(request-target): "+strings.ToLower(r.Method)+" "+requestURI)
+		case "host":
+			lines = append(lines, "host: "+host)
+		default:
+			lines = append(lines, name+": "+strings.Join(r.Header.Values(name), ", "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
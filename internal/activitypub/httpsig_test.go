@@ -0,0 +1,140 @@
+package activitypub
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	body := []byte(`{"type":"Follow"}`)
+
+	tests := []struct {
+		name    string
+		modify  func(r *http.Request) []byte
+		key     *rsa.PublicKey
+		wantErr error
+	}{
+		{
+			name:    "Valid signature",
+			modify:  func(r *http.Request) []byte { return body },
+			key:     &key.PublicKey,
+			wantErr: nil,
+		},
+		{
+			name:    "Wrong key",
+			modify:  func(r *http.Request) []byte { return body },
+			key:     &otherKey.PublicKey,
+			wantErr: ErrBadSignature,
+		},
+		{
+			name:    "Tampered body",
+			modify:  func(r *http.Request) []byte { return []byte(`{"type":"Delete"}`) },
+			key:     &key.PublicKey,
+			wantErr: ErrDigestMismatch,
+		},
+		{
+			name: "Different inbox",
+			modify: func(r *http.Request) []byte {
+				r.URL.Path = "/users/bob/inbox"
+				return body
+			},
+			key:     &key.PublicKey,
+			wantErr: ErrBadSignature,
+		},
+		{
+			name: "Stale date",
+			modify: func(r *http.Request) []byte {
+				r.Header.Set("Date", time.Now().Add(-2*maxClockSkew).UTC().Format(http.TimeFormat))
+				return body
+			},
+			key:     &key.PublicKey,
+			wantErr: ErrStaleSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "https://chirpy.example/users/alice/inbox", nil)
+			err := Sign(req, body, "https://remote.example/users/carol#main-key", key)
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			received := tt.modify(req)
+
+			sig, err := ParseSignature(req.Header)
+			if err != nil {
+				t.Fatalf("ParseSignature() error = %v", err)
+			}
+			if sig.ActorID() != "https://remote.example/users/carol" {
+				t.Errorf("ActorID() = %q, want the key ID without its fragment", sig.ActorID())
+			}
+			err = sig.Verify(req, received, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRequiresSignedDigest(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	// A signature made without a digest can't vouch for a body.
+	req := httptest.NewRequest(http.MethodPost, "https://chirpy.example/inbox", nil)
+	err = Sign(req, nil, "https://remote.example/users/carol#main-key", key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	sig, err := ParseSignature(req.Header)
+	if err != nil {
+		t.Fatalf("ParseSignature() error = %v", err)
+	}
+	err = sig.Verify(req, []byte(`{"type":"Follow"}`), &key.PublicKey)
+	if err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("Verify() error = %v, want an error about the digest", err)
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{name: "Missing", header: "", wantErr: true},
+		{name: "No key ID", header: `algorithm="rsa-sha256",signature="c2ln"`, wantErr: true},
+		{name: "Unsupported algorithm", header: `keyId="k",algorithm="hmac-sha256",signature="c2ln"`, wantErr: true},
+		{name: "Malformed signature", header: `keyId="k",signature="!!"`, wantErr: true},
+		{name: "hs2019", header: `keyId="k",algorithm="hs2019",headers="(request-target) host date",signature="c2ln"`, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Signature", tt.header)
+			}
+			_, err := ParseSignature(header)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
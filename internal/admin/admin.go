@@ -0,0 +1,163 @@
+// Package admin implements `chirpy admin`, the command line operators use
+// to inspect and change a Chirpy instance without writing SQL.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/config"
+)
+
+const usage = `Usage: chirpy admin [-json] <command> [arguments]
+
+Commands:
+  users list [-limit n] [-offset n]
+  users show <user>
+  users suspend [-reason text] <user>
+  users unsuspend [-reason text] <user>
+  users delete -yes <user>
+  tokens revoke <user>
+  membership grant [-plan name] [-until date | -for duration] <user>
+  membership revoke <user>
+  chirps delete [-reason text] <chirp ID>
+  stats
+
+A user is an ID or an email address. -json prints JSON instead of tables,
+before or after the command.
+`
+
+// ErrUsage is wrapped by the errors Run returns for a malformed command
+// line, after it printed the usage.
+var ErrUsage = errors.New("invalid arguments")
+
+// Run runs the admin command line args, which come after "chirpy admin",
+// against the instance apiCfg is configured for. Output goes to stdout,
+// usage errors to stderr. The arguments are checked before anything is
+// read from or written to the database.
+func Run(ctx context.Context, apiCfg *config.ApiConfig, args []string, stdout, stderr io.Writer) error {
+	c := &command{apiCfg: apiCfg, stdout: stdout}
+	err := c.run(ctx, args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+	if errors.Is(err, ErrUsage) {
+		fmt.Fprintf(stderr, "chirpy admin: %s\n\n%s", err, usage)
+	}
+	return err
+}
+
+type command struct {
+	apiCfg *config.ApiConfig
+	stdout io.Writer
+	json   bool
+}
+
+func (c *command) run(ctx context.Context, args []string) error {
+	flags := c.flagSet("admin")
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUsage, err)
+	}
+	args = flags.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing command", ErrUsage)
+	}
+
+	name := args[0]
+	args = args[1:]
+	if name != "stats" && len(args) > 0 {
+		name += " " + args[0]
+		args = args[1:]
+	}
+	switch name {
+	case "users list":
+		return c.listUsers(ctx, args)
+	case "users show":
+		return c.showUser(ctx, args)
+	case "users suspend":
+		return c.suspendUser(ctx, args, true)
+	case "users unsuspend":
+		return c.suspendUser(ctx, args, false)
+	case "users delete":
+		return c.deleteUser(ctx, args)
+	case "tokens revoke":
+		return c.revokeTokens(ctx, args)
+	case "membership grant":
+		return c.grantMembership(ctx, args)
+	case "membership revoke":
+		return c.revokeMembership(ctx, args)
+	case "chirps delete":
+		return c.deleteChirp(ctx, args)
+	case "stats":
+		return c.stats(ctx, args)
+	}
+	return fmt.Errorf("%w: unknown command %q", ErrUsage, name)
+}
+
+// flagSet returns the flags of a command. Every command accepts -json, so
+// it can come after the command too.
+func (c *command) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.BoolVar(&c.json, "json", c.json, "print JSON instead of tables")
+	return flags
+}
+
+// parse parses a command's flags and returns its one positional argument,
+// described by operand, or none when operand is empty.
+func parse(flags *flag.FlagSet, args []string, operand string) (string, error) {
+	err := flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUsage, err)
+	}
+	if operand == "" {
+		if flags.NArg() > 0 {
+			return "", fmt.Errorf("%w: %s takes no arguments", ErrUsage, flags.Name())
+		}
+		return "", nil
+	}
+	if flags.NArg() != 1 {
+		return "", fmt.Errorf("%w: %s expects %s", ErrUsage, flags.Name(), operand)
+	}
+	return flags.Arg(0), nil
+}
+
+// print writes v as JSON, or as the table writeTable writes.
+func (c *command) print(v any, writeTable func(w io.Writer)) error {
+	if c.json {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	writeTable(w)
+	return w.Flush()
+}
+
+// done reports the outcome of a change, as JSON or as a sentence.
+func (c *command) done(v any, message string) error {
+	return c.print(v, func(w io.Writer) {
+		fmt.Fprintln(w, message)
+	})
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRunUsageErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantMessage string
+	}{
+		{name: "No command", args: nil, wantMessage: "missing command"},
+		{name: "Unknown command", args: []string{"users", "rename"}, wantMessage: `unknown command "users rename"`},
+		{name: "Group only", args: []string{"tokens"}, wantMessage: `unknown command "tokens"`},
+		{name: "Unknown flag", args: []string{"users", "list", "-all"}, wantMessage: "flag provided but not defined: -all"},
+		{name: "Bad limit", args: []string{"users", "list", "-limit", "0"}, wantMessage: "-limit must be between 1 and 1000"},
+		{name: "Negative offset", args: []string{"users", "list", "-offset", "-1"}, wantMessage: "-offset can't be negative"},
+		{name: "Missing user", args: []string{"users", "show"}, wantMessage: "users show expects <user>"},
+		{name: "Two users", args: []string{"tokens", "revoke", "a@example.com", "b@example.com"}, wantMessage: "tokens revoke expects <user>"},
+		{name: "Delete without confirmation", args: []string{"users", "delete", "a@example.com"}, wantMessage: "confirm with -yes"},
+		{name: "Bad chirp ID", args: []string{"chirps", "delete", "-reason", "spam", "chirp"}, wantMessage: `invalid chirp ID "chirp"`},
+		{name: "Both ends", args: []string{"membership", "grant", "-until", "2100-01-01", "-for", "720h", "a@example.com"}, wantMessage: "can't be used together"},
+		{name: "Stats arguments", args: []string{"stats", "now"}, wantMessage: "stats takes no arguments"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			// A nil config makes any database access panic, so this also
+			// checks that arguments are validated first.
+			err := Run(context.Background(), nil, tt.args, stdout, stderr)
+			if !errors.Is(err, ErrUsage) {
+				t.Fatalf("Run() error = %v, want ErrUsage", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("Run() error = %q, want it to contain %q", err, tt.wantMessage)
+			}
+			if !strings.Contains(stderr.String(), "Usage: chirpy admin") {
+				t.Errorf("stderr = %q, want the usage", stderr)
+			}
+			if stdout.Len() > 0 {
+				t.Errorf("stdout = %q, want nothing", stdout)
+			}
+		})
+	}
+}
+
+func TestRunHelp(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err := Run(context.Background(), nil, []string{"users", "show", "-h"}, stdout, stderr)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "membership grant") {
+		t.Errorf("stdout = %q, want the usage", stdout)
+	}
+}
+
+func TestMembershipEnd(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		until   string
+		period  time.Duration
+		want    *time.Time
+		wantErr bool
+	}{
+		{name: "Indefinite"},
+		{name: "Period", period: 48 * time.Hour, want: ptr(now.Add(48 * time.Hour))},
+		{name: "Date", until: "2025-07-01", want: ptr(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC))},
+		{name: "Time", until: "2025-06-02T08:30:00Z", want: ptr(time.Date(2025, 6, 2, 8, 30, 0, 0, time.UTC))},
+		{name: "Past date", until: "2025-05-01", wantErr: true},
+		{name: "Malformed date", until: "next week", wantErr: true},
+		{name: "Negative period", period: -time.Hour, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := membershipEnd(tt.until, tt.period, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("membershipEnd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && !got.Equal(*tt.want) {
+				t.Errorf("membershipEnd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintUsers(t *testing.T) {
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	suspended := created.Add(time.Hour)
+	users := []userSummary{
+		{ID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), Email: "a@example.com", Role: "admin", CreatedAt: created},
+		{ID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), Email: "bob@example.com", Role: "user", CreatedAt: created, SuspendedAt: &suspended},
+	}
+
+	stdout := &bytes.Buffer{}
+	c := &command{stdout: stdout}
+	err := c.printUsers(users)
+	if err != nil {
+		t.Fatalf("printUsers() error = %v", err)
+	}
+	want := "" +
+		"ID                                    EMAIL            ROLE   CREATED               STATUS\n" +
+		"11111111-1111-1111-1111-111111111111  a@example.com    admin  2025-01-02T03:04:05Z  active\n" +
+		"22222222-2222-2222-2222-222222222222  bob@example.com  user   2025-01-02T03:04:05Z  suspended\n"
+	if stdout.String() != want {
+		t.Errorf("table =\n%s\nwant\n%s", stdout, want)
+	}
+
+	stdout.Reset()
+	c.json = true
+	err = c.printUsers(users)
+	if err != nil {
+		t.Fatalf("printUsers() error = %v", err)
+	}
+	decoded := []map[string]any{}
+	err = json.Unmarshal(stdout.Bytes(), &decoded)
+	if err != nil {
+		t.Fatalf("printUsers() printed invalid JSON: %v", err)
+	}
+	if len(decoded) != 2 || decoded[1]["email"] != "bob@example.com" || decoded[1]["suspended_at"] != "2025-01-02T04:04:05Z" || decoded[0]["suspended_at"] != nil {
+		t.Errorf("JSON = %s", stdout)
+	}
+}
+
+func TestPrintEmptyList(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	c := &command{stdout: stdout, json: true}
+	err := c.printUsers([]userSummary{})
+	if err != nil {
+		t.Fatalf("printUsers() error = %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "[]" {
+		t.Errorf("JSON = %q, want []", got)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,187 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (c *command) revokeTokens(ctx context.Context, args []string) error {
+	ref, err := parse(c.flagSet("tokens revoke"), args, "<user>")
+	if err != nil {
+		return err
+	}
+
+	user, err := c.findUser(ctx, ref)
+	if err != nil {
+		return err
+	}
+	revoked, err := c.apiCfg.RevokeTokens(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't revoke tokens of %s: %w", user.Email, err)
+	}
+	result := struct {
+		UserID                      uuid.UUID `json:"user_id"`
+		RevokedPersonalAccessTokens int64     `json:"revoked_personal_access_tokens"`
+	}{UserID: user.ID, RevokedPersonalAccessTokens: revoked}
+	return c.done(result, fmt.Sprintf(
+		"Revoked the refresh tokens and %d personal access tokens of %s; access tokens already issued work until they expire",
+		revoked, user.Email,
+	))
+}
+
+func (c *command) grantMembership(ctx context.Context, args []string) error {
+	flags := c.flagSet("membership grant")
+	plan := flags.String("plan", "chirpy_red", "the plan to grant")
+	until := flags.String("until", "", "the date the membership ends, like 2006-01-02")
+	period := flags.Duration("for", 0, "how long the membership lasts, like 720h")
+	ref, err := parse(flags, args, "<user>")
+	if err != nil {
+		return err
+	}
+	endsAt, err := membershipEnd(*until, *period, time.Now())
+	if err != nil {
+		return err
+	}
+
+	user, err := c.findUser(ctx, ref)
+	if err != nil {
+		return err
+	}
+	err = c.apiCfg.GrantMembership(ctx, user.ID, *plan, endsAt)
+	if err != nil {
+		return fmt.Errorf("couldn't grant %s to %s: %w", *plan, user.Email, err)
+	}
+	current, err := c.apiCfg.DbQueries.GetCurrentMembership(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve membership: %w", err)
+	}
+	granted := membershipFromDB(current)
+	return c.done(granted, fmt.Sprintf("Granted %s to %s until %s", granted.Plan, user.Email, formatTime(granted.EndsAt)))
+}
+
+// membershipEnd works out when a granted membership ends from -until or
+// -for. It's nil, for a membership that doesn't end, when neither is set.
+func membershipEnd(until string, period time.Duration, now time.Time) (*time.Time, error) {
+	if until != "" && period != 0 {
+		return nil, fmt.Errorf("%w: -until and -for can't be used together", ErrUsage)
+	}
+	if period < 0 {
+		return nil, fmt.Errorf("%w: -for can't be negative", ErrUsage)
+	}
+	if period > 0 {
+		endsAt := now.Add(period)
+		return &endsAt, nil
+	}
+	if until == "" {
+		return nil, nil
+	}
+
+	endsAt, err := time.Parse(time.DateOnly, until)
+	if err != nil {
+		endsAt, err = time.Parse(time.RFC3339, until)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: -until must be a date like 2006-01-02 or a time like 2006-01-02T15:04:05Z", ErrUsage)
+	}
+	if !endsAt.After(now) {
+		return nil, fmt.Errorf("%w: -until must be in the future", ErrUsage)
+	}
+	return &endsAt, nil
+}
+
+func (c *command) revokeMembership(ctx context.Context, args []string) error {
+	ref, err := parse(c.flagSet("membership revoke"), args, "<user>")
+	if err != nil {
+		return err
+	}
+
+	user, err := c.findUser(ctx, ref)
+	if err != nil {
+		return err
+	}
+	revoked, err := c.apiCfg.RevokeMembership(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't revoke the membership of %s: %w", user.Email, err)
+	}
+	message := fmt.Sprintf("Revoked the membership of %s", user.Email)
+	if !revoked {
+		message = fmt.Sprintf("%s has no active membership", user.Email)
+	}
+	result := struct {
+		UserID  uuid.UUID `json:"user_id"`
+		Revoked bool      `json:"revoked"`
+	}{UserID: user.ID, Revoked: revoked}
+	return c.done(result, message)
+}
+
+func (c *command) deleteChirp(ctx context.Context, args []string) error {
+	flags := c.flagSet("chirps delete")
+	reason := flags.String("reason", "", "why, for the moderation audit trail")
+	ref, err := parse(flags, args, "<chirp ID>")
+	if err != nil {
+		return err
+	}
+	chirpID, err := uuid.Parse(ref)
+	if err != nil {
+		return fmt.Errorf("%w: invalid chirp ID %q", ErrUsage, ref)
+	}
+
+	err = c.apiCfg.RemoveChirp(ctx, chirpID, *reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("couldn't find chirp %s", chirpID)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't delete chirp %s: %w", chirpID, err)
+	}
+	result := struct {
+		DeletedChirpID uuid.UUID `json:"deleted_chirp_id"`
+	}{DeletedChirpID: chirpID}
+	return c.done(result, fmt.Sprintf("Deleted chirp %s", chirpID))
+}
+
+type stats struct {
+	Users                       int64 `json:"users"`
+	SuspendedUsers              int64 `json:"suspended_users"`
+	UsersPendingDeletion        int64 `json:"users_pending_deletion"`
+	Chirps                      int64 `json:"chirps"`
+	ChirpsLastDay               int64 `json:"chirps_last_day"`
+	ScheduledChirps             int64 `json:"scheduled_chirps"`
+	ActiveMemberships           int64 `json:"active_memberships"`
+	OpenModerationCases         int64 `json:"open_moderation_cases"`
+	RemoteFollowers             int64 `json:"remote_followers"`
+	PendingFederationDeliveries int64 `json:"pending_federation_deliveries"`
+}
+
+func (c *command) stats(ctx context.Context, args []string) error {
+	_, err := parse(c.flagSet("stats"), args, "")
+	if err != nil {
+		return err
+	}
+
+	row, err := c.apiCfg.DbQueries.GetInstanceStats(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve stats: %w", err)
+	}
+	return c.printStats(stats(row))
+}
+
+func (c *command) printStats(s stats) error {
+	return c.print(s, func(w io.Writer) {
+		fmt.Fprintf(w, "Users\t%d\n", s.Users)
+		fmt.Fprintf(w, "Suspended users\t%d\n", s.SuspendedUsers)
+		fmt.Fprintf(w, "Users pending deletion\t%d\n", s.UsersPendingDeletion)
+		fmt.Fprintf(w, "Chirps\t%d\n", s.Chirps)
+		fmt.Fprintf(w, "Chirps in the last day\t%d\n", s.ChirpsLastDay)
+		fmt.Fprintf(w, "Scheduled chirps\t%d\n", s.ScheduledChirps)
+		fmt.Fprintf(w, "Active memberships\t%d\n", s.ActiveMemberships)
+		fmt.Fprintf(w, "Open moderation cases\t%d\n", s.OpenModerationCases)
+		fmt.Fprintf(w, "Remote followers\t%d\n", s.RemoteFollowers)
+		fmt.Fprintf(w, "Pending federation deliveries\t%d\n", s.PendingFederationDeliveries)
+	})
+}
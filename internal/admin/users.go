@@ -0,0 +1,243 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxListLimit = 1000
+
+type userSummary struct {
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	Role                string     `json:"role"`
+	CreatedAt           time.Time  `json:"created_at"`
+	SuspendedAt         *time.Time `json:"suspended_at"`
+	ShadowBannedAt      *time.Time `json:"shadow_banned_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+}
+
+type userDetails struct {
+	userSummary
+	TOTPEnabled bool        `json:"totp_enabled"`
+	Chirps      int64       `json:"chirps"`
+	Membership  *membership `json:"membership"`
+}
+
+type membership struct {
+	Plan        string     `json:"plan"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	CancelledAt *time.Time `json:"cancelled_at"`
+}
+
+// status sums up the restrictions on an account, the most severe first.
+func (user userSummary) status() string {
+	switch {
+	case user.DeletionScheduledAt != nil:
+		return "pending deletion"
+	case user.SuspendedAt != nil:
+		return "suspended"
+	case user.ShadowBannedAt != nil:
+		return "shadow-banned"
+	}
+	return "active"
+}
+
+func (c *command) listUsers(ctx context.Context, args []string) error {
+	flags := c.flagSet("users list")
+	limit := flags.Int("limit", 50, "how many users to list")
+	offset := flags.Int("offset", 0, "how many users to skip")
+	_, err := parse(flags, args, "")
+	if err != nil {
+		return err
+	}
+	if *limit < 1 || *limit > maxListLimit {
+		return fmt.Errorf("%w: -limit must be between 1 and %d", ErrUsage, maxListLimit)
+	}
+	if *offset < 0 {
+		return fmt.Errorf("%w: -offset can't be negative", ErrUsage)
+	}
+
+	dbUsers, err := c.apiCfg.DbQueries.ListUsers(ctx, database.ListUsersParams{
+		Limit:  int32(*limit),
+		Offset: int32(*offset),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't list users: %w", err)
+	}
+	users := []userSummary{}
+	for _, dbUser := range dbUsers {
+		users = append(users, userSummaryFromDB(dbUser))
+	}
+	return c.printUsers(users)
+}
+
+func (c *command) printUsers(users []userSummary) error {
+	return c.print(users, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tEMAIL\tROLE\tCREATED\tSTATUS")
+		for _, user := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", user.ID, user.Email, user.Role, formatTime(&user.CreatedAt), user.status())
+		}
+	})
+}
+
+func (c *command) showUser(ctx context.Context, args []string) error {
+	ref, err := parse(c.flagSet("users show"), args, "<user>")
+	if err != nil {
+		return err
+	}
+
+	user, err := c.findUser(ctx, ref)
+	if err != nil {
+		return err
+	}
+	details := userDetails{
+		userSummary: userSummaryFromDB(user),
+		TOTPEnabled: user.TotpEnabled,
+	}
+	details.Chirps, err = c.apiCfg.DbQueries.CountChirpsByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't count chirps: %w", err)
+	}
+	current, err := c.apiCfg.DbQueries.GetCurrentMembership(ctx, user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("couldn't retrieve membership: %w", err)
+	}
+	if err == nil {
+		details.Membership = membershipFromDB(current)
+	}
+	return c.printUserDetails(details)
+}
+
+func (c *command) printUserDetails(user userDetails) error {
+	return c.print(user, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%s\n", user.ID)
+		fmt.Fprintf(w, "Email\t%s\n", user.Email)
+		fmt.Fprintf(w, "Role\t%s\n", user.Role)
+		fmt.Fprintf(w, "Created\t%s\n", formatTime(&user.CreatedAt))
+		fmt.Fprintf(w, "Status\t%s\n", user.status())
+		fmt.Fprintf(w, "Suspended\t%s\n", formatTime(user.SuspendedAt))
+		fmt.Fprintf(w, "Shadow-banned\t%s\n", formatTime(user.ShadowBannedAt))
+		fmt.Fprintf(w, "Deletion scheduled\t%s\n", formatTime(user.DeletionScheduledAt))
+		fmt.Fprintf(w, "Two-factor\t%t\n", user.TOTPEnabled)
+		fmt.Fprintf(w, "Chirps\t%d\n", user.Chirps)
+		if user.Membership == nil {
+			fmt.Fprintf(w, "Membership\t-\n")
+			return
+		}
+		fmt.Fprintf(w, "Membership\t%s, ends %s\n", user.Membership.Plan, formatTime(user.Membership.EndsAt))
+	})
+}
+
+func (c *command) suspendUser(ctx context.Context, args []string, suspend bool) error {
+	name := "users unsuspend"
+	if suspend {
+		name = "users suspend"
+	}
+	flags := c.flagSet(name)
+	reason := flags.String("reason", "", "why, for the moderation audit trail")
+	ref, err := parse(flags, args, "<user>")
+	if err != nil {
+		return err
+	}
+
+	user, err := c.findUser(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if suspend {
+		err = c.apiCfg.SuspendAccount(ctx, user.ID, *reason)
+	} else {
+		err = c.apiCfg.UnsuspendAccount(ctx, user.ID, *reason)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't update %s: %w", user.Email, err)
+	}
+
+	user, err = c.apiCfg.DbQueries.GetUserByID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve user: %w", err)
+	}
+	return c.printUsers([]userSummary{userSummaryFromDB(user)})
+}
+
+func (c *command) deleteUser(ctx context.Context, args []string) error {
+	flags := c.flagSet("users delete")
+	yes := flags.Bool("yes", false, "confirm the deletion, which can't be undone")
+	ref, err := parse(flags, args, "<user>")
+	if err != nil {
+		return err
+	}
+	if !*yes {
+		return fmt.Errorf("%w: deleting a user can't be undone, confirm with -yes", ErrUsage)
+	}
+
+	user, err := c.findUser(ctx, ref)
+	if err != nil {
+		return err
+	}
+	err = c.apiCfg.DeleteAccount(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't delete %s: %w", user.Email, err)
+	}
+	result := struct {
+		DeletedUserID uuid.UUID `json:"deleted_user_id"`
+	}{DeletedUserID: user.ID}
+	return c.done(result, fmt.Sprintf("Deleted %s (%s) and everything they owned", user.Email, user.ID))
+}
+
+// findUser looks a user up by ID or, failing that, by email.
+func (c *command) findUser(ctx context.Context, ref string) (database.User, error) {
+	var user database.User
+	userID, err := uuid.Parse(ref)
+	if err == nil {
+		user, err = c.apiCfg.DbQueries.GetUserByID(ctx, userID)
+	} else {
+		user, err = c.apiCfg.DbQueries.GetUserByEmail(ctx, ref)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, fmt.Errorf("couldn't find user %s", ref)
+	}
+	if err != nil {
+		return user, fmt.Errorf("couldn't retrieve user %s: %w", ref, err)
+	}
+	return user, nil
+}
+
+func userSummaryFromDB(user database.User) userSummary {
+	return userSummary{
+		ID:                  user.ID,
+		Email:               user.Email,
+		Role:                user.Role,
+		CreatedAt:           user.CreatedAt,
+		SuspendedAt:         nullTime(user.SuspendedAt),
+		ShadowBannedAt:      nullTime(user.ShadowBannedAt),
+		DeletionScheduledAt: nullTime(user.DeletionScheduledAt),
+	}
+}
+
+func membershipFromDB(dbMembership database.Membership) *membership {
+	return &membership{
+		Plan:        dbMembership.Plan,
+		Status:      dbMembership.Status,
+		StartedAt:   dbMembership.StartedAt,
+		EndsAt:      nullTime(dbMembership.EndsAt),
+		CancelledAt: nullTime(dbMembership.CancelledAt),
+	}
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -19,6 +19,10 @@ type TokenType string
 const (
 	// TokenTypeAccess -
 	TokenTypeAccess TokenType = "chirpy"
+	// TokenTypeMFA -
+	TokenTypeMFA TokenType = "chirpy-mfa"
+	// TokenTypeDownload -
+	TokenTypeDownload TokenType = "chirpy-download"
 )
 
 var ErrNoAuthHeaderIncluded = errors.New("no auth header included in request")
@@ -41,10 +45,55 @@ func MakeJWT(
 	userID uuid.UUID,
 	tokenSecret string,
 	expiresIn time.Duration,
+) (string, error) {
+	return makeToken(userID, tokenSecret, expiresIn, TokenTypeAccess)
+}
+
+// ValidateJWT -
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(tokenString, tokenSecret, TokenTypeAccess)
+}
+
+// MakeMFAToken creates the short-lived challenge token handed out after a
+// correct password when the user still has to provide a second factor.
+func MakeMFAToken(
+	userID uuid.UUID,
+	tokenSecret string,
+	expiresIn time.Duration,
+) (string, error) {
+	return makeToken(userID, tokenSecret, expiresIn, TokenTypeMFA)
+}
+
+// ValidateMFAToken -
+func ValidateMFAToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(tokenString, tokenSecret, TokenTypeMFA)
+}
+
+// MakeDownloadToken creates the token that signs a download link for a
+// single file, like a data export. Its subject is the file's ID rather than
+// a user's.
+func MakeDownloadToken(
+	fileID uuid.UUID,
+	tokenSecret string,
+	expiresIn time.Duration,
+) (string, error) {
+	return makeToken(fileID, tokenSecret, expiresIn, TokenTypeDownload)
+}
+
+// ValidateDownloadToken returns the ID of the file a download token is for.
+func ValidateDownloadToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(tokenString, tokenSecret, TokenTypeDownload)
+}
+
+func makeToken(
+	userID uuid.UUID,
+	tokenSecret string,
+	expiresIn time.Duration,
+	tokenType TokenType,
 ) (string, error) {
 	signingKey := []byte(tokenSecret)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    string(TokenTypeAccess),
+		Issuer:    string(tokenType),
 		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
 		Subject:   userID.String(),
@@ -52,8 +101,7 @@ func MakeJWT(
 	return token.SignedString(signingKey)
 }
 
-// ValidateJWT -
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+func validateToken(tokenString, tokenSecret string, tokenType TokenType) (uuid.UUID, error) {
 	claimsStruct := jwt.RegisteredClaims{}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
@@ -73,7 +121,7 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	if err != nil {
 		return uuid.Nil, err
 	}
-	if issuer != string(TokenTypeAccess) {
+	if issuer != string(tokenType) {
 		return uuid.Nil, errors.New("invalid issuer")
 	}
 
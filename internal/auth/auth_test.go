@@ -66,6 +66,7 @@ func TestCheckPasswordHash(t *testing.T) {
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
 	validToken, _ := MakeJWT(userID, "secret", time.Hour)
+	downloadToken, _ := MakeDownloadToken(userID, "secret", time.Hour)
 
 	tests := []struct {
 		name        string
@@ -95,6 +96,13 @@ func TestValidateJWT(t *testing.T) {
 			wantUserID:  uuid.Nil,
 			wantErr:     true,
 		},
+		{
+			name:        "Download token",
+			tokenString: downloadToken,
+			tokenSecret: "secret",
+			wantUserID:  uuid.Nil,
+			wantErr:     true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenTypeOAuth -
+const TokenTypeOAuth TokenType = "chirpy-oauth"
+
+// OAuthAccessToken is the validated content of an access token issued to a
+// third-party client.
+type OAuthAccessToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ClientID  uuid.UUID
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+type oauthClaims struct {
+	jwt.RegisteredClaims
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// MakeOAuthAccessToken creates a scoped access JWT for a third-party client.
+// The returned token ID can be used to revoke the token before it expires.
+func MakeOAuthAccessToken(
+	userID uuid.UUID,
+	clientID uuid.UUID,
+	scopes []string,
+	tokenSecret string,
+	expiresIn time.Duration,
+) (string, uuid.UUID, error) {
+	tokenID := uuid.New()
+	now := time.Now().UTC()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, oauthClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID.String(),
+			Issuer:    string(TokenTypeOAuth),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		ClientID: clientID.String(),
+		Scope:    strings.Join(scopes, " "),
+	})
+	signed, err := token.SignedString([]byte(tokenSecret))
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	return signed, tokenID, nil
+}
+
+// ValidateOAuthAccessToken -
+func ValidateOAuthAccessToken(tokenString, tokenSecret string) (OAuthAccessToken, error) {
+	claims := oauthClaims{}
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		&claims,
+		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+	if err != nil {
+		return OAuthAccessToken{}, err
+	}
+	if claims.Issuer != string(TokenTypeOAuth) {
+		return OAuthAccessToken{}, errors.New("invalid issuer")
+	}
+
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return OAuthAccessToken{}, fmt.Errorf("invalid token ID: %w", err)
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return OAuthAccessToken{}, fmt.Errorf("invalid user ID: %w", err)
+	}
+	clientID, err := uuid.Parse(claims.ClientID)
+	if err != nil {
+		return OAuthAccessToken{}, fmt.Errorf("invalid client ID: %w", err)
+	}
+	if claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return OAuthAccessToken{}, errors.New("missing token lifetime")
+	}
+
+	return OAuthAccessToken{
+		ID:        tokenID,
+		UserID:    userID,
+		ClientID:  clientID,
+		Scopes:    ParseScope(claims.Scope),
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// ParseScope splits an OAuth scope parameter into individual scopes.
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// VerifyPKCE checks a code_verifier against the S256 code_challenge sent
+// with the authorization request (RFC 7636).
+func VerifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	expected := PKCEChallenge(verifier)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// PKCEChallenge derives the S256 code_challenge for a code_verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := strings.Repeat("a", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{name: "Matching verifier", verifier: verifier, challenge: challenge, want: true},
+		{name: "Wrong verifier", verifier: strings.Repeat("b", 43), challenge: challenge, want: false},
+		{name: "Plain challenge", verifier: verifier, challenge: verifier, want: false},
+		{name: "Verifier too short", verifier: "short", challenge: challenge, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("VerifyPKCE() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOAuthAccessToken(t *testing.T) {
+	userID := uuid.New()
+	clientID := uuid.New()
+	scopes := []string{ScopeChirpsRead, ScopeChirpsWrite}
+	token, tokenID, err := MakeOAuthAccessToken(userID, clientID, scopes, "secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeOAuthAccessToken() error = %v", err)
+	}
+
+	got, err := ValidateOAuthAccessToken(token, "secret")
+	if err != nil {
+		t.Fatalf("ValidateOAuthAccessToken() error = %v", err)
+	}
+	if got.ID != tokenID || got.UserID != userID || got.ClientID != clientID || !slices.Equal(got.Scopes, scopes) {
+		t.Errorf("ValidateOAuthAccessToken() = %+v", got)
+	}
+
+	if _, err := ValidateOAuthAccessToken(token, "wrong_secret"); err == nil {
+		t.Error("ValidateOAuthAccessToken() accepted a token signed with another secret")
+	}
+	if _, err := ValidateJWT(token, "secret"); err == nil {
+		t.Error("ValidateJWT() accepted a third-party access token")
+	}
+	accessToken, _ := MakeJWT(userID, "secret", time.Hour)
+	if _, err := ValidateOAuthAccessToken(accessToken, "secret"); err == nil {
+		t.Error("ValidateOAuthAccessToken() accepted a first-party access token")
+	}
+}
@@ -0,0 +1,40 @@
+package auth
+
+import "testing"
+
+func TestValidateScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		wantErr bool
+	}{
+		{name: "Single known scope", scopes: []string{ScopeChirpsRead}, wantErr: false},
+		{name: "All scopes", scopes: Scopes, wantErr: false},
+		{name: "No scopes", scopes: nil, wantErr: true},
+		{name: "Unknown scope", scopes: []string{ScopeChirpsRead, "admin"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateScopes(tt.scopes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateScopes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsPersonalAccessToken(t *testing.T) {
+	pat, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatalf("MakePersonalAccessToken() error = %v", err)
+	}
+	if !IsPersonalAccessToken(pat) {
+		t.Errorf("IsPersonalAccessToken(%q) = false, want true", pat)
+	}
+
+	refreshToken, _ := MakeRefreshToken()
+	if IsPersonalAccessToken(refreshToken) {
+		t.Errorf("IsPersonalAccessToken(%q) = true, want false", refreshToken)
+	}
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B test vectors, truncated to six digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		name     string
+		unixTime int64
+		wantCode string
+	}{
+		{name: "T=59", unixTime: 59, wantCode: "287082"},
+		{name: "T=1111111109", unixTime: 1111111109, wantCode: "081804"},
+		{name: "T=1234567890", unixTime: 1234567890, wantCode: "005924"},
+		{name: "T=2000000000", unixTime: 2000000000, wantCode: "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCode, err := GenerateTOTPCode(secret, time.Unix(tt.unixTime, 0))
+			if err != nil {
+				t.Fatalf("GenerateTOTPCode() error = %v", err)
+			}
+			if gotCode != tt.wantCode {
+				t.Errorf("GenerateTOTPCode() gotCode = %v, want %v", gotCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	// The RFC 6238 test key, at a fixed time so the codes don't collide.
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(1_700_000_000, 0)
+	counter := now.Unix() / int64(totpPeriod.Seconds())
+	code, _ := GenerateTOTPCode(secret, now)
+	previousCode, _ := GenerateTOTPCode(secret, now.Add(-totpPeriod))
+	staleCode, _ := GenerateTOTPCode(secret, now.Add(-5*totpPeriod))
+
+	tests := []struct {
+		name        string
+		secret      string
+		code        string
+		wantCounter int64
+		want        bool
+	}{
+		{name: "Current code", secret: secret, code: code, wantCounter: counter, want: true},
+		{name: "Previous period within skew", secret: secret, code: previousCode, wantCounter: counter - 1, want: true},
+		{name: "Stale code", secret: secret, code: staleCode, want: false},
+		{name: "Wrong length", secret: secret, code: "12345", want: false},
+		{name: "Invalid secret", secret: "not base32!", code: code, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCounter, got := ValidateTOTPCode(tt.secret, tt.code, now)
+			if got != tt.want {
+				t.Errorf("ValidateTOTPCode() = %v, want %v", got, tt.want)
+			}
+			if got && gotCounter != tt.wantCounter {
+				t.Errorf("ValidateTOTPCode() counter = %d, want %d", gotCounter, tt.wantCounter)
+			}
+		})
+	}
+}
+
+func TestValidateMFAToken(t *testing.T) {
+	userID := uuid.New()
+	mfaToken, _ := MakeMFAToken(userID, "secret", time.Minute)
+	accessToken, _ := MakeJWT(userID, "secret", time.Hour)
+
+	if _, err := ValidateMFAToken(mfaToken, "secret"); err != nil {
+		t.Errorf("ValidateMFAToken() error = %v, want nil", err)
+	}
+	if _, err := ValidateMFAToken(accessToken, "secret"); err == nil {
+		t.Error("ValidateMFAToken() accepted an access token")
+	}
+	if _, err := ValidateJWT(mfaToken, "secret"); err == nil {
+		t.Error("ValidateJWT() accepted an MFA challenge token")
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("GenerateRecoveryCodes() returned %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+	for _, code := range codes {
+		if got := NormalizeRecoveryCode(code); got != code {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q", code, got)
+		}
+	}
+	if got := NormalizeRecoveryCode(" ABCDE12345 "); got != "abcde-12345" {
+		t.Errorf("NormalizeRecoveryCode() = %q, want %q", got, "abcde-12345")
+	}
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	secret := "whsec_test"
+	sentAt := time.Unix(1700000000, 0)
+	header := SignWebhook(payload, secret, sentAt)
+
+	tests := []struct {
+		name    string
+		header  string
+		payload []byte
+		secret  string
+		now     time.Time
+		wantErr error
+	}{
+		{
+			name:    "Valid signature",
+			header:  header,
+			payload: payload,
+			secret:  secret,
+			now:     sentAt.Add(time.Minute),
+			wantErr: nil,
+		},
+		{
+			name:    "Rotated secret alongside current one",
+			header:  header + ",v1=" + webhookMAC("1700000000", payload, "old"),
+			payload: payload,
+			secret:  "old",
+			now:     sentAt,
+			wantErr: nil,
+		},
+		{
+			name:    "Tampered payload",
+			header:  header,
+			payload: []byte(`{"id":"evt_1","event":"user.downgraded"}`),
+			secret:  secret,
+			now:     sentAt,
+			wantErr: ErrInvalidWebhookSignature,
+		},
+		{
+			name:    "Wrong secret",
+			header:  header,
+			payload: payload,
+			secret:  "other",
+			now:     sentAt,
+			wantErr: ErrInvalidWebhookSignature,
+		},
+		{
+			name:    "Replayed too late",
+			header:  header,
+			payload: payload,
+			secret:  secret,
+			now:     sentAt.Add(10 * time.Minute),
+			wantErr: ErrStaleWebhook,
+		},
+		{
+			name:    "Missing timestamp",
+			header:  "v1=abc",
+			payload: payload,
+			secret:  secret,
+			now:     sentAt,
+			wantErr: ErrInvalidWebhookSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.header, tt.payload, tt.secret, tt.now, 5*time.Minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyWebhookSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
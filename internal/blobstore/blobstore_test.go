@@ -0,0 +1,58 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	err = store.Put(ctx, "exports/a.zip", strings.NewReader("archive"))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	r, err := store.Get(ctx, "exports/a.zip")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != "archive" {
+		t.Errorf("Get() = %q, want %q", got, "archive")
+	}
+
+	err = store.Delete(ctx, "exports/a.zip")
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err = store.Get(ctx, "exports/a.zip")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreRejectsInvalidKeys(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	keys := []string{"", "../secret", "exports/../../secret", "/etc/passwd", "exports//a.zip", ".hidden"}
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			err := store.Put(context.Background(), key, strings.NewReader("x"))
+			if err == nil {
+				t.Errorf("Put(%q) succeeded, want an error", key)
+			}
+		})
+	}
+}
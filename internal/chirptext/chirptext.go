@@ -0,0 +1,39 @@
+// Package chirptext measures chirps the way users see them: in characters
+// rather than bytes, with links counted at a fixed weight.
+package chirptext
+
+import (
+	"regexp"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// URLWeight is how many characters a link counts as, however long it is.
+const URLWeight = 23
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+// Normalize returns text in Unicode normalization form C, so that e.g. an
+// accented letter is stored the same way whichever keyboard typed it.
+func Normalize(text string) string {
+	return norm.NFC.String(text)
+}
+
+// Length returns the length of text in user-perceived characters (grapheme
+// clusters) after NFC normalization. Each link counts as URLWeight.
+func Length(text string) int {
+	text = Normalize(text)
+	length := 0
+	last := 0
+	for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+		length += uniseg.GraphemeClusterCount(text[last:loc[0]]) + URLWeight
+		last = loc[1]
+	}
+	return length + uniseg.GraphemeClusterCount(text[last:])
+}
+
+// FindURLs returns the links in text.
+func FindURLs(text string) []string {
+	return urlPattern.FindAllString(text, -1)
+}
@@ -0,0 +1,39 @@
+package chirptext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "ASCII", text: "hello world", want: 11},
+		{name: "Hebrew", text: "שלום עולם", want: 9},
+		{name: "Emoji", text: "🐦🐦🐦", want: 3},
+		{name: "Emoji with skin tone and ZWJ sequence", text: "👍🏽👩‍👩‍👧", want: 2},
+		{name: "Flag", text: "🇮🇱", want: 1},
+		{name: "Decomposed accent", text: "cafe\u0301", want: 4},
+		{name: "Long link", text: "see https://example.com/" + strings.Repeat("a", 100), want: 4 + URLWeight},
+		{name: "Short link", text: "www.a.io", want: URLWeight},
+		{name: "Two links", text: "https://a.example and https://b.example", want: 2*URLWeight + 5},
+		{name: "Empty", text: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.text); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("cafe\u0301"); got != "caf\u00e9" {
+		t.Errorf("Normalize() = %q, want composed form", got)
+	}
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/activitypub"
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// DeleteUserHandler schedules the caller's account for deletion once the
+// grace period is over. The user is logged out everywhere, their personal
+// access tokens are revoked and third-party apps lose access. Logging in
+// again before the deletion cancels it, but doesn't bring the tokens back.
+func (apiCfg *ApiConfig) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type response struct {
+		DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	err = auth.CheckPasswordHash(user.HashedPassword, params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return
+	}
+
+	deletionScheduledAt := time.Now().Add(apiCfg.AccountDeletionGracePeriod)
+	err = apiCfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+			ID:                  user.ID,
+			DeletionScheduledAt: sql.NullTime{Time: deletionScheduledAt, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		// This covers the refresh tokens of third-party apps too, which
+		// share the table with the user's own.
+		err = q.RevokeRefreshTokensForUser(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+		_, err = q.RevokePersonalAccessTokensForUser(r.Context(), user.ID)
+		return err
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't schedule account deletion", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, response{
+		DeletionScheduledAt: deletionScheduledAt,
+	})
+}
+
+// RunAccountDeletion deletes the accounts whose grace period is over every
+// interval until ctx is cancelled. Deleting a user cascades to their chirps,
+// tokens, credentials, exports and everything else they own; moderation
+// cases and the audit trail keep their entries with the user removed, and
+// their queued federation deliveries are still sent.
+func (apiCfg *ApiConfig) RunAccountDeletion(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := apiCfg.deleteDueAccounts(ctx)
+		if err != nil {
+			log.Printf("failed to delete accounts: %s", err)
+		} else if deleted > 0 {
+			log.Printf("deleted %d accounts", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteDueAccounts deletes the accounts whose grace period is over.
+func (apiCfg *ApiConfig) deleteDueAccounts(ctx context.Context) (int64, error) {
+	userIDs, err := apiCfg.DbQueries.GetUsersDueForDeletion(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, userID := range userIDs {
+		ok, err := apiCfg.deleteAccount(ctx, userID)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// deleteAccount deletes a user whose grace period is over, along with their
+// export archives, which live outside the database, and tells their
+// followers on other servers. It reports false when the user isn't due for
+// deletion anymore.
+func (apiCfg *ApiConfig) deleteAccount(ctx context.Context, userID uuid.UUID) (bool, error) {
+	// Followers go with the user, so they're looked up first. The actor key
+	// outlives the user to sign the Delete.
+	inboxes, err := apiCfg.DbQueries.GetRemoteFollowerInboxes(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	var activity activitypub.Activity
+	if len(inboxes) > 0 {
+		_, err = apiCfg.actorKey(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		activity, err = apiCfg.actorDelete(userID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	keys, err := apiCfg.DbQueries.GetExportBlobKeysByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		err = apiCfg.Blobs.Delete(ctx, key.String)
+		if err != nil {
+			return false, err
+		}
+	}
+	deleted, err := apiCfg.DbQueries.DeleteUserDueForDeletion(ctx, userID)
+	if err != nil || deleted == 0 {
+		return false, err
+	}
+
+	for _, inbox := range inboxes {
+		err = apiCfg.queueDelivery(ctx, userID, inbox, activity)
+		if err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestDeleteUserRevokesTokens(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	user, token := createTestUser(t, apiCfg, "alice@example.com")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chirps", apiCfg.CreateChirpsHandler)
+	mux.HandleFunc("POST /api/tokens", apiCfg.CreatePersonalAccessTokenHandler)
+	mux.HandleFunc("DELETE /api/users", apiCfg.DeleteUserHandler)
+	handler := apiCfg.MiddlewareRejectSuspended(mux)
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/tokens", token, `{"name":"ci","scopes":["chirps:write"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /api/tokens = %d %s", w.Code, w.Body)
+	}
+	pat := PersonalAccessToken{}
+	json.Unmarshal(w.Body.Bytes(), &pat)
+	oauthToken, _, err := auth.MakeOAuthAccessToken(user.ID, uuid.New(), []string{auth.ScopeChirpsWrite}, apiCfg.Secret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeOAuthAccessToken() error = %v", err)
+	}
+
+	tokens := map[string]string{
+		"session token":         token,
+		"personal access token": pat.Token,
+		"OAuth access token":    oauthToken,
+	}
+	for name, token := range tokens {
+		if w := send(http.MethodPost, "/api/chirps", token, `{"body":"hello"}`); w.Code != http.StatusCreated {
+			t.Fatalf("chirping with the %s = %d %s", name, w.Code, w.Body)
+		}
+	}
+
+	if w := send(http.MethodDelete, "/api/users", token, `{"password":"password"}`); w.Code != http.StatusAccepted {
+		t.Fatalf("DELETE /api/users = %d %s", w.Code, w.Body)
+	}
+	for name, token := range tokens {
+		if w := send(http.MethodPost, "/api/chirps", token, `{"body":"hello"}`); w.Code != http.StatusUnauthorized {
+			t.Errorf("chirping with the %s after the deletion request = %d, want %d", name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestDeleteAccountOnlyDeletesThatUser(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	alice, _ := createTestUser(t, apiCfg, "alice@example.com")
+	bob, _ := createTestUser(t, apiCfg, "bob@example.com")
+	err := apiCfg.DbQueries.ScheduleUserDeletion(ctx, database.ScheduleUserDeletionParams{
+		ID:                  bob.ID,
+		DeletionScheduledAt: sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("ScheduleUserDeletion() error = %v", err)
+	}
+
+	err = apiCfg.DeleteAccount(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+	_, err = apiCfg.DbQueries.GetUserByID(ctx, alice.ID)
+	if err == nil {
+		t.Errorf("GetUserByID(alice) found the user, want them deleted")
+	}
+	_, err = apiCfg.DbQueries.GetUserByID(ctx, bob.ID)
+	if err != nil {
+		t.Errorf("GetUserByID(bob) error = %v, want the user left to the deletion job", err)
+	}
+}
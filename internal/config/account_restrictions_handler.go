@@ -0,0 +1,165 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Account state changes admins can make, recorded in the moderation audit
+// trail next to moderationActionSuspendUser.
+const (
+	moderationActionUnsuspendUser   = "unsuspend_user"
+	moderationActionShadowBanUser   = "shadow_ban_user"
+	moderationActionUnshadowBanUser = "unshadow_ban_user"
+)
+
+// AccountStatus is the state of an account as admins see it.
+type AccountStatus struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	SuspendedAt    *time.Time `json:"suspended_at"`
+	ShadowBannedAt *time.Time `json:"shadow_banned_at"`
+}
+
+// SuspendUserHandler suspends an account: the user can't log in or refresh,
+// their existing tokens stop working and their chirps are hidden.
+func (apiCfg *ApiConfig) SuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	apiCfg.changeAccountState(w, r, moderationActionSuspendUser)
+}
+
+func (apiCfg *ApiConfig) UnsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	apiCfg.changeAccountState(w, r, moderationActionUnsuspendUser)
+}
+
+// ShadowBanUserHandler shadow-bans an account: the user can keep using
+// Chirpy, but nobody else sees their chirps.
+func (apiCfg *ApiConfig) ShadowBanUserHandler(w http.ResponseWriter, r *http.Request) {
+	apiCfg.changeAccountState(w, r, moderationActionShadowBanUser)
+}
+
+func (apiCfg *ApiConfig) UnshadowBanUserHandler(w http.ResponseWriter, r *http.Request) {
+	apiCfg.changeAccountState(w, r, moderationActionUnshadowBanUser)
+}
+
+func (apiCfg *ApiConfig) changeAccountState(w http.ResponseWriter, r *http.Request, action string) {
+	admin, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse userID", err)
+		return
+	}
+
+	// The reason is optional, so an empty body is fine.
+	type parameters struct {
+		Reason string `json:"reason"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if user.Role == roleAdmin {
+		respondWithError(w, http.StatusForbidden, "Admin accounts can't be restricted", nil)
+		return
+	}
+
+	err = apiCfg.applyAccountState(r.Context(), uuid.NullUUID{UUID: admin.ID, Valid: true}, user.ID, action, params.Reason)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update account", err)
+		return
+	}
+
+	user, err = apiCfg.DbQueries.GetUserByID(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, accountStatusFromDB(user))
+}
+
+// applyAccountState applies action to an account and records it in the
+// moderation audit trail in one transaction. moderatorID is null when an
+// operator made the change from the command line.
+func (apiCfg *ApiConfig) applyAccountState(ctx context.Context, moderatorID uuid.NullUUID, userID uuid.UUID, action, reason string) error {
+	err := apiCfg.inTx(ctx, func(q *database.Queries) error {
+		var err error
+		switch action {
+		case moderationActionSuspendUser:
+			err = suspendUser(ctx, q, userID)
+		case moderationActionUnsuspendUser:
+			err = q.UnsuspendUser(ctx, userID)
+		case moderationActionShadowBanUser:
+			err = q.ShadowBanUser(ctx, userID)
+		case moderationActionUnshadowBanUser:
+			err = q.UnshadowBanUser(ctx, userID)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+			ModeratorID:  moderatorID,
+			Action:       action,
+			TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+			Note:         reason,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't record moderation action: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Restricted users are hidden from other servers too. Lifting one
+	// restriction republishes the chirps unless the other one still holds.
+	switch action {
+	case moderationActionSuspendUser, moderationActionShadowBanUser:
+		apiCfg.publishUserChirps(ctx, "Delete", userID)
+	case moderationActionUnsuspendUser, moderationActionUnshadowBanUser:
+		apiCfg.publishUserChirps(ctx, "Create", userID)
+	}
+	return nil
+}
+
+// suspendUser suspends an account and revokes its refresh tokens, so that
+// neither the user nor third-party apps can get new access tokens.
+func suspendUser(ctx context.Context, q *database.Queries, userID uuid.UUID) error {
+	err := q.SuspendUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return q.RevokeRefreshTokensForUser(ctx, userID)
+}
+
+func accountStatusFromDB(user database.User) AccountStatus {
+	status := AccountStatus{UserID: user.ID}
+	if user.SuspendedAt.Valid {
+		status.SuspendedAt = &user.SuspendedAt.Time
+	}
+	if user.ShadowBannedAt.Valid {
+		status.ShadowBannedAt = &user.ShadowBannedAt.Time
+	}
+	return status
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OferRavid/chirpy/internal/database"
+)
+
+func newAccountRestrictionsMux(apiCfg *ApiConfig) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/login", apiCfg.LoginHandler)
+	mux.HandleFunc("POST /api/refresh", apiCfg.RefreshTokenHandler)
+	mux.HandleFunc("GET /api/chirps", apiCfg.RetrieveChirpsHandler)
+	mux.HandleFunc("POST /admin/users/{userID}/suspension", apiCfg.SuspendUserHandler)
+	mux.HandleFunc("POST /admin/users/{userID}/shadow-ban", apiCfg.ShadowBanUserHandler)
+	return mux
+}
+
+func TestShadowBannedAuthorSeesOwnChirps(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	_, adminToken := createTestAdmin(t, apiCfg, "admin@example.com")
+	_, aliceToken := createTestUser(t, apiCfg, "alice@example.com")
+	bob, bobToken := createTestUser(t, apiCfg, "bob@example.com")
+	chirp, err := apiCfg.DbQueries.CreateChirp(ctx, database.CreateChirpParams{Body: "hello", UserID: bob.ID})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+
+	mux := newAccountRestrictionsMux(apiCfg)
+	send := func(method, path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send(http.MethodPost, "/admin/users/"+bob.ID.String()+"/shadow-ban", adminToken); w.Code != http.StatusOK {
+		t.Fatalf("POST /admin/users/{userID}/shadow-ban = %d %s", w.Code, w.Body)
+	}
+
+	viewers := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{name: "Anonymous"},
+		{name: "Another user", token: aliceToken},
+		{name: "The author", token: bobToken, want: true},
+	}
+	for _, viewer := range viewers {
+		t.Run(viewer.name, func(t *testing.T) {
+			w := send(http.MethodGet, "/api/chirps", viewer.token)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /api/chirps = %d %s", w.Code, w.Body)
+			}
+			chirps := []Chirp{}
+			json.Unmarshal(w.Body.Bytes(), &chirps)
+			got := false
+			for _, c := range chirps {
+				got = got || c.ID == chirp.ID
+			}
+			if got != viewer.want {
+				t.Errorf("GET /api/chirps lists the shadow-banned chirp = %v, want %v", got, viewer.want)
+			}
+		})
+	}
+}
+
+func TestSuspendUserRevokesRefreshTokens(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	_, adminToken := createTestAdmin(t, apiCfg, "admin@example.com")
+	user, _ := createTestUser(t, apiCfg, "alice@example.com")
+
+	mux := newAccountRestrictionsMux(apiCfg)
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/login", "", `{"email":"alice@example.com","password":"password"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /api/login = %d %s", w.Code, w.Body)
+	}
+	session := struct {
+		RefreshToken string `json:"refresh_token"`
+	}{}
+	json.Unmarshal(w.Body.Bytes(), &session)
+	if w := send(http.MethodPost, "/api/refresh", session.RefreshToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("POST /api/refresh before the suspension = %d %s", w.Code, w.Body)
+	}
+
+	if w := send(http.MethodPost, "/admin/users/"+user.ID.String()+"/suspension", adminToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("POST /admin/users/{userID}/suspension = %d %s", w.Code, w.Body)
+	}
+	if w := send(http.MethodPost, "/api/refresh", session.RefreshToken, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("POST /api/refresh after the suspension = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAdminsCantBeRestricted(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	_, adminToken := createTestAdmin(t, apiCfg, "admin@example.com")
+	other, _ := createTestAdmin(t, apiCfg, "other-admin@example.com")
+
+	mux := newAccountRestrictionsMux(apiCfg)
+	for _, action := range []string{"suspension", "shadow-ban"} {
+		t.Run(action, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/admin/users/"+other.ID.String()+"/"+action, nil)
+			r.Header.Set("Authorization", "Bearer "+adminToken)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("POST /admin/users/{userID}/%s of an admin = %d, want %d", action, w.Code, http.StatusForbidden)
+			}
+		})
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if user.SuspendedAt.Valid || user.ShadowBannedAt.Valid {
+		t.Errorf("admin account status = %+v, want unrestricted", accountStatusFromDB(user))
+	}
+	var actions int
+	err = apiCfg.DB.QueryRow("SELECT COUNT(*) FROM moderation_actions WHERE target_user_id = $1", other.ID).Scan(&actions)
+	if actions != 0 || err != nil {
+		t.Errorf("moderation actions recorded = %d (error %v), want 0", actions, err)
+	}
+}
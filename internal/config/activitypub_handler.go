@@ -0,0 +1,459 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/activitypub"
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// outboxLength is how many of the latest chirps an outbox lists.
+	outboxLength = 20
+	// maxActivitySize bounds the activities an inbox accepts.
+	maxActivitySize = 1 << 20
+)
+
+// RemoteNote is a post from another server addressed to a local user, like
+// a reply or a mention.
+type RemoteNote struct {
+	ID            uuid.UUID `json:"id"`
+	URI           string    `json:"uri"`
+	Content       string    `json:"content"`
+	InReplyTo     string    `json:"in_reply_to,omitempty"`
+	PublishedAt   time.Time `json:"published_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ActorURI      string    `json:"actor_uri"`
+	ActorUsername string    `json:"actor_username"`
+}
+
+// WebFingerHandler resolves acct:{userID}@{host} and actor URIs to actors,
+// which is how other servers look up an account before following it.
+func (apiCfg *ApiConfig) WebFingerHandler(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	baseURL, err := url.Parse(apiCfg.BaseURL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't parse base URL", err)
+		return
+	}
+
+	userID, ok := apiCfg.localUserID(resource)
+	if account, isAcct := strings.CutPrefix(resource, "acct:"); isAcct {
+		name, host, _ := strings.Cut(account, "@")
+		parsed, err := uuid.Parse(name)
+		userID, ok = parsed, err == nil && host == baseURL.Host
+	}
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Couldn't find resource", nil)
+		return
+	}
+	user, err := apiCfg.federatedUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	actorURI := apiCfg.actorURI(user.ID)
+	respondWithActivityJSON(w, http.StatusOK, activitypub.JRDContentType, activitypub.JRD{
+		Subject: "acct:" + user.ID.String() + "@" + baseURL.Host,
+		Aliases: []string{actorURI},
+		Links: []activitypub.JRDLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actorURI},
+		},
+	})
+}
+
+// ActorHandler serves a user's actor document, with the public key other
+// servers verify the user's activities with.
+func (apiCfg *ApiConfig) ActorHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := apiCfg.pathFederatedUser(w, r)
+	if err != nil {
+		return
+	}
+	key, err := apiCfg.actorKey(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve actor key", err)
+		return
+	}
+
+	actorURI := apiCfg.actorURI(user.ID)
+	published := user.CreatedAt.UTC()
+	respondWithActivityJSON(w, http.StatusOK, activitypub.ContentType, activitypub.Actor{
+		Context:           activitypub.Context,
+		ID:                actorURI,
+		Type:              "Person",
+		PreferredUsername: user.ID.String(),
+		URL:               apiCfg.BaseURL + "/api/chirps?author_id=" + user.ID.String(),
+		Inbox:             actorURI + "/inbox",
+		Outbox:            actorURI + "/outbox",
+		Followers:         actorURI + "/followers",
+		Endpoints:         &activitypub.Endpoints{SharedInbox: apiCfg.BaseURL + "/inbox"},
+		PublicKey: &activitypub.PublicKey{
+			ID:           actorURI + "#main-key",
+			Owner:        actorURI,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+		Published: &published,
+	})
+}
+
+// OutboxHandler lists a user's latest chirps as Create activities.
+func (apiCfg *ApiConfig) OutboxHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := apiCfg.pathFederatedUser(w, r)
+	if err != nil {
+		return
+	}
+
+	total, err := apiCfg.DbQueries.CountPublicChirpsByUserID(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count chirps", err)
+		return
+	}
+	dbChirps, err := apiCfg.DbQueries.GetPublicChirpsByUserID(r.Context(), database.GetPublicChirpsByUserIDParams{
+		UserID: user.ID,
+		Limit:  outboxLength,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	items := []any{}
+	for _, dbChirp := range dbChirps {
+		activity, err := apiCfg.chirpActivity("Create", dbChirp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't build activity", err)
+			return
+		}
+		activity.Context = nil
+		items = append(items, activity)
+	}
+
+	respondWithActivityJSON(w, http.StatusOK, activitypub.ContentType, activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           apiCfg.actorURI(user.ID) + "/outbox",
+		Type:         "OrderedCollection",
+		TotalItems:   total,
+		OrderedItems: items,
+	})
+}
+
+// FollowersHandler serves how many followers a user has on other servers,
+// without listing them.
+func (apiCfg *ApiConfig) FollowersHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := apiCfg.pathFederatedUser(w, r)
+	if err != nil {
+		return
+	}
+
+	total, err := apiCfg.DbQueries.CountRemoteFollowers(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't count followers", err)
+		return
+	}
+	respondWithActivityJSON(w, http.StatusOK, activitypub.ContentType, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         apiCfg.actorURI(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: total,
+	})
+}
+
+// NoteHandler serves a chirp as a Note, so other servers can dereference
+// the IDs of the activities they receive.
+func (apiCfg *ApiConfig) NoteHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := apiCfg.pathFederatedUser(w, r)
+	if err != nil {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse chirpID", err)
+		return
+	}
+	chirp, err := apiCfg.DbQueries.GetChirpByID(r.Context(), chirpID)
+	if err != nil || chirp.UserID != user.ID || chirp.HiddenAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp with the given ID", err)
+		return
+	}
+
+	note := apiCfg.chirpNote(chirp)
+	note.Context = activitypub.Context
+	respondWithActivityJSON(w, http.StatusOK, activitypub.ContentType, note)
+}
+
+// InboxHandler receives activities from other servers, both on users'
+// inboxes and on the shared inbox. Every activity must be signed by the
+// actor it claims to come from.
+func (apiCfg *ApiConfig) InboxHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxActivitySize))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read activity", err)
+		return
+	}
+	activity := activitypub.Activity{}
+	err = json.Unmarshal(body, &activity)
+	if err != nil || activity.Type == "" || activity.Actor == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid activity", err)
+		return
+	}
+	sig, err := activitypub.ParseSignature(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed signature", err)
+		return
+	}
+
+	// Deleted actors can't be fetched anymore, so there's no way to verify
+	// their farewell, and nothing to delete if they were never cached.
+	if activity.Type == "Delete" && activity.ObjectID() == activity.Actor {
+		_, err = apiCfg.DbQueries.GetRemoteActorByURI(r.Context(), activity.Actor)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
+	remoteActor, err := apiCfg.verifyInboxRequest(r, body, sig)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't verify signature", err)
+		return
+	}
+	if remoteActor.Uri != activity.Actor {
+		respondWithError(w, http.StatusForbidden, "Activity wasn't signed by its actor", nil)
+		return
+	}
+
+	err = apiCfg.handleActivity(r.Context(), remoteActor, activity)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't process activity", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ListRemoteNotesHandler lists the posts from other servers addressed to
+// the caller.
+func (apiCfg *ApiConfig) ListRemoteNotesHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsRead)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	dbNotes, err := apiCfg.DbQueries.GetRemoteNotesForUser(r.Context(), database.GetRemoteNotesForUserParams{
+		UserID: user_id,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve notes", err)
+		return
+	}
+
+	notes := []RemoteNote{}
+	for _, dbNote := range dbNotes {
+		notes = append(notes, RemoteNote{
+			ID:            dbNote.ID,
+			URI:           dbNote.Uri,
+			Content:       dbNote.Content,
+			InReplyTo:     dbNote.InReplyTo.String,
+			PublishedAt:   dbNote.PublishedAt,
+			UpdatedAt:     dbNote.UpdatedAt,
+			ActorURI:      dbNote.ActorUri,
+			ActorUsername: dbNote.PreferredUsername,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, notes)
+}
+
+// handleActivity applies a verified activity from remoteActor. Activities
+// Chirpy doesn't support, or that don't concern its users, are ignored.
+func (apiCfg *ApiConfig) handleActivity(ctx context.Context, remoteActor database.RemoteActor, activity activitypub.Activity) error {
+	switch activity.Type {
+	case "Follow":
+		return apiCfg.handleFollow(ctx, remoteActor, activity)
+	case "Undo":
+		return apiCfg.handleUndo(ctx, remoteActor, activity)
+	case "Create", "Update":
+		return apiCfg.handleNote(ctx, remoteActor, activity)
+	case "Delete":
+		objectID := activity.ObjectID()
+		if objectID == remoteActor.Uri {
+			_, err := apiCfg.DbQueries.DeleteRemoteActor(ctx, remoteActor.ID)
+			return err
+		}
+		_, err := apiCfg.DbQueries.DeleteRemoteNote(ctx, database.DeleteRemoteNoteParams{
+			Uri:     objectID,
+			ActorID: remoteActor.ID,
+		})
+		return err
+	}
+	return nil
+}
+
+// handleFollow records a remote follower and accepts the follow right
+// away, as Chirpy accounts have no follow requests to approve.
+func (apiCfg *ApiConfig) handleFollow(ctx context.Context, remoteActor database.RemoteActor, activity activitypub.Activity) error {
+	userID, ok := apiCfg.localUserID(activity.ObjectID())
+	if !ok {
+		return nil
+	}
+	_, err := apiCfg.federatedUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = apiCfg.DbQueries.CreateRemoteFollower(ctx, database.CreateRemoteFollowerParams{
+		UserID:    userID,
+		ActorID:   remoteActor.ID,
+		FollowUri: activity.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	follow, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	actorURI := apiCfg.actorURI(userID)
+	return apiCfg.queueDelivery(ctx, userID, remoteActor.Inbox, activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      actorURI + "#accepts/" + uuid.NewString(),
+		Type:    "Accept",
+		Actor:   actorURI,
+		Object:  follow,
+	})
+}
+
+// handleUndo removes a remote follower. The undone Follow may be embedded
+// or only referenced by its ID.
+func (apiCfg *ApiConfig) handleUndo(ctx context.Context, remoteActor database.RemoteActor, activity activitypub.Activity) error {
+	follow := activitypub.Activity{}
+	if json.Unmarshal(activity.Object, &follow) == nil && follow.Type == "Follow" {
+		if userID, ok := apiCfg.localUserID(follow.ObjectID()); ok {
+			_, err := apiCfg.DbQueries.DeleteRemoteFollower(ctx, database.DeleteRemoteFollowerParams{
+				UserID:  userID,
+				ActorID: remoteActor.ID,
+			})
+			return err
+		}
+	}
+	_, err := apiCfg.DbQueries.DeleteRemoteFollowerByFollowURI(ctx, database.DeleteRemoteFollowerByFollowURIParams{
+		ActorID:   remoteActor.ID,
+		FollowUri: activity.ObjectID(),
+	})
+	return err
+}
+
+// handleNote stores a created or updated note when it's addressed to local
+// users. Notes that aren't are none of Chirpy's business.
+func (apiCfg *ApiConfig) handleNote(ctx context.Context, remoteActor database.RemoteActor, activity activitypub.Activity) error {
+	if activity.ObjectType() != "Note" {
+		return nil
+	}
+	note := activitypub.Note{}
+	err := json.Unmarshal(activity.Object, &note)
+	if err != nil {
+		return nil
+	}
+	if note.AttributedTo != remoteActor.Uri {
+		return nil
+	}
+
+	recipients := []uuid.UUID{}
+	for _, recipient := range append(note.To, note.Cc...) {
+		userID, ok := apiCfg.localUserID(recipient)
+		if !ok {
+			continue
+		}
+		_, err := apiCfg.federatedUser(ctx, userID)
+		if err == nil {
+			recipients = append(recipients, userID)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	publishedAt := note.Published
+	if publishedAt.IsZero() {
+		publishedAt = time.Now()
+	}
+	dbNote, err := apiCfg.DbQueries.UpsertRemoteNote(ctx, database.UpsertRemoteNoteParams{
+		Uri:         note.ID,
+		ActorID:     remoteActor.ID,
+		Content:     activitypub.PlainText(note.Content),
+		InReplyTo:   sql.NullString{String: note.InReplyTo, Valid: note.InReplyTo != ""},
+		PublishedAt: publishedAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// The note belongs to another actor.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, userID := range recipients {
+		err = apiCfg.DbQueries.AddRemoteNoteRecipient(ctx, database.AddRemoteNoteRecipientParams{
+			NoteID: dbNote.ID,
+			UserID: userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pathFederatedUser reads the user in the path of an ActivityPub request,
+// responding with an error when they can't be found.
+func (apiCfg *ApiConfig) pathFederatedUser(w http.ResponseWriter, r *http.Request) (database.User, error) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse userID", err)
+		return database.User{}, err
+	}
+	user, err := apiCfg.federatedUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+func respondWithActivityJSON(w http.ResponseWriter, code int, contentType string, payload any) {
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	w.Write(dat)
+}
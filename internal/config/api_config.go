@@ -1,10 +1,19 @@
 package config
 
 import (
+	"context"
+	"database/sql"
 	"sync/atomic"
 	"time"
 
+	"github.com/OferRavid/chirpy/internal/activitypub"
+	"github.com/OferRavid/chirpy/internal/blobstore"
 	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/moderation"
+	"github.com/OferRavid/chirpy/internal/oidc"
+	"github.com/OferRavid/chirpy/internal/payments"
+	"github.com/OferRavid/chirpy/internal/ratelimit"
+	"github.com/OferRavid/chirpy/internal/webauthn"
 	"github.com/google/uuid"
 )
 
@@ -13,7 +22,49 @@ type ApiConfig struct {
 	DbQueries      *database.Queries
 	Platform       string
 	Secret         string
-	ApiKey         string
+	Payments       payments.PaymentProvider
+	WebAuthn       webauthn.RelyingParty
+	// DB is the connection DbQueries runs on, for changes that need a
+	// transaction.
+	DB *sql.DB
+	// ChirpLimiter enforces each plan's chirps per minute. It counts per
+	// process, so with several instances the limit applies to each one.
+	ChirpLimiter  *ratelimit.Limiter
+	ContentFilter atomic.Pointer[moderation.Pipeline]
+	// OIDC is nil when single sign-on isn't configured.
+	OIDC *oidc.Provider
+	// ReportAutoHideThreshold is how many reports hide a chirp until a
+	// moderator reviews it. Zero turns auto-hiding off.
+	ReportAutoHideThreshold int
+	// AccountDeletionGracePeriod is how long a user has to change their mind
+	// after asking for their account to be deleted.
+	AccountDeletionGracePeriod time.Duration
+	// IdempotencyKeyRetention is how long the response to a request sent
+	// with an Idempotency-Key is replayed for retries.
+	IdempotencyKeyRetention time.Duration
+	// Blobs stores data export archives.
+	Blobs blobstore.Store
+	// BaseURL is the public address of the server, used for absolute links
+	// in feeds and as the base of ActivityPub IDs.
+	BaseURL string
+	// Federation talks to other ActivityPub servers.
+	Federation *activitypub.Client
+}
+
+// inTx runs fn on queries in a transaction, which is committed when fn
+// returns nil and rolled back otherwise.
+func (apiCfg *ApiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := apiCfg.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(apiCfg.DbQueries.WithTx(tx))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 type User struct {
@@ -30,4 +81,10 @@ type Chirp struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Body      string    `json:"body"`
 	UserID    uuid.UUID `json:"user_id"`
+	// Filtered is set when the chirp matches one of the viewer's muted
+	// words, so clients can collapse it behind a warning.
+	Filtered bool `json:"filtered,omitempty"`
+	// PublishAt is when a scheduled chirp goes live. Until then, only its
+	// author sees it.
+	PublishAt *time.Time `json:"publish_at,omitempty"`
 }
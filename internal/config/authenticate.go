@@ -0,0 +1,175 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Roles a user can have. Everyone starts as roleUser; moderators and admins
+// are promoted directly in the database.
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+// authenticateAdmin resolves the user behind a login session and checks
+// that they're an admin, responding with an error when they aren't.
+func (apiCfg *ApiConfig) authenticateAdmin(w http.ResponseWriter, r *http.Request) (database.User, error) {
+	return apiCfg.authenticateRole(w, r, "Admin", roleAdmin)
+}
+
+// authenticateModerator is authenticateAdmin for the moderation queue, which
+// admins can work on too.
+func (apiCfg *ApiConfig) authenticateModerator(w http.ResponseWriter, r *http.Request) (database.User, error) {
+	return apiCfg.authenticateRole(w, r, "Moderator", roleModerator, roleAdmin)
+}
+
+func (apiCfg *ApiConfig) authenticateRole(w http.ResponseWriter, r *http.Request, name string, roles ...string) (database.User, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return database.User{}, err
+	}
+	userID, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return database.User{}, err
+	}
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find user", err)
+		return database.User{}, err
+	}
+	if !slices.Contains(roles, user.Role) {
+		err = fmt.Errorf("user %v has role %s, not one of %v", user.ID, user.Role, roles)
+		respondWithError(w, http.StatusForbidden, name+" access required", err)
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+// validateBearerToken resolves the user behind a bearer token. Access JWTs
+// carry the full rights of a login session; personal access tokens and
+// tokens issued to third-party OAuth clients are only accepted when they
+// were granted scope. Pass an empty scope for endpoints that must only be
+// reachable from a login session.
+func (apiCfg *ApiConfig) validateBearerToken(ctx context.Context, token, scope string) (uuid.UUID, error) {
+	if !auth.IsPersonalAccessToken(token) {
+		userID, err := auth.ValidateJWT(token, apiCfg.Secret)
+		if err == nil {
+			return userID, nil
+		}
+		accessToken, oauthErr := auth.ValidateOAuthAccessToken(token, apiCfg.Secret)
+		if oauthErr != nil {
+			return uuid.Nil, err
+		}
+		return apiCfg.checkOAuthAccessToken(ctx, accessToken, scope)
+	}
+	if scope == "" {
+		return uuid.Nil, errors.New("personal access tokens can't be used for this endpoint")
+	}
+
+	pat, err := apiCfg.DbQueries.GetPersonalAccessTokenByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unknown personal access token: %w", err)
+	}
+	if pat.RevokedAt.Valid {
+		return uuid.Nil, fmt.Errorf("personal access token %v was revoked", pat.ID)
+	}
+	if pat.ExpiresAt.Valid && time.Now().After(pat.ExpiresAt.Time) {
+		return uuid.Nil, fmt.Errorf("personal access token %v expired", pat.ID)
+	}
+	if !auth.HasScope(pat.Scopes, scope) {
+		return uuid.Nil, fmt.Errorf("personal access token %v lacks scope %s", pat.ID, scope)
+	}
+
+	err = apiCfg.DbQueries.UpdatePersonalAccessTokenLastUsed(ctx, pat.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return pat.UserID, nil
+}
+
+func (apiCfg *ApiConfig) checkOAuthAccessToken(ctx context.Context, accessToken auth.OAuthAccessToken, scope string) (uuid.UUID, error) {
+	if scope == "" {
+		return uuid.Nil, errors.New("third-party access tokens can't be used for this endpoint")
+	}
+	if !auth.HasScope(accessToken.Scopes, scope) {
+		return uuid.Nil, fmt.Errorf("access token %v lacks scope %s", accessToken.ID, scope)
+	}
+
+	revoked, err := apiCfg.DbQueries.IsOauthAccessTokenRevoked(ctx, accessToken.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if revoked {
+		return uuid.Nil, fmt.Errorf("access token %v was revoked", accessToken.ID)
+	}
+	return accessToken.UserID, nil
+}
+
+// MiddlewareRejectSuspended turns away requests carrying an access token of
+// a suspended user, or of a user who asked for their account to be deleted.
+// Access tokens can't be revoked one by one, so this is what cuts off the
+// tokens a user held at the time. Logging in again cancels a deletion, and
+// the new tokens work.
+func (apiCfg *ApiConfig) MiddlewareRejectSuspended(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, ok := apiCfg.accessTokenUser(r.Context(), token)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		state, err := apiCfg.DbQueries.GetUserAccessState(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check account status", err)
+			return
+		}
+		if state.Suspended {
+			respondWithError(w, http.StatusUnauthorized, "Account suspended", nil)
+			return
+		}
+		if state.DeletionScheduled {
+			respondWithError(w, http.StatusUnauthorized, "Account is scheduled for deletion, log in to cancel it", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// accessTokenUser returns the user an access token belongs to, without
+// checking scopes or revocation. Tokens that aren't access tokens, like
+// refresh tokens, aren't recognized.
+func (apiCfg *ApiConfig) accessTokenUser(ctx context.Context, token string) (uuid.UUID, bool) {
+	if auth.IsPersonalAccessToken(token) {
+		pat, err := apiCfg.DbQueries.GetPersonalAccessTokenByHash(ctx, auth.HashToken(token))
+		if err != nil {
+			return uuid.Nil, false
+		}
+		return pat.UserID, true
+	}
+	userID, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err == nil {
+		return userID, true
+	}
+	accessToken, err := auth.ValidateOAuthAccessToken(token, apiCfg.Secret)
+	if err == nil {
+		return accessToken.UserID, true
+	}
+	return uuid.Nil, false
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// UserRelationship is a block or a mute, seen from the user who made it.
+type UserRelationship struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlockUserHandler blocks another user. Blocked users don't see the
+// blocker's chirps, and the blocker doesn't see theirs.
+func (apiCfg *ApiConfig) BlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	user_id, targetID, err := apiCfg.decodeRelationshipTarget(w, r)
+	if err != nil {
+		return
+	}
+
+	err = apiCfg.DbQueries.CreateBlock(r.Context(), database.CreateBlockParams{
+		BlockerID: user_id,
+		BlockedID: targetID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't block user", err)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (apiCfg *ApiConfig) ListBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsRead)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbBlocks, err := apiCfg.DbQueries.GetBlocksByBlockerID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve blocks", err)
+		return
+	}
+
+	blocks := []UserRelationship{}
+	for _, dbBlock := range dbBlocks {
+		blocks = append(blocks, UserRelationship{
+			UserID:    dbBlock.BlockedID,
+			CreatedAt: dbBlock.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, blocks)
+}
+
+func (apiCfg *ApiConfig) UnblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	blockedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse userID", err)
+		return
+	}
+
+	deleted, err := apiCfg.DbQueries.DeleteBlock(r.Context(), database.DeleteBlockParams{
+		BlockerID: user_id,
+		BlockedID: blockedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unblock user", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "User isn't blocked", nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// MuteUserHandler mutes another user, hiding their chirps from the muting
+// user without them knowing.
+func (apiCfg *ApiConfig) MuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	user_id, targetID, err := apiCfg.decodeRelationshipTarget(w, r)
+	if err != nil {
+		return
+	}
+
+	err = apiCfg.DbQueries.CreateMute(r.Context(), database.CreateMuteParams{
+		MuterID: user_id,
+		MutedID: targetID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't mute user", err)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (apiCfg *ApiConfig) ListMutesHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsRead)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbMutes, err := apiCfg.DbQueries.GetMutesByMuterID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve mutes", err)
+		return
+	}
+
+	mutes := []UserRelationship{}
+	for _, dbMute := range dbMutes {
+		mutes = append(mutes, UserRelationship{
+			UserID:    dbMute.MutedID,
+			CreatedAt: dbMute.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, mutes)
+}
+
+func (apiCfg *ApiConfig) UnmuteUserHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	mutedID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse userID", err)
+		return
+	}
+
+	deleted, err := apiCfg.DbQueries.DeleteMute(r.Context(), database.DeleteMuteParams{
+		MuterID: user_id,
+		MutedID: mutedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unmute user", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "User isn't muted", nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// decodeRelationshipTarget authenticates a block or mute request and reads
+// the user it targets, responding with an error when either is invalid.
+func (apiCfg *ApiConfig) decodeRelationshipTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return uuid.Nil, uuid.Nil, err
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	type parameters struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return uuid.Nil, uuid.Nil, err
+	}
+	if params.UserID == user_id {
+		err = errors.New("user targeted themselves")
+		respondWithError(w, http.StatusBadRequest, "You can't block or mute yourself", err)
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	_, err = apiCfg.DbQueries.GetUserByID(r.Context(), params.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return uuid.Nil, uuid.Nil, err
+	}
+	return user_id, params.UserID, nil
+}
+
+// optionalViewer returns the user reading chirps, if the request is
+// authenticated. Reading chirps doesn't need a token, so a missing, expired
+// or otherwise invalid one makes the request anonymous rather than failing.
+func (apiCfg *ApiConfig) optionalViewer(r *http.Request) uuid.NullUUID {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsRead)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// hiddenAuthors returns the authors whose chirps viewer shouldn't see:
+// suspended and shadow-banned users other than the viewer, and for signed
+// in viewers, users they blocked or muted and users who blocked them.
+func (apiCfg *ApiConfig) hiddenAuthors(ctx context.Context, viewer uuid.NullUUID) (map[uuid.UUID]bool, error) {
+	hidden := map[uuid.UUID]bool{}
+	restricted, err := apiCfg.DbQueries.GetRestrictedUserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range restricted {
+		hidden[userID] = true
+	}
+	if !viewer.Valid {
+		return hidden, nil
+	}
+	// Shadow-banned users still see their own chirps.
+	delete(hidden, viewer.UUID)
+
+	userIDs, err := apiCfg.DbQueries.GetHiddenAuthorIDs(ctx, viewer.UUID)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		hidden[userID] = true
+	}
+	return hidden, nil
+}
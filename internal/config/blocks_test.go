@@ -0,0 +1,48 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+func TestOptionalViewer(t *testing.T) {
+	apiCfg := &ApiConfig{Secret: testSecret}
+	userID := uuid.New()
+	token, err := auth.MakeJWT(userID, testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	expired, err := auth.MakeJWT(userID, testSecret, -time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          uuid.NullUUID
+	}{
+		{name: "Anonymous"},
+		{name: "Signed in", authorization: "Bearer " + token, want: uuid.NullUUID{UUID: userID, Valid: true}},
+		{name: "Expired token", authorization: "Bearer " + expired},
+		{name: "Garbage token", authorization: "Bearer garbage"},
+		{name: "Not a bearer token", authorization: "Basic dXNlcjpwYXNz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if got := apiCfg.optionalViewer(r); got != tt.want {
+				t.Errorf("optionalViewer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
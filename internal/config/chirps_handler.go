@@ -1,18 +1,23 @@
 package config
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"sort"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/chirptext"
 	"github.com/OferRavid/chirpy/internal/database"
 	"github.com/google/uuid"
 )
 
+// CreateChirpsHandler posts a chirp, or schedules it when publish_at is
+// set and the author's plan allows scheduling.
 func (apiCfg *ApiConfig) CreateChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	bearerToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -21,7 +26,8 @@ func (apiCfg *ApiConfig) CreateChirpsHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string     `json:"body"`
+		PublishAt *time.Time `json:"publish_at"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -32,15 +38,28 @@ func (apiCfg *ApiConfig) CreateChirpsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	user_id, err := auth.ValidateJWT(bearerToken, apiCfg.Secret)
+	user_id, err := apiCfg.validateBearerToken(r.Context(), bearerToken, auth.ScopeChirpsWrite)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
 		return
 	}
 
-	cleaned, err := validateChirp(params.Body)
+	cleaned, err := apiCfg.prepareChirp(w, r, user_id, params.Body, params.PublishAt)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if params.PublishAt != nil {
+		chirp, err := apiCfg.DbQueries.ScheduleChirp(r.Context(), database.ScheduleChirpParams{
+			Body:      cleaned,
+			UserID:    user_id,
+			PublishAt: sql.NullTime{Time: params.PublishAt.UTC(), Valid: true},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't schedule chirp", err)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, chirpFromDB(chirp))
 		return
 	}
 
@@ -52,6 +71,7 @@ func (apiCfg *ApiConfig) CreateChirpsHandler(w http.ResponseWriter, r *http.Requ
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
 		return
 	}
+	apiCfg.publishChirp(r.Context(), "Create", chirp)
 
 	respondWithJSON(w, http.StatusCreated, Chirp{
 		ID:        chirp.ID,
@@ -62,9 +82,58 @@ func (apiCfg *ApiConfig) CreateChirpsHandler(w http.ResponseWriter, r *http.Requ
 	})
 }
 
+// prepareChirp runs the checks every new chirp goes through: the author's
+// plan, including scheduling when publishAt is set, the rate limit and
+// validateChirp. It returns the cleaned body, or responds with an error.
+func (apiCfg *ApiConfig) prepareChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID, body string, publishAt *time.Time) (string, error) {
+	entitlements, err := apiCfg.DbQueries.GetUserEntitlements(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve plan", err)
+		return "", err
+	}
+	if publishAt != nil {
+		err = checkPublishAt(w, entitlements, *publishAt)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	allowed, retryAfter := apiCfg.ChirpLimiter.Allow(userID.String(), int(entitlements.ChirpsPerMinute), time.Now())
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())))
+		err = fmt.Errorf("user %v is over the chirp rate limit", userID)
+		respondWithError(w, http.StatusTooManyRequests, "Too many chirps, slow down", nil)
+		return "", err
+	}
+
+	cleaned, err := apiCfg.validateChirp(body, int(entitlements.MaxChirpLength))
+	if err != nil {
+		respondWithChirpError(w, err)
+		return "", err
+	}
+	return cleaned, nil
+}
+
+// RetrieveChirpsHandler lists chirps, leaving out those of suspended and
+// shadow-banned users. Authenticated viewers don't see chirps from users
+// they blocked or muted, or from users who blocked them, and their muted
+// words hide or flag matching chirps.
 func (apiCfg *ApiConfig) RetrieveChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	author_id := r.URL.Query().Get("author_id")
 	sortType := r.URL.Query().Get("sort")
+
+	viewer := apiCfg.optionalViewer(r)
+	hiddenAuthors, err := apiCfg.hiddenAuthors(r.Context(), viewer)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve hidden authors", err)
+		return
+	}
+	chirpFilter, err := apiCfg.chirpFilterFor(r.Context(), viewer)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve muted words", err)
+		return
+	}
+
 	dbChirps, err := apiCfg.DbQueries.GetChirps(r.Context())
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
@@ -76,12 +145,20 @@ func (apiCfg *ApiConfig) RetrieveChirpsHandler(w http.ResponseWriter, r *http.Re
 		if author_id != "" && author_id != dbChirp.UserID.String() {
 			continue
 		}
+		if hiddenAuthors[dbChirp.UserID] {
+			continue
+		}
+		hide, filtered := chirpFilter.apply(dbChirp)
+		if hide {
+			continue
+		}
 		chirps = append(chirps, Chirp{
 			ID:        dbChirp.ID,
 			CreatedAt: dbChirp.CreatedAt,
 			UpdatedAt: dbChirp.UpdatedAt,
 			UserID:    dbChirp.UserID,
 			Body:      dbChirp.Body,
+			Filtered:  filtered,
 		})
 	}
 
@@ -98,11 +175,45 @@ func (apiCfg *ApiConfig) GetChirpsHandler(w http.ResponseWriter, r *http.Request
 		respondWithError(w, http.StatusBadRequest, "Failed to parse chirpID", err)
 		return
 	}
+	viewer := apiCfg.optionalViewer(r)
 	chirp, err := apiCfg.DbQueries.GetChirpByID(r.Context(), chirpID)
-	if err != nil {
+	if err != nil || chirp.HiddenAt.Valid {
 		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", err)
 		return
 	}
+	author, err := apiCfg.DbQueries.GetUserByID(r.Context(), chirp.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp author", err)
+		return
+	}
+	isAuthor := viewer.Valid && viewer.UUID == author.ID
+	if (author.SuspendedAt.Valid || author.ShadowBannedAt.Valid) && !isAuthor {
+		respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", nil)
+		return
+	}
+	if viewer.Valid {
+		blocked, err := apiCfg.DbQueries.IsBlocked(r.Context(), database.IsBlockedParams{
+			BlockerID: chirp.UserID,
+			BlockedID: viewer.UUID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+			return
+		}
+		if blocked {
+			respondWithError(w, http.StatusNotFound, "Couldn't retrieve chirp", nil)
+			return
+		}
+	}
+
+	// A chirp asked for by ID is returned even if a muted word would hide
+	// it from listings, flagged so the client can warn first.
+	chirpFilter, err := apiCfg.chirpFilterFor(r.Context(), viewer)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve muted words", err)
+		return
+	}
+	_, filtered := chirpFilter.apply(chirp)
 
 	respondWithJSON(w, http.StatusOK, Chirp{
 		ID:        chirp.ID,
@@ -110,34 +221,142 @@ func (apiCfg *ApiConfig) GetChirpsHandler(w http.ResponseWriter, r *http.Request
 		UpdatedAt: chirp.UpdatedAt,
 		Body:      chirp.Body,
 		UserID:    chirp.UserID,
+		Filtered:  filtered,
 	})
 }
 
-func validateChirp(body string) (string, error) {
-	const maxChirpLength = 140
-	if len(body) > maxChirpLength {
-		return "", errors.New("Chirp is too long")
+// EditChirpHandler replaces the body of a chirp, as long as the author's
+// plan allows edits and the chirp is still inside the edit window.
+func (apiCfg *ApiConfig) EditChirpHandler(w http.ResponseWriter, r *http.Request) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing token in Authorization header", err)
+		return
 	}
 
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+	type parameters struct {
+		Body string `json:"body"`
 	}
-	cleaned := cleaner(body, badWords)
-	return cleaned, nil
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user_id, err := apiCfg.validateBearerToken(r.Context(), bearerToken, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse chirpID", err)
+		return
+	}
+	chirp, err := apiCfg.DbQueries.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp with the given ID", err)
+		return
+	}
+	if chirp.UserID != user_id {
+		respondWithError(w, http.StatusForbidden, "Unauthorized to edit chirp", nil)
+		return
+	}
+
+	entitlements, err := apiCfg.DbQueries.GetUserEntitlements(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve plan", err)
+		return
+	}
+	editWindow := time.Duration(entitlements.EditWindowSeconds) * time.Second
+	if time.Since(chirp.CreatedAt) > editWindow {
+		respondWithError(w, http.StatusForbidden, "Chirp can no longer be edited", nil)
+		return
+	}
+
+	cleaned, err := apiCfg.validateChirp(params.Body, int(entitlements.MaxChirpLength))
+	if err != nil {
+		respondWithChirpError(w, err)
+		return
+	}
+
+	chirp, err = apiCfg.DbQueries.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:   chirp.ID,
+		Body: cleaned,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update chirp", err)
+		return
+	}
+	apiCfg.publishChirp(r.Context(), "Update", chirp)
+
+	respondWithJSON(w, http.StatusOK, Chirp{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+	})
 }
 
-func cleaner(body string, badWords map[string]struct{}) string {
-	words := strings.Split(body, " ")
-	for i, word := range words {
-		loweredWord := strings.ToLower(word)
-		if _, ok := badWords[loweredWord]; ok {
-			words[i] = "****"
-		}
+// chirpTooLongError reports a chirp's length, measured the way users count
+// characters, along with how far over the limit it is.
+type chirpTooLongError struct {
+	Length    int
+	MaxLength int
+}
+
+func (e chirpTooLongError) Error() string {
+	return "Chirp is too long"
+}
+
+// validateChirp checks a chirp body against the author's length limit and
+// the content rules, and returns it NFC normalized with masked words
+// replaced.
+func (apiCfg *ApiConfig) validateChirp(body string, maxChirpLength int) (string, error) {
+	body = chirptext.Normalize(body)
+	length := chirptext.Length(body)
+	if length > maxChirpLength {
+		return "", chirpTooLongError{Length: length, MaxLength: maxChirpLength}
+	}
+
+	contentFilter := apiCfg.ContentFilter.Load()
+	if contentFilter == nil {
+		return "", errors.New("content rules aren't loaded")
+	}
+	result := contentFilter.Check(body)
+	if result.Rejected {
+		return "", errors.New("Chirp violates the content rules")
+	}
+	return result.Text, nil
+}
+
+// respondWithChirpError writes the response for a chirp validateChirp
+// refused. Chirps that are too long get their length and remaining
+// characters so clients can show how much to cut.
+func respondWithChirpError(w http.ResponseWriter, err error) {
+	type tooLongResponse struct {
+		Error     string `json:"error"`
+		Length    int    `json:"length"`
+		MaxLength int    `json:"max_length"`
+		Remaining int    `json:"remaining"`
+	}
+
+	tooLong := chirpTooLongError{}
+	if errors.As(err, &tooLong) {
+		respondWithJSON(w, http.StatusBadRequest, tooLongResponse{
+			Error:     tooLong.Error(),
+			Length:    tooLong.Length,
+			MaxLength: tooLong.MaxLength,
+			Remaining: tooLong.MaxLength - tooLong.Length,
+		})
+		return
 	}
-	cleaned := strings.Join(words, " ")
-	return cleaned
+	respondWithError(w, http.StatusBadRequest, err.Error(), err)
 }
 
 func (apiCfg *ApiConfig) DeleteChirpsHandler(w http.ResponseWriter, r *http.Request) {
@@ -147,7 +366,7 @@ func (apiCfg *ApiConfig) DeleteChirpsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	user_id, err := auth.ValidateJWT(bearerToken, apiCfg.Secret)
+	user_id, err := apiCfg.validateBearerToken(r.Context(), bearerToken, auth.ScopeChirpsWrite)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
 		return
@@ -181,6 +400,7 @@ func (apiCfg *ApiConfig) DeleteChirpsHandler(w http.ResponseWriter, r *http.Requ
 		respondWithError(w, http.StatusInternalServerError, "Failed to delete chirp", err)
 		return
 	}
+	apiCfg.publishChirp(r.Context(), "Delete", chirp)
 
 	respondWithJSON(w, http.StatusNoContent, nil)
 }
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+type ContentRule struct {
+	ID        uuid.UUID           `json:"id"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+	Kind      moderation.RuleKind `json:"kind"`
+	Pattern   string              `json:"pattern"`
+	Action    moderation.Action   `json:"action"`
+}
+
+// ReloadContentRules rebuilds the content filter from the rules in the
+// database. Chirps that are being checked keep using the previous filter.
+func (apiCfg *ApiConfig) ReloadContentRules(ctx context.Context) error {
+	dbRules, err := apiCfg.DbQueries.GetContentRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := []moderation.Rule{}
+	for _, dbRule := range dbRules {
+		rules = append(rules, moderation.Rule{
+			ID:      dbRule.ID,
+			Kind:    moderation.RuleKind(dbRule.Kind),
+			Pattern: dbRule.Pattern,
+			Action:  moderation.Action(dbRule.Action),
+		})
+	}
+	pipeline, err := moderation.New(rules)
+	if err != nil {
+		return err
+	}
+	apiCfg.ContentFilter.Store(pipeline)
+	return nil
+}
+
+// RunContentRuleReload reloads the content rules every interval until ctx is
+// cancelled, so edits made through another server show up here too.
+func (apiCfg *ApiConfig) RunContentRuleReload(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := apiCfg.ReloadContentRules(ctx)
+		if err != nil {
+			log.Printf("failed to reload content rules: %s", err)
+		}
+	}
+}
+
+func (apiCfg *ApiConfig) ListContentRulesHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	dbRules, err := apiCfg.DbQueries.GetContentRules(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve content rules", err)
+		return
+	}
+
+	rules := []ContentRule{}
+	for _, dbRule := range dbRules {
+		rules = append(rules, contentRuleFromDB(dbRule))
+	}
+	respondWithJSON(w, http.StatusOK, rules)
+}
+
+func (apiCfg *ApiConfig) CreateContentRuleHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	params, err := decodeContentRule(w, r)
+	if err != nil {
+		return
+	}
+
+	dbRule, err := apiCfg.DbQueries.CreateContentRule(r.Context(), database.CreateContentRuleParams{
+		Kind:    string(params.Kind),
+		Pattern: params.Pattern,
+		Action:  string(params.Action),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create content rule", err)
+		return
+	}
+
+	err = apiCfg.ReloadContentRules(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload content rules", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, contentRuleFromDB(dbRule))
+}
+
+func (apiCfg *ApiConfig) UpdateContentRuleHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse ruleID", err)
+		return
+	}
+	params, err := decodeContentRule(w, r)
+	if err != nil {
+		return
+	}
+
+	dbRule, err := apiCfg.DbQueries.UpdateContentRule(r.Context(), database.UpdateContentRuleParams{
+		ID:      ruleID,
+		Kind:    string(params.Kind),
+		Pattern: params.Pattern,
+		Action:  string(params.Action),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Couldn't find content rule", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update content rule", err)
+		return
+	}
+
+	err = apiCfg.ReloadContentRules(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload content rules", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, contentRuleFromDB(dbRule))
+}
+
+func (apiCfg *ApiConfig) DeleteContentRuleHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse ruleID", err)
+		return
+	}
+
+	deleted, err := apiCfg.DbQueries.DeleteContentRule(r.Context(), ruleID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete content rule", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find content rule", nil)
+		return
+	}
+
+	err = apiCfg.ReloadContentRules(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't reload content rules", err)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// decodeContentRule reads and validates a rule from the request body,
+// responding with an error when it's invalid.
+func decodeContentRule(w http.ResponseWriter, r *http.Request) (moderation.Rule, error) {
+	type parameters struct {
+		Kind    moderation.RuleKind `json:"kind"`
+		Pattern string              `json:"pattern"`
+		Action  moderation.Action   `json:"action"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return moderation.Rule{}, err
+	}
+
+	rule := moderation.Rule{
+		Kind:    params.Kind,
+		Pattern: params.Pattern,
+		Action:  params.Action,
+	}
+	err = rule.Validate()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return moderation.Rule{}, err
+	}
+	return rule, nil
+}
+
+func contentRuleFromDB(rule database.ContentRule) ContentRule {
+	return ContentRule{
+		ID:        rule.ID,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+		Kind:      moderation.RuleKind(rule.Kind),
+		Pattern:   rule.Pattern,
+		Action:    moderation.Action(rule.Action),
+	}
+}
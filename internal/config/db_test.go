@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/activitypub"
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/ratelimit"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+const testSecret = "test-secret"
+
+// newTestConfig returns an ApiConfig backed by a schema of its own in the
+// Postgres database at CHIRPY_TEST_DB_URL, with every migration applied.
+// Tests that need a database are skipped when the variable isn't set.
+func newTestConfig(t *testing.T) *ApiConfig {
+	t.Helper()
+	dbURL := os.Getenv("CHIRPY_TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("CHIRPY_TEST_DB_URL isn't set")
+	}
+
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	if err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(t, dbURL, schema))
+	if err != nil {
+		t.Fatalf("failed to open test schema: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrate(t, db)
+
+	apiCfg := &ApiConfig{
+		DB:                         db,
+		DbQueries:                  database.New(db),
+		Platform:                   "dev",
+		Secret:                     testSecret,
+		ChirpLimiter:               ratelimit.New(time.Minute),
+		AccountDeletionGracePeriod: 30 * 24 * time.Hour,
+		IdempotencyKeyRetention:    24 * time.Hour,
+		BaseURL:                    "http://chirpy.test",
+		Federation:                 &activitypub.Client{AllowHTTP: true, AllowPrivateAddresses: true},
+	}
+	err = apiCfg.ReloadContentRules(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load content rules: %v", err)
+	}
+	return apiCfg
+}
+
+// withSearchPath points a connection string at schema. lib/pq passes
+// settings it doesn't know on to the server.
+func withSearchPath(t *testing.T, dbURL, schema string) string {
+	t.Helper()
+	if !strings.HasPrefix(dbURL, "postgres://") && !strings.HasPrefix(dbURL, "postgresql://") {
+		return dbURL + " search_path=" + schema
+	}
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatalf("failed to parse CHIRPY_TEST_DB_URL: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// migrate runs the Up part of every goose migration in order.
+func migrate(t *testing.T, db *sql.DB) {
+	t.Helper()
+	files, err := filepath.Glob("../../sql/schema/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("failed to find migrations: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		_, err = db.Exec(up)
+		if err != nil {
+			t.Fatalf("failed to apply %s: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+// createTestUser creates a user and logs them in, returning an access JWT.
+func createTestUser(t *testing.T, apiCfg *ApiConfig, email string) (database.CreateUserRow, string) {
+	t.Helper()
+	hash, err := auth.HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	user, err := apiCfg.DbQueries.CreateUser(context.Background(), database.CreateUserParams{
+		Email:          email,
+		HashedPassword: hash,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	token, err := auth.MakeJWT(user.ID, apiCfg.Secret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+	return user, token
+}
+
+// createTestAdmin is createTestUser for an admin account.
+func createTestAdmin(t *testing.T, apiCfg *ApiConfig, email string) (database.CreateUserRow, string) {
+	t.Helper()
+	admin, token := createTestUser(t, apiCfg, email)
+	_, err := apiCfg.DB.Exec("UPDATE users SET role = $1 WHERE id = $2", roleAdmin, admin.ID)
+	if err != nil {
+		t.Fatalf("failed to make %s an admin: %v", email, err)
+	}
+	return admin, token
+}
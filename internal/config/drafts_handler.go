@@ -0,0 +1,281 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// Drafts can run over the plan's chirp length while they're being
+	// written, up to maxDraftLength bytes. They're only validated as chirps
+	// when published.
+	maxDraftLength = 10000
+	maxDrafts      = 100
+)
+
+type Draft struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+func (apiCfg *ApiConfig) CreateDraftHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	err = checkDraftBody(w, params.Body)
+	if err != nil {
+		return
+	}
+	// The limit is checked by the insert itself, which adds no row once
+	// the user has maxDrafts.
+	draft, err := apiCfg.DbQueries.CreateDraft(r.Context(), database.CreateDraftParams{
+		Body:      params.Body,
+		UserID:    user_id,
+		MaxDrafts: maxDrafts,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("You can't have more than %d drafts", maxDrafts), err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create draft", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, draftFromDB(draft))
+}
+
+// ListDraftsHandler returns the user's drafts, the most recently saved
+// first.
+func (apiCfg *ApiConfig) ListDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsRead)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbDrafts, err := apiCfg.DbQueries.GetDraftsByUserID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve drafts", err)
+		return
+	}
+
+	drafts := []Draft{}
+	for _, dbDraft := range dbDrafts {
+		drafts = append(drafts, draftFromDB(dbDraft))
+	}
+	respondWithJSON(w, http.StatusOK, drafts)
+}
+
+// UpdateDraftHandler saves a draft's body. Clients autosave as the user
+// types, so the last save wins.
+func (apiCfg *ApiConfig) UpdateDraftHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	err = checkDraftBody(w, params.Body)
+	if err != nil {
+		return
+	}
+	draft, err := apiCfg.ownDraft(w, r, user_id)
+	if err != nil {
+		return
+	}
+
+	draft, err = apiCfg.DbQueries.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:   draft.ID,
+		Body: params.Body,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find draft", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update draft", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, draftFromDB(draft))
+}
+
+func (apiCfg *ApiConfig) DeleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	draft, err := apiCfg.ownDraft(w, r, user_id)
+	if err != nil {
+		return
+	}
+
+	err = apiCfg.DbQueries.DeleteDraft(r.Context(), draft.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete draft", err)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// PublishDraftHandler turns a draft into a chirp. The draft goes through the
+// same checks as a new chirp, and publish_at schedules it. The draft is
+// deleted and the chirp created in one statement, so a draft is never
+// published twice or lost, and a draft saved again while it was being
+// checked isn't published.
+func (apiCfg *ApiConfig) PublishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+
+	// publish_at is optional, so an empty body is fine.
+	type parameters struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	draft, err := apiCfg.ownDraft(w, r, user_id)
+	if err != nil {
+		return
+	}
+	cleaned, err := apiCfg.prepareChirp(w, r, user_id, draft.Body, params.PublishAt)
+	if err != nil {
+		return
+	}
+
+	publishAt := sql.NullTime{}
+	if params.PublishAt != nil {
+		publishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+	}
+	chirp, err := apiCfg.DbQueries.PublishDraft(r.Context(), database.PublishDraftParams{
+		ID:        draft.ID,
+		UpdatedAt: draft.UpdatedAt,
+		Body:      cleaned,
+		PublishAt: publishAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Draft was changed while publishing, try again", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't publish draft", err)
+		return
+	}
+	if !chirp.PublishAt.Valid {
+		apiCfg.publishChirp(r.Context(), "Create", chirp)
+	}
+	respondWithJSON(w, http.StatusCreated, chirpFromDB(chirp))
+}
+
+// ownDraft looks up the draft in the path, responding with an error unless
+// it belongs to userID.
+func (apiCfg *ApiConfig) ownDraft(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.Draft, error) {
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse draftID", err)
+		return database.Draft{}, err
+	}
+	draft, err := apiCfg.DbQueries.GetDraftByID(r.Context(), draftID)
+	if err == nil && draft.UserID != userID {
+		err = fmt.Errorf("draft %v belongs to user %v", draft.ID, draft.UserID)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find draft", err)
+		return database.Draft{}, err
+	}
+	return draft, nil
+}
+
+func checkDraftBody(w http.ResponseWriter, body string) error {
+	if len(body) > maxDraftLength {
+		err := fmt.Errorf("draft is %d bytes long", len(body))
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("body can't be longer than %d bytes", maxDraftLength), err)
+		return err
+	}
+	return nil
+}
+
+func draftFromDB(draft database.Draft) Draft {
+	return Draft{
+		ID:        draft.ID,
+		CreatedAt: draft.CreatedAt,
+		UpdatedAt: draft.UpdatedAt,
+		Body:      draft.Body,
+	}
+}
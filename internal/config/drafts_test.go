@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+)
+
+func newDraftsMux(apiCfg *ApiConfig) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/drafts", apiCfg.CreateDraftHandler)
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.PublishDraftHandler)
+	return mux
+}
+
+func TestCreateDraftLimit(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	user, token := createTestUser(t, apiCfg, "alice@example.com")
+	for i := 0; i < maxDrafts-1; i++ {
+		_, err := apiCfg.DbQueries.CreateDraft(ctx, database.CreateDraftParams{
+			Body:      "draft",
+			UserID:    user.ID,
+			MaxDrafts: maxDrafts,
+		})
+		if err != nil {
+			t.Fatalf("CreateDraft() error = %v", err)
+		}
+	}
+
+	mux := newDraftsMux(apiCfg)
+	send := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/drafts", strings.NewReader(`{"body":"draft"}`))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+	if w := send(); w.Code != http.StatusCreated {
+		t.Fatalf("POST /api/drafts with %d drafts = %d %s, want %d", maxDrafts-1, w.Code, w.Body, http.StatusCreated)
+	}
+	if w := send(); w.Code != http.StatusConflict {
+		t.Errorf("POST /api/drafts with %d drafts = %d, want %d", maxDrafts, w.Code, http.StatusConflict)
+	}
+}
+
+func TestPublishDraft(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	user, token := createTestUser(t, apiCfg, "alice@example.com")
+	err := apiCfg.GrantMembership(ctx, user.ID, "chirpy_red", nil)
+	if err != nil {
+		t.Fatalf("GrantMembership() error = %v", err)
+	}
+	createDraft := func(body string) database.Draft {
+		draft, err := apiCfg.DbQueries.CreateDraft(ctx, database.CreateDraftParams{
+			Body:      body,
+			UserID:    user.ID,
+			MaxDrafts: maxDrafts,
+		})
+		if err != nil {
+			t.Fatalf("CreateDraft() error = %v", err)
+		}
+		return draft
+	}
+
+	mux := newDraftsMux(apiCfg)
+	publish := func(draft database.Draft, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/drafts/"+draft.ID.String()+"/publish", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Invalid chirp", func(t *testing.T) {
+		draft := createDraft(strings.Repeat("a", 1000))
+		if w := publish(draft, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("publishing a draft that's too long = %d %s, want %d", w.Code, w.Body, http.StatusBadRequest)
+		}
+		_, err := apiCfg.DbQueries.GetDraftByID(ctx, draft.ID)
+		if err != nil {
+			t.Errorf("GetDraftByID() error = %v, want the draft kept", err)
+		}
+	})
+
+	t.Run("Scheduled", func(t *testing.T) {
+		draft := createDraft("later")
+		publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		w := publish(draft, `{"publish_at":"`+publishAt.Format(time.RFC3339)+`"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("publishing a draft with publish_at = %d %s", w.Code, w.Body)
+		}
+		chirp := Chirp{}
+		json.Unmarshal(w.Body.Bytes(), &chirp)
+		if chirp.PublishAt == nil || !chirp.PublishAt.Equal(publishAt) {
+			t.Errorf("publish_at = %v, want %v", chirp.PublishAt, publishAt)
+		}
+		scheduled, err := apiCfg.DbQueries.GetScheduledChirpByID(ctx, chirp.ID)
+		if err != nil || scheduled.Body != "later" {
+			t.Errorf("GetScheduledChirpByID() = %q (error %v), want the draft's body", scheduled.Body, err)
+		}
+		_, err = apiCfg.DbQueries.GetDraftByID(ctx, draft.ID)
+		if err == nil {
+			t.Errorf("GetDraftByID() found the draft, want it consumed")
+		}
+	})
+
+	t.Run("Saved while publishing", func(t *testing.T) {
+		draft := createDraft("first")
+
+		// Save the draft in a transaction that stays open, so the request
+		// reads the old draft and then waits on the row lock to publish it.
+		tx, err := apiCfg.DB.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx() error = %v", err)
+		}
+		defer tx.Rollback()
+		_, err = apiCfg.DbQueries.WithTx(tx).UpdateDraft(ctx, database.UpdateDraftParams{
+			ID:   draft.ID,
+			Body: "second",
+		})
+		if err != nil {
+			t.Fatalf("UpdateDraft() error = %v", err)
+		}
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() { done <- publish(draft, "") }()
+		waitForLockWait(t, apiCfg.DB)
+		err = tx.Commit()
+		if err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+
+		if w := <-done; w.Code != http.StatusConflict {
+			t.Errorf("publishing a draft saved meanwhile = %d %s, want %d", w.Code, w.Body, http.StatusConflict)
+		}
+		saved, err := apiCfg.DbQueries.GetDraftByID(ctx, draft.ID)
+		if err != nil || saved.Body != "second" {
+			t.Errorf("GetDraftByID() = %q (error %v), want the saved draft kept", saved.Body, err)
+		}
+	})
+}
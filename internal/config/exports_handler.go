@@ -0,0 +1,512 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/export"
+	"github.com/google/uuid"
+)
+
+const (
+	exportStatusReady = "ready"
+
+	// exportRetention is how long a finished archive can be downloaded.
+	exportRetention = 7 * 24 * time.Hour
+	// exportLinkDuration is how long a signed download link stays valid.
+	exportLinkDuration = 15 * time.Minute
+
+	timeCSVLayout = time.RFC3339
+)
+
+type Export struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	// DownloadURL is a signed link to the archive, valid for
+	// exportLinkDuration, set once the export is ready.
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// CreateExportHandler asks for an archive of everything Chirpy holds about
+// the caller. Archives are built in the background; poll
+// ListExportsHandler for the download link.
+func (apiCfg *ApiConfig) CreateExportHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbExport, err := apiCfg.DbQueries.CreateExport(r.Context(), user_id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "An export is already in progress", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create export", err)
+		return
+	}
+
+	response, err := apiCfg.exportFromDB(dbExport)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't sign download link", err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, response)
+}
+
+func (apiCfg *ApiConfig) ListExportsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbExports, err := apiCfg.DbQueries.GetExportsByUserID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve exports", err)
+		return
+	}
+
+	exports := []Export{}
+	for _, dbExport := range dbExports {
+		response, err := apiCfg.exportFromDB(dbExport)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't sign download link", err)
+			return
+		}
+		exports = append(exports, response)
+	}
+	respondWithJSON(w, http.StatusOK, exports)
+}
+
+// DownloadExportHandler serves an archive to whoever holds a signed link to
+// it, so it works from a plain browser download.
+func (apiCfg *ApiConfig) DownloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	exportID, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse exportID", err)
+		return
+	}
+	signedID, err := auth.ValidateDownloadToken(r.URL.Query().Get("token"), apiCfg.Secret)
+	if err != nil || signedID != exportID {
+		respondWithError(w, http.StatusForbidden, "Invalid or expired download link", err)
+		return
+	}
+
+	dbExport, err := apiCfg.DbQueries.GetExport(r.Context(), exportID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find export", err)
+		return
+	}
+	if dbExport.Status != exportStatusReady || time.Now().After(dbExport.ExpiresAt.Time) {
+		respondWithError(w, http.StatusNotFound, "Export isn't available", nil)
+		return
+	}
+
+	blob, err := apiCfg.Blobs.Get(r.Context(), dbExport.BlobKey.String)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't read export", err)
+		return
+	}
+	defer blob.Close()
+
+	filename := fmt.Sprintf("chirpy-export-%s.zip", dbExport.CreatedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		log.Printf("failed to send export %v: %s", dbExport.ID, err)
+	}
+}
+
+// RunExports builds requested archives and deletes expired ones every
+// interval until ctx is cancelled.
+func (apiCfg *ApiConfig) RunExports(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := apiCfg.deleteExpiredExports(ctx)
+		if err != nil {
+			log.Printf("failed to delete expired exports: %s", err)
+		}
+		err = apiCfg.processPendingExports(ctx)
+		if err != nil {
+			log.Printf("failed to process exports: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (apiCfg *ApiConfig) processPendingExports(ctx context.Context) error {
+	for {
+		dbExport, err := apiCfg.DbQueries.ClaimPendingExport(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("exports/%s.zip", dbExport.ID)
+		err = apiCfg.buildExport(ctx, dbExport.UserID, key)
+		if err != nil {
+			log.Printf("export %v failed: %s", dbExport.ID, err)
+			err = apiCfg.DbQueries.FailExport(ctx, database.FailExportParams{
+				ID:    dbExport.ID,
+				Error: sql.NullString{String: err.Error(), Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = apiCfg.DbQueries.FinishExport(ctx, database.FinishExportParams{
+			ID:        dbExport.ID,
+			BlobKey:   sql.NullString{String: key, Valid: true},
+			ExpiresAt: sql.NullTime{Time: time.Now().Add(exportRetention), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (apiCfg *ApiConfig) deleteExpiredExports(ctx context.Context) error {
+	keys, err := apiCfg.DbQueries.DeleteExpiredExports(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if !key.Valid {
+			continue
+		}
+		err = apiCfg.Blobs.Delete(ctx, key.String)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildExport collects a user's data into a zip archive and stores it
+// under key.
+func (apiCfg *ApiConfig) buildExport(ctx context.Context, userID uuid.UUID, key string) error {
+	files, err := apiCfg.exportFiles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	archive := bytes.Buffer{}
+	err = export.Write(&archive, files, time.Now())
+	if err != nil {
+		return err
+	}
+	return apiCfg.Blobs.Put(ctx, key, &archive)
+}
+
+func (apiCfg *ApiConfig) exportFiles(ctx context.Context, userID uuid.UUID) ([]export.File, error) {
+	type profile struct {
+		User
+		Role string `json:"role"`
+	}
+	type session struct {
+		CreatedAt time.Time  `json:"created_at"`
+		ExpiresAt time.Time  `json:"expires_at"`
+		RevokedAt *time.Time `json:"revoked_at"`
+		ClientID  *uuid.UUID `json:"client_id"`
+		Scopes    []string   `json:"scopes"`
+	}
+	type identity struct {
+		CreatedAt time.Time `json:"created_at"`
+		Issuer    string    `json:"issuer"`
+		Subject   string    `json:"subject"`
+		Email     string    `json:"email"`
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	isChirpyRed, err := apiCfg.DbQueries.HasActiveMembership(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	files := []export.File{{
+		Name: "profile",
+		Data: profile{
+			User: User{
+				ID:          user.ID,
+				CreatedAt:   user.CreatedAt,
+				UpdatedAt:   user.UpdatedAt,
+				Email:       user.Email,
+				IsChirpyRed: isChirpyRed,
+			},
+			Role: user.Role,
+		},
+	}}
+
+	dbChirps, err := apiCfg.DbQueries.GetChirpsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	chirps := []Chirp{}
+	chirpRows := [][]string{}
+	for _, dbChirp := range dbChirps {
+		chirps = append(chirps, chirpFromDB(dbChirp))
+		publishAt := ""
+		if dbChirp.PublishAt.Valid {
+			publishAt = dbChirp.PublishAt.Time.Format(timeCSVLayout)
+		}
+		chirpRows = append(chirpRows, []string{
+			dbChirp.ID.String(),
+			dbChirp.CreatedAt.Format(timeCSVLayout),
+			dbChirp.UpdatedAt.Format(timeCSVLayout),
+			dbChirp.Body,
+			publishAt,
+		})
+	}
+	files = append(files, export.File{
+		Name:   "chirps",
+		Data:   chirps,
+		Header: []string{"id", "created_at", "updated_at", "body", "publish_at"},
+		Rows:   chirpRows,
+	})
+
+	dbDrafts, err := apiCfg.DbQueries.GetDraftsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	drafts := []Draft{}
+	draftRows := [][]string{}
+	for _, dbDraft := range dbDrafts {
+		drafts = append(drafts, draftFromDB(dbDraft))
+		draftRows = append(draftRows, []string{
+			dbDraft.ID.String(),
+			dbDraft.CreatedAt.Format(timeCSVLayout),
+			dbDraft.UpdatedAt.Format(timeCSVLayout),
+			dbDraft.Body,
+		})
+	}
+	files = append(files, export.File{
+		Name:   "drafts",
+		Data:   drafts,
+		Header: []string{"id", "created_at", "updated_at", "body"},
+		Rows:   draftRows,
+	})
+
+	dbRefreshTokens, err := apiCfg.DbQueries.GetRefreshTokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []session{}
+	sessionRows := [][]string{}
+	for _, dbRefreshToken := range dbRefreshTokens {
+		s := session{
+			CreatedAt: dbRefreshToken.CreatedAt,
+			ExpiresAt: dbRefreshToken.ExpiresAt,
+			Scopes:    dbRefreshToken.Scopes,
+		}
+		revokedAt := ""
+		if dbRefreshToken.RevokedAt.Valid {
+			s.RevokedAt = &dbRefreshToken.RevokedAt.Time
+			revokedAt = dbRefreshToken.RevokedAt.Time.Format(timeCSVLayout)
+		}
+		clientID := ""
+		if dbRefreshToken.ClientID.Valid {
+			s.ClientID = &dbRefreshToken.ClientID.UUID
+			clientID = dbRefreshToken.ClientID.UUID.String()
+		}
+		sessions = append(sessions, s)
+		sessionRows = append(sessionRows, []string{
+			dbRefreshToken.CreatedAt.Format(timeCSVLayout),
+			dbRefreshToken.ExpiresAt.Format(timeCSVLayout),
+			revokedAt,
+			clientID,
+		})
+	}
+	files = append(files, export.File{
+		Name:   "sessions",
+		Data:   sessions,
+		Header: []string{"created_at", "expires_at", "revoked_at", "client_id"},
+		Rows:   sessionRows,
+	})
+
+	dbMemberships, err := apiCfg.DbQueries.GetMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	memberships := []Membership{}
+	membershipRows := [][]string{}
+	for _, dbMembership := range dbMemberships {
+		memberships = append(memberships, membershipFromDB(dbMembership))
+		endsAt := ""
+		if dbMembership.EndsAt.Valid {
+			endsAt = dbMembership.EndsAt.Time.Format(timeCSVLayout)
+		}
+		membershipRows = append(membershipRows, []string{
+			dbMembership.Plan,
+			dbMembership.Status,
+			dbMembership.StartedAt.Format(timeCSVLayout),
+			endsAt,
+		})
+	}
+	files = append(files, export.File{
+		Name:   "memberships",
+		Data:   memberships,
+		Header: []string{"plan", "status", "started_at", "ends_at"},
+		Rows:   membershipRows,
+	})
+
+	dbPasskeys, err := apiCfg.DbQueries.GetWebauthnCredentialsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	passkeys := []Passkey{}
+	for _, dbPasskey := range dbPasskeys {
+		passkeys = append(passkeys, passkeyFromDB(dbPasskey))
+	}
+	files = append(files, export.File{Name: "passkeys", Data: passkeys})
+
+	dbTokens, err := apiCfg.DbQueries.GetPersonalAccessTokensByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tokens := []PersonalAccessToken{}
+	for _, dbToken := range dbTokens {
+		tokens = append(tokens, personalAccessTokenFromDB(dbToken))
+	}
+	files = append(files, export.File{Name: "personal_access_tokens", Data: tokens})
+
+	dbClients, err := apiCfg.DbQueries.GetOauthClientsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	clients := []OAuthClient{}
+	for _, dbClient := range dbClients {
+		clients = append(clients, oauthClientFromDB(dbClient))
+	}
+	files = append(files, export.File{Name: "oauth_clients", Data: clients})
+
+	dbIdentities, err := apiCfg.DbQueries.GetUserIdentitiesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	identities := []identity{}
+	for _, dbIdentity := range dbIdentities {
+		identities = append(identities, identity{
+			CreatedAt: dbIdentity.CreatedAt,
+			Issuer:    dbIdentity.Issuer,
+			Subject:   dbIdentity.Subject,
+			Email:     dbIdentity.Email,
+		})
+	}
+	files = append(files, export.File{Name: "linked_accounts", Data: identities})
+
+	dbBlocks, err := apiCfg.DbQueries.GetBlocksByBlockerID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	blocks := []UserRelationship{}
+	for _, dbBlock := range dbBlocks {
+		blocks = append(blocks, UserRelationship{UserID: dbBlock.BlockedID, CreatedAt: dbBlock.CreatedAt})
+	}
+	files = append(files, export.File{Name: "blocks", Data: blocks})
+
+	dbMutes, err := apiCfg.DbQueries.GetMutesByMuterID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	mutes := []UserRelationship{}
+	for _, dbMute := range dbMutes {
+		mutes = append(mutes, UserRelationship{UserID: dbMute.MutedID, CreatedAt: dbMute.CreatedAt})
+	}
+	files = append(files, export.File{Name: "mutes", Data: mutes})
+
+	dbMutedWords, err := apiCfg.DbQueries.GetActiveMutedWords(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	mutedWords := []MutedWord{}
+	for _, dbMutedWord := range dbMutedWords {
+		mutedWords = append(mutedWords, mutedWordFromDB(dbMutedWord))
+	}
+	files = append(files, export.File{Name: "muted_words", Data: mutedWords})
+
+	dbReports, err := apiCfg.DbQueries.GetReportsByReporterID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	reports := []Report{}
+	for _, dbReport := range dbReports {
+		reports = append(reports, reportFromDB(dbReport))
+	}
+	files = append(files, export.File{Name: "reports", Data: reports})
+
+	dbWarnings, err := apiCfg.DbQueries.GetWarningsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	warnings := []Warning{}
+	for _, dbWarning := range dbWarnings {
+		warnings = append(warnings, warningFromDB(dbWarning))
+	}
+	files = append(files, export.File{Name: "warnings", Data: warnings})
+
+	return files, nil
+}
+
+func (apiCfg *ApiConfig) exportFromDB(dbExport database.Export) (Export, error) {
+	response := Export{
+		ID:        dbExport.ID,
+		CreatedAt: dbExport.CreatedAt,
+		Status:    dbExport.Status,
+		Error:     dbExport.Error.String,
+	}
+	if dbExport.CompletedAt.Valid {
+		response.CompletedAt = &dbExport.CompletedAt.Time
+	}
+	if dbExport.ExpiresAt.Valid {
+		response.ExpiresAt = &dbExport.ExpiresAt.Time
+	}
+	if dbExport.Status != exportStatusReady {
+		return response, nil
+	}
+
+	token, err := auth.MakeDownloadToken(dbExport.ID, apiCfg.Secret, exportLinkDuration)
+	if err != nil {
+		return Export{}, err
+	}
+	response.DownloadURL = fmt.Sprintf("/api/exports/%s/download?token=%s", dbExport.ID, token)
+	return response, nil
+}
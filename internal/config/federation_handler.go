@@ -0,0 +1,343 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/activitypub"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// remoteActorTTL is how long a cached remote actor is trusted before
+	// it's fetched again.
+	remoteActorTTL = 24 * time.Hour
+	// maxDeliveryAttempts is how many times an activity is sent to an inbox
+	// before giving up on it.
+	maxDeliveryAttempts = 8
+)
+
+func (apiCfg *ApiConfig) actorURI(userID uuid.UUID) string {
+	return apiCfg.BaseURL + "/users/" + userID.String()
+}
+
+func (apiCfg *ApiConfig) noteURI(chirp database.Chirp) string {
+	return apiCfg.actorURI(chirp.UserID) + "/chirps/" + chirp.ID.String()
+}
+
+// localUserID returns the user a local actor URI belongs to.
+func (apiCfg *ApiConfig) localUserID(uri string) (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(uri, apiCfg.BaseURL+"/users/")
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(rest)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// federatedUser returns the user behind a local actor, or an error when
+// they don't exist or are suspended or shadow-banned, which hides them
+// from other servers too.
+func (apiCfg *ApiConfig) federatedUser(ctx context.Context, userID uuid.UUID) (database.User, error) {
+	user, err := apiCfg.DbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return database.User{}, err
+	}
+	if user.SuspendedAt.Valid || user.ShadowBannedAt.Valid {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+// actorKey returns the key pair a user's actor signs with, generating it
+// the first time it's needed.
+func (apiCfg *ApiConfig) actorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := apiCfg.DbQueries.GetActorKey(ctx, userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+
+	privateKeyPEM, publicKeyPEM, err := activitypub.GenerateKey()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	key, err = apiCfg.DbQueries.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  publicKeyPEM,
+		PrivateKeyPem: privateKeyPEM,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Another request generated the key first.
+		return apiCfg.DbQueries.GetActorKey(ctx, userID)
+	}
+	return key, err
+}
+
+// chirpNote turns a chirp into the Note other servers see.
+func (apiCfg *ApiConfig) chirpNote(chirp database.Chirp) activitypub.Note {
+	note := activitypub.Note{
+		ID:           apiCfg.noteURI(chirp),
+		Type:         "Note",
+		AttributedTo: apiCfg.actorURI(chirp.UserID),
+		Content:      activitypub.HTMLContent(chirp.Body),
+		URL:          apiCfg.BaseURL + "/api/chirps/" + chirp.ID.String(),
+		Published:    chirp.CreatedAt.UTC(),
+		To:           activitypub.Audience{activitypub.Public},
+		Cc:           activitypub.Audience{apiCfg.actorURI(chirp.UserID) + "/followers"},
+	}
+	if chirp.UpdatedAt.After(chirp.CreatedAt) {
+		updated := chirp.UpdatedAt.UTC()
+		note.Updated = &updated
+	}
+	return note
+}
+
+// chirpActivity wraps a chirp in a Create, Update or Delete activity.
+func (apiCfg *ApiConfig) chirpActivity(activityType string, chirp database.Chirp) (activitypub.Activity, error) {
+	note := apiCfg.chirpNote(chirp)
+	activity := activitypub.Activity{
+		Context: activitypub.Context,
+		Type:    activityType,
+		Actor:   note.AttributedTo,
+		To:      note.To,
+		Cc:      note.Cc,
+	}
+
+	var object any = note
+	switch activityType {
+	case "Create":
+		activity.ID = note.ID + "/activity"
+	case "Update":
+		activity.ID = fmt.Sprintf("%s#updates/%d", note.ID, chirp.UpdatedAt.Unix())
+	case "Delete":
+		activity.ID = note.ID + "#delete"
+		object = activitypub.Tombstone{ID: note.ID, Type: "Tombstone"}
+	}
+
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return activitypub.Activity{}, err
+	}
+	activity.Object = raw
+	return activity, nil
+}
+
+// publishChirp queues an activity about chirp for delivery to its author's
+// followers on other servers. Failing to queue it doesn't fail the request
+// that changed the chirp, so errors are only logged.
+func (apiCfg *ApiConfig) publishChirp(ctx context.Context, activityType string, chirp database.Chirp) {
+	err := apiCfg.queueChirpActivity(ctx, activityType, chirp)
+	if err != nil {
+		log.Printf("failed to publish %s of chirp %v: %s", activityType, chirp.ID, err)
+	}
+}
+
+func (apiCfg *ApiConfig) queueChirpActivity(ctx context.Context, activityType string, chirp database.Chirp) error {
+	// Deletes go out for restricted users too, as taking their chirps down
+	// is what they're for.
+	if activityType != "Delete" {
+		_, err := apiCfg.federatedUser(ctx, chirp.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	inboxes, err := apiCfg.DbQueries.GetRemoteFollowerInboxes(ctx, chirp.UserID)
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+	activity, err := apiCfg.chirpActivity(activityType, chirp)
+	if err != nil {
+		return err
+	}
+	for _, inbox := range inboxes {
+		err = apiCfg.queueDelivery(ctx, chirp.UserID, inbox, activity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishUserChirps sends activityType for each of a user's published
+// chirps, to take them down on other servers when the user is restricted
+// and to put them back when the restriction is lifted.
+func (apiCfg *ApiConfig) publishUserChirps(ctx context.Context, activityType string, userID uuid.UUID) {
+	chirps, err := apiCfg.DbQueries.GetPublishedChirpsByUserID(ctx, userID)
+	if err != nil {
+		log.Printf("failed to publish %s of chirps by %v: %s", activityType, userID, err)
+		return
+	}
+	for _, chirp := range chirps {
+		apiCfg.publishChirp(ctx, activityType, chirp)
+	}
+}
+
+// actorDelete is the Delete activity that tells other servers an account
+// is gone, along with everything it posted.
+func (apiCfg *ApiConfig) actorDelete(userID uuid.UUID) (activitypub.Activity, error) {
+	actorURI := apiCfg.actorURI(userID)
+	raw, err := json.Marshal(actorURI)
+	if err != nil {
+		return activitypub.Activity{}, err
+	}
+	return activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      actorURI + "#delete",
+		Type:    "Delete",
+		Actor:   actorURI,
+		Object:  raw,
+		To:      activitypub.Audience{activitypub.Public},
+	}, nil
+}
+
+// queueDelivery queues activity, sent by userID's actor, for delivery to
+// inbox by RunFederationDelivery.
+func (apiCfg *ApiConfig) queueDelivery(ctx context.Context, userID uuid.UUID, inbox string, activity any) error {
+	raw, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return apiCfg.DbQueries.CreateFederationDelivery(ctx, database.CreateFederationDeliveryParams{
+		UserID:   userID,
+		Inbox:    inbox,
+		Activity: raw,
+	})
+}
+
+// RunFederationDelivery delivers queued activities to other servers every
+// interval until ctx is cancelled. Failed deliveries are retried with
+// exponential backoff, and dropped when the inbox rejects them.
+func (apiCfg *ApiConfig) RunFederationDelivery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := apiCfg.DbQueries.DeleteFinishedFederationDeliveries(ctx)
+		if err != nil {
+			log.Printf("failed to delete finished deliveries: %s", err)
+		}
+		_, err = apiCfg.DbQueries.DeleteOrphanedActorKeys(ctx)
+		if err != nil {
+			log.Printf("failed to delete keys of deleted accounts: %s", err)
+		}
+		err = apiCfg.processFederationDeliveries(ctx)
+		if err != nil {
+			log.Printf("failed to deliver activities: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (apiCfg *ApiConfig) processFederationDeliveries(ctx context.Context) error {
+	for {
+		delivery, err := apiCfg.DbQueries.ClaimFederationDelivery(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = apiCfg.deliver(ctx, delivery)
+		if err == nil {
+			err = apiCfg.DbQueries.FinishFederationDelivery(ctx, delivery.ID)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		deliveryErr := sql.NullString{String: err.Error(), Valid: true}
+		statusErr := &activitypub.StatusError{}
+		if delivery.Attempts+1 >= maxDeliveryAttempts || (errors.As(err, &statusErr) && statusErr.Permanent()) {
+			log.Printf("giving up on delivery %v to %s: %s", delivery.ID, delivery.Inbox, err)
+			err = apiCfg.DbQueries.FailFederationDelivery(ctx, database.FailFederationDeliveryParams{
+				ID:    delivery.ID,
+				Error: deliveryErr,
+			})
+		} else {
+			err = apiCfg.DbQueries.RetryFederationDelivery(ctx, database.RetryFederationDeliveryParams{
+				ID:            delivery.ID,
+				NextAttemptAt: time.Now().Add(time.Minute << delivery.Attempts),
+				Error:         deliveryErr,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (apiCfg *ApiConfig) deliver(ctx context.Context, delivery database.FederationDelivery) error {
+	key, err := apiCfg.actorKey(ctx, delivery.UserID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return err
+	}
+	keyID := apiCfg.actorURI(delivery.UserID) + "#main-key"
+	return apiCfg.Federation.Deliver(ctx, delivery.Inbox, delivery.Activity, keyID, privateKey)
+}
+
+// verifyInboxRequest checks the signature of a request to an inbox and
+// returns the remote actor who signed it. Cached actors are used while
+// they're fresh, and fetched again if their key doesn't verify, in case
+// they rotated it.
+func (apiCfg *ApiConfig) verifyInboxRequest(r *http.Request, body []byte, sig activitypub.Signature) (database.RemoteActor, error) {
+	remoteActor, err := apiCfg.DbQueries.GetRemoteActorByKeyID(r.Context(), sig.KeyID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return database.RemoteActor{}, err
+	}
+	if err == nil && time.Since(remoteActor.FetchedAt) < remoteActorTTL {
+		if verifyRemoteSignature(r, body, sig, remoteActor) == nil {
+			return remoteActor, nil
+		}
+	}
+
+	actor, err := apiCfg.Federation.FetchKeyOwner(r.Context(), sig.KeyID)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	remoteActor, err = apiCfg.DbQueries.UpsertRemoteActor(r.Context(), database.UpsertRemoteActorParams{
+		Uri:               actor.ID,
+		PreferredUsername: actor.PreferredUsername,
+		Inbox:             actor.Inbox,
+		SharedInbox:       actor.SharedInbox(),
+		KeyID:             actor.PublicKey.ID,
+		PublicKeyPem:      actor.PublicKey.PublicKeyPem,
+	})
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	return remoteActor, verifyRemoteSignature(r, body, sig, remoteActor)
+}
+
+func verifyRemoteSignature(r *http.Request, body []byte, sig activitypub.Signature, remoteActor database.RemoteActor) error {
+	publicKey, err := activitypub.ParsePublicKey(remoteActor.PublicKeyPem)
+	if err != nil {
+		return err
+	}
+	return sig.Verify(r, body, publicKey)
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/OferRavid/chirpy/internal/activitypub"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// federationPeer is a Chirpy instance served over HTTP, which records the
+// activities its inboxes accept.
+type federationPeer struct {
+	apiCfg *ApiConfig
+	server *httptest.Server
+
+	mu       sync.Mutex
+	received []activitypub.Activity
+}
+
+func newFederationPeer(t *testing.T) *federationPeer {
+	t.Helper()
+	peer := &federationPeer{apiCfg: newTestConfig(t)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{userID}", peer.apiCfg.ActorHandler)
+	mux.HandleFunc("POST /users/{userID}/inbox", peer.inbox)
+	mux.HandleFunc("POST /inbox", peer.inbox)
+	mux.HandleFunc("POST /api/chirps", peer.apiCfg.CreateChirpsHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", peer.apiCfg.CreateReportHandler)
+	peer.server = httptest.NewServer(mux)
+	t.Cleanup(peer.server.Close)
+	peer.apiCfg.BaseURL = peer.server.URL
+	return peer
+}
+
+func (p *federationPeer) inbox(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	p.apiCfg.InboxHandler(rec, r)
+
+	if rec.Code == http.StatusAccepted {
+		activity := activitypub.Activity{}
+		json.Unmarshal(body, &activity)
+		p.mu.Lock()
+		p.received = append(p.received, activity)
+		p.mu.Unlock()
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+// deliver sends the peer's queued activities and returns the ones other
+// peers accepted since the last call.
+func (p *federationPeer) deliver(t *testing.T, to *federationPeer) []activitypub.Activity {
+	t.Helper()
+	err := p.apiCfg.processFederationDeliveries(context.Background())
+	if err != nil {
+		t.Fatalf("processFederationDeliveries() error = %v", err)
+	}
+	to.mu.Lock()
+	defer to.mu.Unlock()
+	received := to.received
+	to.received = nil
+	return received
+}
+
+func (p *federationPeer) post(t *testing.T, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, p.server.URL+path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	p.server.Config.Handler.ServeHTTP(w, r)
+	if w.Code/100 != 2 {
+		t.Fatalf("POST %s = %d %s", path, w.Code, w.Body)
+	}
+	return w
+}
+
+func (p *federationPeer) chirp(t *testing.T, token, body string) database.Chirp {
+	t.Helper()
+	w := p.post(t, "/api/chirps", token, `{"body":"`+body+`"}`)
+	chirp := Chirp{}
+	json.Unmarshal(w.Body.Bytes(), &chirp)
+	dbChirp, err := p.apiCfg.DbQueries.GetChirpByID(context.Background(), chirp.ID)
+	if err != nil {
+		t.Fatalf("GetChirpByID() error = %v", err)
+	}
+	return dbChirp
+}
+
+func wantActivity(t *testing.T, received []activitypub.Activity, activityType, objectID string) {
+	t.Helper()
+	for _, activity := range received {
+		if activity.Type == activityType && activity.ObjectID() == objectID {
+			return
+		}
+	}
+	t.Errorf("no %s of %s among the accepted activities %+v", activityType, objectID, received)
+}
+
+// TestFederationBetweenInstances runs two Chirpy instances against each
+// other: one follows a user of the other, receives their chirps, and sees
+// them taken down when they're hidden, the user is suspended and the
+// account is deleted.
+func TestFederationBetweenInstances(t *testing.T) {
+	a := newFederationPeer(t)
+	b := newFederationPeer(t)
+	ctx := context.Background()
+
+	alice, aliceToken := createTestUser(t, a.apiCfg, "alice@example.com")
+	_, carolToken := createTestUser(t, a.apiCfg, "carol@example.com")
+	bob, _ := createTestUser(t, b.apiCfg, "bob@example.com")
+	aliceURI := a.apiCfg.actorURI(alice.ID)
+	bobURI := b.apiCfg.actorURI(bob.ID)
+
+	object, _ := json.Marshal(aliceURI)
+	err := b.apiCfg.queueDelivery(ctx, bob.ID, aliceURI+"/inbox", activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      bobURI + "#follows/" + uuid.NewString(),
+		Type:    "Follow",
+		Actor:   bobURI,
+		Object:  object,
+	})
+	if err != nil {
+		t.Fatalf("queueDelivery() error = %v", err)
+	}
+	wantActivity(t, b.deliver(t, a), "Follow", aliceURI)
+	followers, err := a.apiCfg.DbQueries.CountRemoteFollowers(ctx, alice.ID)
+	if err != nil || followers != 1 {
+		t.Fatalf("CountRemoteFollowers() = %d (error %v), want 1", followers, err)
+	}
+	received := a.deliver(t, b)
+	if len(received) != 1 || received[0].Type != "Accept" {
+		t.Fatalf("accepted activities = %+v, want the Accept", received)
+	}
+
+	hidden := a.chirp(t, aliceToken, "first")
+	kept := a.chirp(t, aliceToken, "second")
+	received = a.deliver(t, b)
+	wantActivity(t, received, "Create", a.apiCfg.noteURI(hidden))
+	wantActivity(t, received, "Create", a.apiCfg.noteURI(kept))
+
+	note, _ := json.Marshal(activitypub.Note{
+		ID:           bobURI + "/chirps/" + uuid.NewString(),
+		Type:         "Note",
+		AttributedTo: bobURI,
+		Content:      "<p>hi alice</p>",
+		InReplyTo:    a.apiCfg.noteURI(kept),
+		To:           activitypub.Audience{aliceURI},
+	})
+	err = b.apiCfg.queueDelivery(ctx, bob.ID, a.server.URL+"/inbox", activitypub.Activity{
+		Context: activitypub.Context,
+		ID:      bobURI + "#creates/" + uuid.NewString(),
+		Type:    "Create",
+		Actor:   bobURI,
+		Object:  note,
+		To:      activitypub.Audience{aliceURI},
+	})
+	if err != nil {
+		t.Fatalf("queueDelivery() error = %v", err)
+	}
+	b.deliver(t, a)
+	notes, err := a.apiCfg.DbQueries.GetRemoteNotesForUser(ctx, database.GetRemoteNotesForUserParams{
+		UserID: alice.ID,
+		Limit:  10,
+	})
+	if err != nil || len(notes) != 1 || notes[0].ActorUri != bobURI {
+		t.Errorf("GetRemoteNotesForUser() = %+v (error %v), want bob's reply", notes, err)
+	}
+
+	a.apiCfg.ReportAutoHideThreshold = 1
+	a.post(t, "/api/chirps/"+hidden.ID.String()+"/reports", carolToken, `{"reason":"spam"}`)
+	wantActivity(t, a.deliver(t, b), "Delete", a.apiCfg.noteURI(hidden))
+
+	err = a.apiCfg.SuspendAccount(ctx, alice.ID, "spam")
+	if err != nil {
+		t.Fatalf("SuspendAccount() error = %v", err)
+	}
+	received = a.deliver(t, b)
+	if len(received) != 1 {
+		t.Errorf("accepted activities = %+v, want a Delete of the visible chirp only", received)
+	}
+	wantActivity(t, received, "Delete", a.apiCfg.noteURI(kept))
+
+	_, err = b.apiCfg.DbQueries.GetRemoteActorByURI(ctx, aliceURI)
+	if err != nil {
+		t.Fatalf("GetRemoteActorByURI() error = %v, want alice cached", err)
+	}
+	err = a.apiCfg.DeleteAccount(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+	wantActivity(t, a.deliver(t, b), "Delete", aliceURI)
+	_, err = b.apiCfg.DbQueries.GetRemoteActorByURI(ctx, aliceURI)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetRemoteActorByURI() error = %v, want alice forgotten", err)
+	}
+
+	deleted, err := a.apiCfg.DbQueries.DeleteOrphanedActorKeys(ctx)
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteOrphanedActorKeys() = %d (error %v), want alice's key deleted", deleted, err)
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/feed"
+	"github.com/google/uuid"
+)
+
+// feedLength is how many of the latest chirps a feed holds.
+const feedLength = 50
+
+type feedFormat string
+
+const (
+	feedFormatRSS  feedFormat = "rss"
+	feedFormatAtom feedFormat = "atom"
+)
+
+func (apiCfg *ApiConfig) PublicRSSFeedHandler(w http.ResponseWriter, r *http.Request) {
+	apiCfg.servePublicFeed(w, r, feedFormatRSS)
+}
+
+func (apiCfg *ApiConfig) PublicAtomFeedHandler(w http.ResponseWriter, r *http.Request) {
+	apiCfg.servePublicFeed(w, r, feedFormatAtom)
+}
+
+func (apiCfg *ApiConfig) UserRSSFeedHandler(w http.ResponseWriter, r *http.Request) {
+	apiCfg.serveUserFeed(w, r, feedFormatRSS)
+}
+
+func (apiCfg *ApiConfig) UserAtomFeedHandler(w http.ResponseWriter, r *http.Request) {
+	apiCfg.serveUserFeed(w, r, feedFormatAtom)
+}
+
+// servePublicFeed serves the latest chirps from everyone.
+func (apiCfg *ApiConfig) servePublicFeed(w http.ResponseWriter, r *http.Request, format feedFormat) {
+	dbChirps, err := apiCfg.DbQueries.GetPublicChirps(r.Context(), feedLength)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	apiCfg.serveFeed(w, r, format, feed.Feed{
+		Title:       "Chirpy",
+		Description: "The latest chirps on Chirpy",
+		Link:        apiCfg.BaseURL + "/api/chirps",
+		SelfLink:    apiCfg.BaseURL + "/feed." + string(format),
+		ID:          apiCfg.BaseURL + "/feed",
+	}, dbChirps)
+}
+
+// serveUserFeed serves the latest chirps of the user named in the path.
+func (apiCfg *ApiConfig) serveUserFeed(w http.ResponseWriter, r *http.Request, format feedFormat) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse userID", err)
+		return
+	}
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil || user.SuspendedAt.Valid || user.ShadowBannedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	dbChirps, err := apiCfg.DbQueries.GetPublicChirpsByUserID(r.Context(), database.GetPublicChirpsByUserIDParams{
+		UserID: user.ID,
+		Limit:  feedLength,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
+	}
+
+	userURL := apiCfg.BaseURL + "/users/" + user.ID.String()
+	apiCfg.serveFeed(w, r, format, feed.Feed{
+		Title:       "Chirps by " + user.ID.String(),
+		Description: "The latest chirps by " + user.ID.String() + " on Chirpy",
+		Link:        apiCfg.BaseURL + "/api/chirps?author_id=" + user.ID.String(),
+		SelfLink:    userURL + "/feed." + string(format),
+		ID:          userURL + "/feed",
+		Updated:     user.CreatedAt,
+	}, dbChirps)
+}
+
+// serveFeed renders chirps into f and writes it out. The ETag and
+// Last-Modified headers let feed readers poll with conditional requests and
+// get a 304 when nothing changed.
+func (apiCfg *ApiConfig) serveFeed(w http.ResponseWriter, r *http.Request, format feedFormat, f feed.Feed, dbChirps []database.Chirp) {
+	for _, dbChirp := range dbChirps {
+		f.Items = append(f.Items, feed.Item{
+			ID:        dbChirp.ID,
+			Link:      apiCfg.BaseURL + "/api/chirps/" + dbChirp.ID.String(),
+			Author:    dbChirp.UserID.String(),
+			Body:      dbChirp.Body,
+			Published: dbChirp.CreatedAt,
+			Updated:   dbChirp.UpdatedAt,
+		})
+		if dbChirp.UpdatedAt.After(f.Updated) {
+			f.Updated = dbChirp.UpdatedAt
+		}
+	}
+
+	var body []byte
+	var err error
+	contentType := ""
+	switch format {
+	case feedFormatRSS:
+		body, err = feed.RSS(f)
+		contentType = "application/rss+xml; charset=utf-8"
+	case feedFormatAtom:
+		body, err = feed.Atom(f)
+		contentType = "application/atom+xml; charset=utf-8"
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't render feed", err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	// Last-Modified only has second precision.
+	http.ServeContent(w, r, "", f.Updated.Truncate(time.Second), bytes.NewReader(body))
+}
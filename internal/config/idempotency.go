@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/idempotency"
+	"github.com/google/uuid"
+)
+
+// idempotencyLockTimeout is how long a key stays reserved for a request
+// that never finished, e.g. because the server restarted, before a retry
+// can take it over.
+const idempotencyLockTimeout = time.Minute
+
+var errIdempotencyKeyInUse = errors.New("idempotency key is in use by a request in progress")
+
+// idempotentRoutes are the routes whose responses are kept for retries.
+// Responses are stored as they were sent, so routes that hand out
+// credentials, like tokens, client secrets or TOTP secrets and recovery
+// codes, must never be on the list.
+var idempotentRoutes = []string{
+	"POST /api/chirps",
+	"POST /api/chirps/{chirpID}/reports",
+	"POST /api/drafts",
+	"POST /api/drafts/{draftID}/publish",
+	"POST /api/blocks",
+	"POST /api/mutes",
+	"POST /api/muted-words",
+}
+
+var idempotentRouteMux = func() *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, pattern := range idempotentRoutes {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {})
+	}
+	return mux
+}()
+
+// idempotentRoute reports whether r is for one of idempotentRoutes.
+func idempotentRoute(r *http.Request) bool {
+	_, pattern := idempotentRouteMux.Handler(r)
+	return slices.Contains(idempotentRoutes, pattern)
+}
+
+// MiddlewareIdempotency makes requests to idempotentRoutes sent with an
+// Idempotency-Key header safe to retry. The first successful response for
+// a user's key is kept for IdempotencyKeyRetention and replayed for retries
+// with the same key, without running the request again. Reusing a key for
+// a different request is rejected. Other routes ignore the header, and
+// requests without an access token aren't tracked, since keys belong to a
+// user.
+func (apiCfg *ApiConfig) MiddlewareIdempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotency.Header)
+		if key == "" || !idempotentRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, ok := apiCfg.accessTokenUser(r.Context(), token)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !idempotency.ValidKey(key) {
+			respondWithError(w, http.StatusBadRequest, "Idempotency-Key must be 1 to 255 printable characters", nil)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Couldn't read request body", err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := idempotency.Fingerprint(r.Method, r.URL.RequestURI(), body)
+
+		claim, previous, err := apiCfg.claimIdempotencyKey(r.Context(), userID, key, fingerprint)
+		if errors.Is(err, errIdempotencyKeyInUse) {
+			respondWithError(w, http.StatusConflict, "A request with this idempotency key is still in progress", nil)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check idempotency key", err)
+			return
+		}
+		if previous != nil {
+			if previous.Fingerprint != fingerprint {
+				respondWithError(w, http.StatusUnprocessableEntity, "Idempotency key was already used for a different request", nil)
+				return
+			}
+			if !previous.ResponseStatus.Valid {
+				respondWithError(w, http.StatusConflict, "A request with this idempotency key is still in progress", nil)
+				return
+			}
+			header := http.Header{}
+			err = json.Unmarshal(previous.ResponseHeaders, &header)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't replay response", err)
+				return
+			}
+			idempotency.Response{
+				Status: int(previous.ResponseStatus.Int32),
+				Header: header,
+				Body:   previous.ResponseBody,
+			}.Replay(w)
+			return
+		}
+
+		recorder := idempotency.NewRecorder(w)
+		next.ServeHTTP(recorder, r)
+
+		// The client may have given up on the request, which is the reason
+		// for it to retry, so the outcome is recorded regardless.
+		apiCfg.finishIdempotentRequest(context.WithoutCancel(r.Context()), claim, recorder.Response())
+	})
+}
+
+// claimIdempotencyKey reserves key for a request. When a request already
+// has it, that request is returned instead, unless it expired or never
+// finished, in which case the key is taken over.
+func (apiCfg *ApiConfig) claimIdempotencyKey(ctx context.Context, userID uuid.UUID, key, fingerprint string) (database.IdempotencyKey, *database.IdempotencyKey, error) {
+	for range 2 {
+		claim, err := apiCfg.DbQueries.CreateIdempotencyKey(ctx, database.CreateIdempotencyKeyParams{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint,
+		})
+		if err == nil {
+			return claim, nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return database.IdempotencyKey{}, nil, err
+		}
+
+		previous, err := apiCfg.DbQueries.GetIdempotencyKey(ctx, database.GetIdempotencyKeyParams{
+			UserID: userID,
+			Key:    key,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// The request that had it failed in the meantime.
+			continue
+		}
+		if err != nil {
+			return database.IdempotencyKey{}, nil, err
+		}
+		if !apiCfg.idempotencyKeyStale(previous, time.Now().UTC()) {
+			return database.IdempotencyKey{}, &previous, nil
+		}
+		err = apiCfg.DbQueries.DeleteIdempotencyKey(ctx, database.DeleteIdempotencyKeyParams{
+			UserID:    userID,
+			Key:       key,
+			CreatedAt: previous.CreatedAt,
+		})
+		if err != nil {
+			return database.IdempotencyKey{}, nil, err
+		}
+	}
+	return database.IdempotencyKey{}, nil, errIdempotencyKeyInUse
+}
+
+func (apiCfg *ApiConfig) idempotencyKeyStale(key database.IdempotencyKey, now time.Time) bool {
+	if key.CreatedAt.Before(now.Add(-apiCfg.IdempotencyKeyRetention)) {
+		return true
+	}
+	return !key.ResponseStatus.Valid && key.CreatedAt.Before(now.Add(-idempotencyLockTimeout))
+}
+
+// finishIdempotentRequest keeps a successful response for retries, and
+// releases the key of a failed request so that a retry runs it again. The
+// response was already sent, so errors are only logged.
+func (apiCfg *ApiConfig) finishIdempotentRequest(ctx context.Context, claim database.IdempotencyKey, resp idempotency.Response) {
+	if !resp.Successful() {
+		err := apiCfg.DbQueries.DeleteIdempotencyKey(ctx, database.DeleteIdempotencyKeyParams{
+			UserID:    claim.UserID,
+			Key:       claim.Key,
+			CreatedAt: claim.CreatedAt,
+		})
+		if err != nil {
+			log.Printf("failed to release idempotency key of user %v: %s", claim.UserID, err)
+		}
+		return
+	}
+
+	header, err := json.Marshal(resp.Header)
+	if err == nil {
+		err = apiCfg.DbQueries.SaveIdempotentResponse(ctx, database.SaveIdempotentResponseParams{
+			UserID:          claim.UserID,
+			Key:             claim.Key,
+			ResponseStatus:  sql.NullInt32{Int32: int32(resp.Status), Valid: true},
+			ResponseHeaders: header,
+			ResponseBody:    resp.Body,
+		})
+	}
+	if err != nil {
+		log.Printf("failed to save idempotent response of user %v: %s", claim.UserID, err)
+	}
+}
+
+// RunIdempotencyKeyCleanup deletes idempotency keys older than
+// IdempotencyKeyRetention every interval until ctx is cancelled. Expired
+// keys are already ignored when requests come in.
+func (apiCfg *ApiConfig) RunIdempotencyKeyCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := apiCfg.DbQueries.DeleteExpiredIdempotencyKeys(ctx, time.Now().UTC().Add(-apiCfg.IdempotencyKeyRetention))
+		if err != nil {
+			log.Printf("failed to delete expired idempotency keys: %s", err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/idempotency"
+)
+
+func TestIdempotentRoute(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{method: http.MethodPost, path: "/api/chirps", want: true},
+		{method: http.MethodPost, path: "/api/chirps/5b1f7a52-6a3f-4d7e-9a43-1b3f0c2a9e10/reports", want: true},
+		{method: http.MethodPost, path: "/api/drafts/5b1f7a52-6a3f-4d7e-9a43-1b3f0c2a9e10/publish", want: true},
+		{method: http.MethodGet, path: "/api/chirps"},
+		{method: http.MethodPut, path: "/api/drafts/5b1f7a52-6a3f-4d7e-9a43-1b3f0c2a9e10"},
+		{method: http.MethodPost, path: "/api/chirps/"},
+		{method: http.MethodPost, path: "/api/users"},
+		{method: http.MethodPost, path: "/api/login"},
+		{method: http.MethodPost, path: "/api/login/mfa"},
+		{method: http.MethodPost, path: "/api/refresh"},
+		{method: http.MethodPost, path: "/api/tokens"},
+		{method: http.MethodPost, path: "/api/oauth/clients"},
+		{method: http.MethodPost, path: "/oauth/token"},
+		{method: http.MethodPost, path: "/api/totp/enroll"},
+		{method: http.MethodPost, path: "/api/totp/confirm"},
+		{method: http.MethodPost, path: "/api/passkeys/login/finish"},
+		{method: http.MethodPost, path: "/api/exports"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := idempotentRoute(r); got != tt.want {
+			t.Errorf("idempotentRoute(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestIdempotentRoutesReturnNoSecrets checks the documented responses of
+// every idempotent route, so that a route added to the list later can't
+// leak a credential into idempotency_keys.
+func TestIdempotentRoutesReturnNoSecrets(t *testing.T) {
+	spec := struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}{}
+	err := json.Unmarshal(openAPISpec, &spec)
+	if err != nil {
+		t.Fatalf("failed to parse openapi.json: %v", err)
+	}
+
+	secretWords := []string{"token", "secret", "recovery", "password", "code", "url"}
+	for _, route := range idempotentRoutes {
+		method, path, _ := strings.Cut(route, " ")
+		operation, ok := spec.Paths[path][strings.ToLower(method)]
+		if !ok {
+			t.Errorf("%s isn't in openapi.json", route)
+			continue
+		}
+		for _, property := range responseProperties(t, operation, spec.Components.Schemas) {
+			for _, word := range secretWords {
+				if strings.Contains(property, word) {
+					t.Errorf("%s responds with %q, which looks like a credential", route, property)
+				}
+			}
+		}
+	}
+}
+
+// responseProperties lists the property names of an operation's successful
+// responses, following references.
+func responseProperties(t *testing.T, operation json.RawMessage, schemas map[string]json.RawMessage) []string {
+	t.Helper()
+	op := struct {
+		Responses map[string]struct {
+			Content map[string]struct {
+				Schema json.RawMessage `json:"schema"`
+			} `json:"content"`
+		} `json:"responses"`
+	}{}
+	err := json.Unmarshal(operation, &op)
+	if err != nil {
+		t.Fatalf("failed to parse operation: %v", err)
+	}
+
+	properties := []string{}
+	seen := map[string]bool{}
+	var walk func(raw json.RawMessage)
+	walk = func(raw json.RawMessage) {
+		schema := struct {
+			Ref        string                     `json:"$ref"`
+			Properties map[string]json.RawMessage `json:"properties"`
+			Items      json.RawMessage            `json:"items"`
+			OneOf      []json.RawMessage          `json:"oneOf"`
+			AllOf      []json.RawMessage          `json:"allOf"`
+		}{}
+		if len(raw) == 0 || json.Unmarshal(raw, &schema) != nil {
+			return
+		}
+		if name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/"); ok {
+			if !seen[name] {
+				seen[name] = true
+				walk(schemas[name])
+			}
+			return
+		}
+		for name, property := range schema.Properties {
+			properties = append(properties, name)
+			walk(property)
+		}
+		walk(schema.Items)
+		for _, sub := range append(schema.OneOf, schema.AllOf...) {
+			walk(sub)
+		}
+	}
+	for status, response := range op.Responses {
+		if !strings.HasPrefix(status, "2") {
+			continue
+		}
+		for _, content := range response.Content {
+			walk(content.Schema)
+		}
+	}
+	return properties
+}
+
+func TestIdempotencyNeverStoresCredentials(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	user, token := createTestUser(t, apiCfg, "alice@example.com")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chirps", apiCfg.CreateChirpsHandler)
+	mux.HandleFunc("POST /api/tokens", apiCfg.CreatePersonalAccessTokenHandler)
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.CreateOAuthClientHandler)
+	mux.HandleFunc("POST /api/totp/enroll", apiCfg.EnrollTOTPHandler)
+	handler := apiCfg.MiddlewareIdempotency(mux)
+
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		r.Header.Set(idempotency.Header, key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	credentialRoutes := []struct {
+		path string
+		body string
+	}{
+		{path: "/api/tokens", body: `{"name":"ci","scopes":["chirps:read"]}`},
+		{path: "/api/oauth/clients", body: `{"name":"app","redirect_uris":["https://app.example.com/callback"],"confidential":true}`},
+		{path: "/api/totp/enroll", body: `{}`},
+	}
+	for _, route := range credentialRoutes {
+		key := "key " + route.path
+		first := send(route.path, key, route.body)
+		if first.Code/100 != 2 {
+			t.Fatalf("POST %s = %d %s", route.path, first.Code, first.Body)
+		}
+		second := send(route.path, key, route.body)
+		if second.Header().Get(idempotency.ReplayedHeader) != "" {
+			t.Errorf("POST %s was replayed, want it run again", route.path)
+		}
+		_, err := apiCfg.DbQueries.GetIdempotencyKey(context.Background(), database.GetIdempotencyKeyParams{
+			UserID: user.ID,
+			Key:    key,
+		})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("POST %s stored its response under the idempotency key (error %v)", route.path, err)
+		}
+	}
+
+	first := send("/api/chirps", "chirp", `{"body":"hello"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("POST /api/chirps = %d %s", first.Code, first.Body)
+	}
+	second := send("/api/chirps", "chirp", `{"body":"hello"}`)
+	if second.Header().Get(idempotency.ReplayedHeader) != "true" || second.Body.String() != first.Body.String() {
+		t.Errorf("retried POST /api/chirps = %d %s, want the first response replayed", second.Code, second.Body)
+	}
+}
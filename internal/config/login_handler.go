@@ -9,17 +9,16 @@ import (
 	"github.com/OferRavid/chirpy/internal/database"
 )
 
+// mfaTokenDuration is how long a user has to enter their second factor
+// after a successful password check.
+const mfaTokenDuration = 5 * time.Minute
+
 func (apiCfg *ApiConfig) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 		// ExpiresInSeconds int64  `json:"expires_in_seconds"`
 	}
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
-	}
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
@@ -29,11 +28,6 @@ func (apiCfg *ApiConfig) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	duration := time.Hour
-	// if params.ExpiresInSeconds > 0 && params.ExpiresInSeconds < 3600 {
-	// 	duration = time.Duration(params.ExpiresInSeconds) * time.Second
-	// }
-
 	user, err := apiCfg.DbQueries.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
@@ -46,6 +40,61 @@ func (apiCfg *ApiConfig) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	apiCfg.respondWithLogin(w, r, user)
+}
+
+// respondWithLogin finishes the first step of a login. Users with two-factor
+// authentication get an MFA token to exchange at LoginMFAHandler, everyone
+// else gets a session right away.
+func (apiCfg *ApiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	type mfaResponse struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}
+
+	if user.TotpEnabled {
+		mfaToken, err := auth.MakeMFAToken(user.ID, apiCfg.Secret, mfaTokenDuration)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create MFA token", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, mfaResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
+		return
+	}
+
+	apiCfg.respondWithSession(w, r, user)
+}
+
+// respondWithSession issues a new access JWT and refresh token pair for a
+// fully authenticated user and writes the login response. Logging in
+// cancels a pending account deletion.
+func (apiCfg *ApiConfig) respondWithSession(w http.ResponseWriter, r *http.Request, user database.User) {
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if user.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account suspended", nil)
+		return
+	}
+	if user.DeletionScheduledAt.Valid {
+		err := apiCfg.DbQueries.CancelUserDeletion(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't cancel account deletion", err)
+			return
+		}
+	}
+
+	duration := time.Hour
+	// if params.ExpiresInSeconds > 0 && params.ExpiresInSeconds < 3600 {
+	// 	duration = time.Duration(params.ExpiresInSeconds) * time.Second
+	// }
+
 	token, err := auth.MakeJWT(user.ID, apiCfg.Secret, duration)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create access JWT", err)
@@ -70,6 +119,12 @@ func (apiCfg *ApiConfig) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	isChirpyRed, err := apiCfg.DbQueries.HasActiveMembership(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve membership", err)
+		return
+	}
+
 	respondWithJSON(
 		w,
 		http.StatusOK,
@@ -77,9 +132,9 @@ func (apiCfg *ApiConfig) LoginHandler(w http.ResponseWriter, r *http.Request) {
 			User: User{
 				ID:          user.ID,
 				CreatedAt:   user.CreatedAt,
-				UpdatedAt:   user.CreatedAt,
+				UpdatedAt:   user.UpdatedAt,
 				Email:       user.Email,
-				IsChirpyRed: user.IsChirpyRed,
+				IsChirpyRed: isChirpyRed,
 			},
 			Token:        token,
 			RefreshToken: refreshToken.Token,
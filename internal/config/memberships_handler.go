@@ -0,0 +1,168 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// A user has at most one "active" membership. It stays active until its end
+// date, after which the expiry job marks it expired, or "cancelled" if the
+// user cancelled renewal. A downgrade, or an operator revoking it, ends it
+// immediately.
+const (
+	membershipStatusExpired    = "expired"
+	membershipStatusDowngraded = "downgraded"
+	membershipStatusRevoked    = "revoked"
+)
+
+// Where a membership comes from. The payment provider only manages the
+// memberships it bills for; an operator's grant lasts until it ends or is
+// revoked.
+const (
+	membershipSourceProvider = "provider"
+	membershipSourceAdmin    = "admin"
+)
+
+type Membership struct {
+	ID          uuid.UUID  `json:"id"`
+	Plan        string     `json:"plan"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	CancelledAt *time.Time `json:"cancelled_at"`
+}
+
+// ListMembershipsHandler returns the user's plan history, newest first.
+func (apiCfg *ApiConfig) ListMembershipsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbMemberships, err := apiCfg.DbQueries.GetMembershipsByUserID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve memberships", err)
+		return
+	}
+
+	memberships := []Membership{}
+	for _, dbMembership := range dbMemberships {
+		memberships = append(memberships, membershipFromDB(dbMembership))
+	}
+	respondWithJSON(w, http.StatusOK, memberships)
+}
+
+// upgradeMembership starts a membership, or changes the plan, period and
+// source of the one that's already active, undoing any pending
+// cancellation.
+func (apiCfg *ApiConfig) upgradeMembership(ctx context.Context, userID uuid.UUID, plan string, endsAt sql.NullTime, source string) error {
+	current, err := apiCfg.currentMembership(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		_, err = apiCfg.DbQueries.CreateMembership(ctx, database.CreateMembershipParams{
+			UserID: userID,
+			Plan:   plan,
+			EndsAt: endsAt,
+			Source: source,
+		})
+		return err
+	}
+
+	if !endsAt.Valid {
+		endsAt = current.EndsAt
+	}
+	return apiCfg.DbQueries.UpdateMembershipPeriod(ctx, database.UpdateMembershipPeriodParams{
+		ID:     current.ID,
+		Plan:   plan,
+		EndsAt: endsAt,
+		Source: source,
+	})
+}
+
+// cancelMembership stops the active membership from renewing. It stays
+// active until the end of the paid period.
+func (apiCfg *ApiConfig) cancelMembership(ctx context.Context, userID uuid.UUID) (bool, error) {
+	current, err := apiCfg.currentMembership(ctx, userID)
+	if err != nil || current == nil {
+		return false, err
+	}
+	return true, apiCfg.DbQueries.CancelMembership(ctx, current.ID)
+}
+
+// endMembership ends the active membership immediately with the given status.
+func (apiCfg *ApiConfig) endMembership(ctx context.Context, userID uuid.UUID, status string) (bool, error) {
+	current, err := apiCfg.currentMembership(ctx, userID)
+	if err != nil || current == nil {
+		return false, err
+	}
+	return true, apiCfg.DbQueries.EndMembership(ctx, database.EndMembershipParams{
+		ID:     current.ID,
+		Status: status,
+	})
+}
+
+func (apiCfg *ApiConfig) currentMembership(ctx context.Context, userID uuid.UUID) (*database.Membership, error) {
+	current, err := apiCfg.DbQueries.GetCurrentMembership(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't retrieve membership: %w", err)
+	}
+	return &current, nil
+}
+
+// RunMembershipExpiry marks lapsed memberships as ended every interval until
+// ctx is cancelled.
+func (apiCfg *ApiConfig) RunMembershipExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := apiCfg.DbQueries.ExpireMemberships(ctx)
+		if err != nil {
+			log.Printf("failed to expire memberships: %s", err)
+		} else if expired > 0 {
+			log.Printf("expired %d memberships", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func membershipFromDB(membership database.Membership) Membership {
+	response := Membership{
+		ID:        membership.ID,
+		Plan:      membership.Plan,
+		Status:    membership.Status,
+		StartedAt: membership.StartedAt,
+	}
+	if membership.EndsAt.Valid {
+		response.EndsAt = &membership.EndsAt.Time
+	}
+	if membership.CancelledAt.Valid {
+		response.CancelledAt = &membership.CancelledAt.Time
+	}
+	return response
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/payments"
+	"github.com/google/uuid"
+)
+
+func TestMembershipLifecycle(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+
+	period := func(d time.Duration) sql.NullTime {
+		return sql.NullTime{Time: time.Now().UTC().Add(d), Valid: true}
+	}
+	// lapse moves the end of the user's active membership into the past, as
+	// if its period ran out.
+	lapse := func(t *testing.T, userID uuid.UUID) {
+		t.Helper()
+		_, err := apiCfg.DB.Exec("UPDATE memberships SET ends_at = NOW() - interval '1 minute' WHERE user_id = $1 AND status = 'active'", userID)
+		if err != nil {
+			t.Fatalf("failed to lapse membership: %v", err)
+		}
+	}
+	checkPlan := func(t *testing.T, userID uuid.UUID, want string) {
+		t.Helper()
+		entitlements, err := apiCfg.DbQueries.GetUserEntitlements(ctx, userID)
+		if err != nil || entitlements.Plan != want {
+			t.Errorf("GetUserEntitlements() plan = %q (error %v), want %q", entitlements.Plan, err, want)
+		}
+	}
+	expire := func(t *testing.T) {
+		t.Helper()
+		_, err := apiCfg.DbQueries.ExpireMemberships(ctx)
+		if err != nil {
+			t.Fatalf("ExpireMemberships() error = %v", err)
+		}
+	}
+
+	t.Run("Renewal", func(t *testing.T) {
+		user, _ := createTestUser(t, apiCfg, "renewed@example.com")
+		err := apiCfg.upgradeMembership(ctx, user.ID, payments.DefaultPlan, period(time.Hour), membershipSourceProvider)
+		if err != nil {
+			t.Fatalf("upgradeMembership() error = %v", err)
+		}
+		_, err = apiCfg.cancelMembership(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("cancelMembership() error = %v", err)
+		}
+
+		renewedUntil := period(30 * 24 * time.Hour)
+		err = apiCfg.upgradeMembership(ctx, user.ID, payments.DefaultPlan, renewedUntil, membershipSourceProvider)
+		if err != nil {
+			t.Fatalf("upgradeMembership() error = %v", err)
+		}
+		memberships, err := apiCfg.DbQueries.GetMembershipsByUserID(ctx, user.ID)
+		if err != nil || len(memberships) != 1 {
+			t.Fatalf("GetMembershipsByUserID() = %d memberships (error %v), want the renewal to extend the one", len(memberships), err)
+		}
+		renewed := memberships[0]
+		if renewed.Status != "active" || renewed.CancelledAt.Valid {
+			t.Errorf("renewed membership status = %q, cancelled = %v, want active and no longer cancelled", renewed.Status, renewed.CancelledAt.Valid)
+		}
+		if renewed.EndsAt.Time.Sub(renewedUntil.Time).Abs() > time.Millisecond {
+			t.Errorf("renewed membership ends at %v, want %v", renewed.EndsAt.Time, renewedUntil.Time)
+		}
+		checkPlan(t, user.ID, payments.DefaultPlan)
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		user, _ := createTestUser(t, apiCfg, "cancelled@example.com")
+		err := apiCfg.upgradeMembership(ctx, user.ID, payments.DefaultPlan, period(time.Hour), membershipSourceProvider)
+		if err != nil {
+			t.Fatalf("upgradeMembership() error = %v", err)
+		}
+		cancelled, err := apiCfg.cancelMembership(ctx, user.ID)
+		if err != nil || !cancelled {
+			t.Fatalf("cancelMembership() = %v (error %v), want true", cancelled, err)
+		}
+
+		// The paid period still counts.
+		expire(t)
+		checkPlan(t, user.ID, payments.DefaultPlan)
+
+		lapse(t, user.ID)
+		checkPlan(t, user.ID, "free")
+		expire(t)
+		memberships, err := apiCfg.DbQueries.GetMembershipsByUserID(ctx, user.ID)
+		if err != nil || len(memberships) != 1 || memberships[0].Status != "cancelled" {
+			t.Errorf("GetMembershipsByUserID() = %+v (error %v), want one cancelled membership", memberships, err)
+		}
+		cancelled, err = apiCfg.cancelMembership(ctx, user.ID)
+		if err != nil || cancelled {
+			t.Errorf("cancelMembership() after it ended = %v (error %v), want false", cancelled, err)
+		}
+	})
+
+	t.Run("Cancelling a membership without an end", func(t *testing.T) {
+		user, _ := createTestUser(t, apiCfg, "granted@example.com")
+		err := apiCfg.GrantMembership(ctx, user.ID, payments.DefaultPlan, nil)
+		if err != nil {
+			t.Fatalf("GrantMembership() error = %v", err)
+		}
+		_, err = apiCfg.cancelMembership(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("cancelMembership() error = %v", err)
+		}
+		checkPlan(t, user.ID, "free")
+		_, err = apiCfg.DbQueries.GetCurrentMembership(ctx, user.ID)
+		if err == nil {
+			t.Errorf("GetCurrentMembership() found a membership, want it cancelled right away")
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		user, token := createTestUser(t, apiCfg, "expired@example.com")
+		err := apiCfg.upgradeMembership(ctx, user.ID, payments.DefaultPlan, period(time.Hour), membershipSourceProvider)
+		if err != nil {
+			t.Fatalf("upgradeMembership() error = %v", err)
+		}
+		lapse(t, user.ID)
+		expire(t)
+		checkPlan(t, user.ID, "free")
+
+		// Subscribing again starts a new membership rather than reviving
+		// the expired one.
+		err = apiCfg.upgradeMembership(ctx, user.ID, payments.DefaultPlan, period(time.Hour), membershipSourceProvider)
+		if err != nil {
+			t.Fatalf("upgradeMembership() error = %v", err)
+		}
+		checkPlan(t, user.ID, payments.DefaultPlan)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/memberships", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		apiCfg.ListMembershipsHandler(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/memberships = %d %s", w.Code, w.Body)
+		}
+		memberships := []Membership{}
+		json.Unmarshal(w.Body.Bytes(), &memberships)
+		statuses := []string{}
+		for _, membership := range memberships {
+			statuses = append(statuses, membership.Status)
+		}
+		if len(statuses) != 2 || statuses[0] != "active" || statuses[1] != membershipStatusExpired {
+			t.Errorf("GET /api/memberships statuses = %v, want [active %s]", statuses, membershipStatusExpired)
+		}
+	})
+}
@@ -0,0 +1,470 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	caseStatusOpen     = "open"
+	caseStatusClaimed  = "claimed"
+	caseStatusResolved = "resolved"
+
+	// Decisions a moderator can resolve a case with.
+	moderationActionDismiss     = "dismiss"
+	moderationActionHideChirp   = "hide_chirp"
+	moderationActionWarn        = "warn"
+	moderationActionSuspendUser = "suspend_user"
+
+	// moderationActionAutoHide is recorded when a chirp is hidden for
+	// collecting too many reports, before any moderator saw it.
+	moderationActionAutoHide = "auto_hide"
+)
+
+var (
+	caseStatuses      = []string{caseStatusOpen, caseStatusClaimed, caseStatusResolved}
+	moderationActions = []string{moderationActionDismiss, moderationActionHideChirp, moderationActionWarn, moderationActionSuspendUser}
+)
+
+// ModerationCase is the reports about a chirp and what was decided. The
+// case keeps the chirp as it was first reported and who wrote it, so it
+// outlives the chirp; ChirpID is null once the chirp is deleted.
+type ModerationCase struct {
+	ID          uuid.UUID          `json:"id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	ChirpID     *uuid.UUID         `json:"chirp_id"`
+	AuthorID    *uuid.UUID         `json:"author_id"`
+	ChirpBody   string             `json:"chirp_body"`
+	Status      string             `json:"status"`
+	ClaimedBy   *uuid.UUID         `json:"claimed_by"`
+	ClaimedAt   *time.Time         `json:"claimed_at"`
+	ResolvedAt  *time.Time         `json:"resolved_at"`
+	Resolution  string             `json:"resolution,omitempty"`
+	ReportCount int64              `json:"report_count"`
+	Chirp       *Chirp             `json:"chirp,omitempty"`
+	Reports     []Report           `json:"reports,omitempty"`
+	Actions     []ModerationAction `json:"actions,omitempty"`
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CaseID       *uuid.UUID `json:"case_id"`
+	ModeratorID  *uuid.UUID `json:"moderator_id"`
+	Action       string     `json:"action"`
+	TargetUserID *uuid.UUID `json:"target_user_id"`
+	ChirpID      *uuid.UUID `json:"chirp_id"`
+	Note         string     `json:"note"`
+}
+
+// ListModerationCasesHandler lists the moderation queue, oldest case first.
+// It shows open cases unless asked for another status.
+func (apiCfg *ApiConfig) ListModerationCasesHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateModerator(w, r)
+	if err != nil {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = caseStatusOpen
+	}
+	if !slices.Contains(caseStatuses, status) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("status must be one of %v", caseStatuses), nil)
+		return
+	}
+
+	dbCases, err := apiCfg.DbQueries.ListModerationCases(r.Context(), database.ListModerationCasesParams{
+		Status: status,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve moderation cases", err)
+		return
+	}
+
+	cases := []ModerationCase{}
+	for _, dbCase := range dbCases {
+		moderationCase := moderationCaseFromDB(database.ModerationCase{
+			ID:         dbCase.ID,
+			CreatedAt:  dbCase.CreatedAt,
+			UpdatedAt:  dbCase.UpdatedAt,
+			ChirpID:    dbCase.ChirpID,
+			Status:     dbCase.Status,
+			ClaimedBy:  dbCase.ClaimedBy,
+			ClaimedAt:  dbCase.ClaimedAt,
+			ResolvedAt: dbCase.ResolvedAt,
+			Resolution: dbCase.Resolution,
+			AuthorID:   dbCase.AuthorID,
+			ChirpBody:  dbCase.ChirpBody,
+		})
+		moderationCase.ReportCount = dbCase.ReportCount
+		cases = append(cases, moderationCase)
+	}
+	respondWithJSON(w, http.StatusOK, cases)
+}
+
+// GetModerationCaseHandler returns a case along with the reported chirp,
+// hidden or not and unless it was deleted, its reports and the decisions
+// taken on it so far.
+func (apiCfg *ApiConfig) GetModerationCaseHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateModerator(w, r)
+	if err != nil {
+		return
+	}
+
+	dbCase, err := apiCfg.getModerationCase(w, r)
+	if err != nil {
+		return
+	}
+
+	moderationCase, err := apiCfg.moderationCaseDetails(r.Context(), dbCase)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve moderation case", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, moderationCase)
+}
+
+// ClaimModerationCaseHandler assigns an open case to the calling moderator
+// so two moderators don't work on the same reports. Claiming a case again
+// is a no-op.
+func (apiCfg *ApiConfig) ClaimModerationCaseHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, err := apiCfg.authenticateModerator(w, r)
+	if err != nil {
+		return
+	}
+
+	dbCase, err := apiCfg.getModerationCase(w, r)
+	if err != nil {
+		return
+	}
+	if dbCase.Status == caseStatusClaimed && dbCase.ClaimedBy.UUID == moderator.ID {
+		respondWithJSON(w, http.StatusOK, moderationCaseFromDB(dbCase))
+		return
+	}
+
+	dbCase, err = apiCfg.DbQueries.ClaimModerationCase(r.Context(), database.ClaimModerationCaseParams{
+		ID:        dbCase.ID,
+		ClaimedBy: uuid.NullUUID{UUID: moderator.ID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "Case is already claimed or resolved", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim moderation case", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, moderationCaseFromDB(dbCase))
+}
+
+// ResolveModerationCaseHandler closes a case with a decision and records it
+// in the audit trail. Moderators resolve the cases they claimed; admins can
+// resolve any unresolved case. Dismissing a case restores a chirp that was
+// hidden automatically, and warning the author shows them the note.
+func (apiCfg *ApiConfig) ResolveModerationCaseHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, err := apiCfg.authenticateModerator(w, r)
+	if err != nil {
+		return
+	}
+
+	type parameters struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+	if !slices.Contains(moderationActions, params.Action) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("action must be one of %v", moderationActions), nil)
+		return
+	}
+
+	dbCase, err := apiCfg.getModerationCase(w, r)
+	if err != nil {
+		return
+	}
+	if moderator.Role != roleAdmin && (dbCase.Status != caseStatusClaimed || dbCase.ClaimedBy.UUID != moderator.ID) {
+		respondWithError(w, http.StatusConflict, "Claim the case before resolving it", nil)
+		return
+	}
+	chirp, err := apiCfg.reportedChirp(r.Context(), dbCase)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve reported chirp", err)
+		return
+	}
+	needsAuthor := params.Action == moderationActionWarn || params.Action == moderationActionSuspendUser
+	if needsAuthor && !dbCase.AuthorID.Valid {
+		respondWithError(w, http.StatusConflict, "The author's account was deleted", nil)
+		return
+	}
+	if params.Action == moderationActionSuspendUser {
+		author, err := apiCfg.DbQueries.GetUserByID(r.Context(), dbCase.AuthorID.UUID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve author", err)
+			return
+		}
+		if author.Role == roleAdmin {
+			respondWithError(w, http.StatusForbidden, "Admin accounts can't be restricted", nil)
+			return
+		}
+	}
+
+	// The case is resolved, the decision carried out and recorded together.
+	// Resolving first makes sure the decision is only carried out once, even
+	// if two requests race.
+	var hidden int64
+	err = apiCfg.inTx(r.Context(), func(q *database.Queries) error {
+		dbCase, err = q.ResolveModerationCase(r.Context(), database.ResolveModerationCaseParams{
+			ID:         dbCase.ID,
+			Resolution: sql.NullString{String: params.Action, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		// A chirp deleted since it was reported has nothing to hide or
+		// restore, so for it those decisions are only recorded.
+		switch params.Action {
+		case moderationActionDismiss:
+			if chirp != nil {
+				err = q.UnhideChirp(r.Context(), chirp.ID)
+			}
+		case moderationActionHideChirp:
+			if chirp != nil {
+				hidden, err = q.HideChirp(r.Context(), chirp.ID)
+			}
+		case moderationActionWarn:
+			chirpBody := dbCase.ChirpBody
+			if chirp != nil {
+				chirpBody = chirp.Body
+			}
+			err = q.CreateWarning(r.Context(), database.CreateWarningParams{
+				UserID:    dbCase.AuthorID.UUID,
+				CaseID:    uuid.NullUUID{UUID: dbCase.ID, Valid: true},
+				ChirpID:   dbCase.ChirpID,
+				ChirpBody: chirpBody,
+				Note:      params.Note,
+			})
+		case moderationActionSuspendUser:
+			err = suspendUser(r.Context(), q, dbCase.AuthorID.UUID)
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't carry out moderation action: %w", err)
+		}
+
+		_, err = q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			CaseID:       uuid.NullUUID{UUID: dbCase.ID, Valid: true},
+			ModeratorID:  uuid.NullUUID{UUID: moderator.ID, Valid: true},
+			Action:       params.Action,
+			TargetUserID: dbCase.AuthorID,
+			ChirpID:      dbCase.ChirpID,
+			Note:         params.Note,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't record moderation action: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "Case is already resolved", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't resolve moderation case", err)
+		return
+	}
+
+	// Other servers follow what's visible here.
+	switch {
+	case params.Action == moderationActionDismiss && chirp != nil && chirp.HiddenAt.Valid:
+		apiCfg.publishChirp(r.Context(), "Create", *chirp)
+	case hidden > 0:
+		apiCfg.publishChirp(r.Context(), "Delete", *chirp)
+	case params.Action == moderationActionSuspendUser:
+		apiCfg.publishUserChirps(r.Context(), "Delete", dbCase.AuthorID.UUID)
+	}
+
+	moderationCase, err := apiCfg.moderationCaseDetails(r.Context(), dbCase)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve moderation case", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, moderationCase)
+}
+
+// ListModerationActionsHandler lists the audit trail of moderation
+// decisions, newest first.
+func (apiCfg *ApiConfig) ListModerationActionsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateModerator(w, r)
+	if err != nil {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	dbActions, err := apiCfg.DbQueries.ListModerationActions(r.Context(), database.ListModerationActionsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve moderation actions", err)
+		return
+	}
+
+	actions := []ModerationAction{}
+	for _, dbAction := range dbActions {
+		actions = append(actions, moderationActionFromDB(dbAction))
+	}
+	respondWithJSON(w, http.StatusOK, actions)
+}
+
+// getModerationCase looks up the case named in the path, responding with an
+// error when there isn't one.
+func (apiCfg *ApiConfig) getModerationCase(w http.ResponseWriter, r *http.Request) (database.ModerationCase, error) {
+	caseID, err := uuid.Parse(r.PathValue("caseID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse caseID", err)
+		return database.ModerationCase{}, err
+	}
+
+	dbCase, err := apiCfg.DbQueries.GetModerationCase(r.Context(), caseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Couldn't find moderation case", err)
+			return database.ModerationCase{}, err
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve moderation case", err)
+		return database.ModerationCase{}, err
+	}
+	return dbCase, nil
+}
+
+// reportedChirp returns the chirp a case is about, or nil when it has been
+// deleted since.
+func (apiCfg *ApiConfig) reportedChirp(ctx context.Context, dbCase database.ModerationCase) (*database.Chirp, error) {
+	if !dbCase.ChirpID.Valid {
+		return nil, nil
+	}
+	chirp, err := apiCfg.DbQueries.GetChirpByID(ctx, dbCase.ChirpID.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &chirp, nil
+}
+
+func (apiCfg *ApiConfig) moderationCaseDetails(ctx context.Context, dbCase database.ModerationCase) (ModerationCase, error) {
+	moderationCase := moderationCaseFromDB(dbCase)
+
+	chirp, err := apiCfg.reportedChirp(ctx, dbCase)
+	if err != nil {
+		return ModerationCase{}, err
+	}
+	if chirp != nil {
+		moderationCase.Chirp = &Chirp{
+			ID:        chirp.ID,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Body:      chirp.Body,
+			UserID:    chirp.UserID,
+		}
+	}
+
+	dbReports, err := apiCfg.DbQueries.GetReportsByCaseID(ctx, dbCase.ID)
+	if err != nil {
+		return ModerationCase{}, err
+	}
+	moderationCase.Reports = []Report{}
+	for _, dbReport := range dbReports {
+		moderationCase.Reports = append(moderationCase.Reports, reportFromDB(dbReport))
+	}
+	moderationCase.ReportCount = int64(len(dbReports))
+
+	dbActions, err := apiCfg.DbQueries.GetModerationActionsByCaseID(ctx, uuid.NullUUID{UUID: dbCase.ID, Valid: true})
+	if err != nil {
+		return ModerationCase{}, err
+	}
+	moderationCase.Actions = []ModerationAction{}
+	for _, dbAction := range dbActions {
+		moderationCase.Actions = append(moderationCase.Actions, moderationActionFromDB(dbAction))
+	}
+	return moderationCase, nil
+}
+
+func moderationCaseFromDB(dbCase database.ModerationCase) ModerationCase {
+	moderationCase := ModerationCase{
+		ID:         dbCase.ID,
+		CreatedAt:  dbCase.CreatedAt,
+		UpdatedAt:  dbCase.UpdatedAt,
+		ChirpBody:  dbCase.ChirpBody,
+		Status:     dbCase.Status,
+		Resolution: dbCase.Resolution.String,
+	}
+	if dbCase.ChirpID.Valid {
+		moderationCase.ChirpID = &dbCase.ChirpID.UUID
+	}
+	if dbCase.AuthorID.Valid {
+		moderationCase.AuthorID = &dbCase.AuthorID.UUID
+	}
+	if dbCase.ClaimedBy.Valid {
+		moderationCase.ClaimedBy = &dbCase.ClaimedBy.UUID
+	}
+	if dbCase.ClaimedAt.Valid {
+		moderationCase.ClaimedAt = &dbCase.ClaimedAt.Time
+	}
+	if dbCase.ResolvedAt.Valid {
+		moderationCase.ResolvedAt = &dbCase.ResolvedAt.Time
+	}
+	return moderationCase
+}
+
+func moderationActionFromDB(dbAction database.ModerationAction) ModerationAction {
+	action := ModerationAction{
+		ID:        dbAction.ID,
+		CreatedAt: dbAction.CreatedAt,
+		Action:    dbAction.Action,
+		Note:      dbAction.Note,
+	}
+	if dbAction.CaseID.Valid {
+		action.CaseID = &dbAction.CaseID.UUID
+	}
+	if dbAction.ModeratorID.Valid {
+		action.ModeratorID = &dbAction.ModeratorID.UUID
+	}
+	if dbAction.TargetUserID.Valid {
+		action.TargetUserID = &dbAction.TargetUserID.UUID
+	}
+	if dbAction.ChirpID.Valid {
+		action.ChirpID = &dbAction.ChirpID.UUID
+	}
+	return action
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestResolveModerationCaseOnce(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+
+	admin, adminToken := createTestUser(t, apiCfg, "admin@example.com")
+	_, err := apiCfg.DB.Exec("UPDATE users SET role = $1 WHERE id = $2", roleAdmin, admin.ID)
+	if err != nil {
+		t.Fatalf("failed to make admin: %v", err)
+	}
+	author, _ := createTestUser(t, apiCfg, "author@example.com")
+	chirp, err := apiCfg.DbQueries.CreateChirp(ctx, database.CreateChirpParams{
+		Body:   "spam",
+		UserID: author.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	moderationCase, err := apiCfg.DbQueries.OpenModerationCase(ctx, database.OpenModerationCaseParams{
+		ChirpID:   uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AuthorID:  uuid.NullUUID{UUID: author.ID, Valid: true},
+		ChirpBody: chirp.Body,
+	})
+	if err != nil {
+		t.Fatalf("OpenModerationCase() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /admin/moderation/cases/{caseID}/resolve", apiCfg.ResolveModerationCaseHandler)
+	resolve := func() int {
+		r := httptest.NewRequest(http.MethodPost, "/admin/moderation/cases/"+moderationCase.ID.String()+"/resolve", strings.NewReader(`{"action":"hide_chirp"}`))
+		r.Header.Set("Authorization", "Bearer "+adminToken)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := resolve(); code != http.StatusOK {
+		t.Fatalf("first resolve = %d, want %d", code, http.StatusOK)
+	}
+	if code := resolve(); code != http.StatusConflict {
+		t.Errorf("second resolve = %d, want %d", code, http.StatusConflict)
+	}
+
+	chirp, err = apiCfg.DbQueries.GetChirpByID(ctx, chirp.ID)
+	if err != nil || !chirp.HiddenAt.Valid {
+		t.Errorf("chirp hidden = %v (error %v), want it hidden", chirp.HiddenAt.Valid, err)
+	}
+	actions, err := apiCfg.DbQueries.ListModerationActions(ctx, database.ListModerationActionsParams{Limit: 10})
+	if err != nil || len(actions) != 1 || actions[0].Action != moderationActionHideChirp {
+		t.Errorf("ListModerationActions() = %+v (error %v), want one hide_chirp", actions, err)
+	}
+}
+
+func TestModerationCaseOutlivesDeletedChirp(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	apiCfg.ReportAutoHideThreshold = 1
+
+	author, authorToken := createTestUser(t, apiCfg, "author@example.com")
+	_, reporterToken := createTestUser(t, apiCfg, "reporter@example.com")
+	chirp, err := apiCfg.DbQueries.CreateChirp(ctx, database.CreateChirpParams{
+		Body:   "spam",
+		UserID: author.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", apiCfg.CreateReportHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.DeleteChirpsHandler)
+	send := func(method, token, body string) *httptest.ResponseRecorder {
+		path := "/api/chirps/" + chirp.ID.String()
+		if method == http.MethodPost {
+			path += "/reports"
+		}
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send(http.MethodPost, reporterToken, `{"reason":"spam"}`); w.Code != http.StatusCreated {
+		t.Fatalf("report = %d %s", w.Code, w.Body)
+	}
+	if w := send(http.MethodDelete, authorToken, ``); w.Code/100 != 2 {
+		t.Fatalf("delete = %d %s", w.Code, w.Body)
+	}
+
+	cases, err := apiCfg.DbQueries.ListModerationCases(ctx, database.ListModerationCasesParams{
+		Status: caseStatusOpen,
+		Limit:  10,
+	})
+	if err != nil || len(cases) != 1 {
+		t.Fatalf("ListModerationCases() = %+v (error %v), want the case kept", cases, err)
+	}
+	moderationCase := cases[0]
+	if moderationCase.ChirpID.Valid || moderationCase.AuthorID.UUID != author.ID || moderationCase.ChirpBody != "spam" || moderationCase.ReportCount != 1 {
+		t.Errorf("case = %+v, want the deleted chirp's author, body and report", moderationCase)
+	}
+	actions, err := apiCfg.DbQueries.GetModerationActionsByCaseID(ctx, uuid.NullUUID{UUID: moderationCase.ID, Valid: true})
+	if err != nil || len(actions) != 1 || actions[0].Action != moderationActionAutoHide {
+		t.Errorf("GetModerationActionsByCaseID() = %+v (error %v), want the auto-hide kept", actions, err)
+	}
+}
+
+func TestResolveModerationCaseCantSuspendAdmin(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+
+	moderator, moderatorToken := createTestUser(t, apiCfg, "moderator@example.com")
+	admin, _ := createTestUser(t, apiCfg, "admin@example.com")
+	for id, role := range map[uuid.UUID]string{moderator.ID: roleModerator, admin.ID: roleAdmin} {
+		_, err := apiCfg.DB.Exec("UPDATE users SET role = $1 WHERE id = $2", role, id)
+		if err != nil {
+			t.Fatalf("failed to set role: %v", err)
+		}
+	}
+	chirp, err := apiCfg.DbQueries.CreateChirp(ctx, database.CreateChirpParams{
+		Body:   "announcement",
+		UserID: admin.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	moderationCase, err := apiCfg.DbQueries.OpenModerationCase(ctx, database.OpenModerationCaseParams{
+		ChirpID:   uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AuthorID:  uuid.NullUUID{UUID: admin.ID, Valid: true},
+		ChirpBody: chirp.Body,
+	})
+	if err != nil {
+		t.Fatalf("OpenModerationCase() error = %v", err)
+	}
+	_, err = apiCfg.DbQueries.ClaimModerationCase(ctx, database.ClaimModerationCaseParams{
+		ID:        moderationCase.ID,
+		ClaimedBy: uuid.NullUUID{UUID: moderator.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("ClaimModerationCase() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /admin/moderation/cases/{caseID}/resolve", apiCfg.ResolveModerationCaseHandler)
+	r := httptest.NewRequest(http.MethodPost, "/admin/moderation/cases/"+moderationCase.ID.String()+"/resolve", strings.NewReader(`{"action":"suspend_user"}`))
+	r.Header.Set("Authorization", "Bearer "+moderatorToken)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("resolve = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	suspended, err := apiCfg.DbQueries.IsUserSuspended(ctx, admin.ID)
+	if err != nil || suspended {
+		t.Errorf("IsUserSuspended() = %v (error %v), want the admin left alone", suspended, err)
+	}
+	moderationCase, err = apiCfg.DbQueries.GetModerationCase(ctx, moderationCase.ID)
+	if err != nil || moderationCase.Status != caseStatusClaimed {
+		t.Errorf("case status = %q (error %v), want it still claimed", moderationCase.Status, err)
+	}
+}
+
+func TestResolveModerationCaseWarnsAuthor(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+
+	admin, adminToken := createTestUser(t, apiCfg, "admin@example.com")
+	_, err := apiCfg.DB.Exec("UPDATE users SET role = $1 WHERE id = $2", roleAdmin, admin.ID)
+	if err != nil {
+		t.Fatalf("failed to make admin: %v", err)
+	}
+	author, authorToken := createTestUser(t, apiCfg, "author@example.com")
+	chirp, err := apiCfg.DbQueries.CreateChirp(ctx, database.CreateChirpParams{
+		Body:   "borderline",
+		UserID: author.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	moderationCase, err := apiCfg.DbQueries.OpenModerationCase(ctx, database.OpenModerationCaseParams{
+		ChirpID:   uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AuthorID:  uuid.NullUUID{UUID: author.ID, Valid: true},
+		ChirpBody: chirp.Body,
+	})
+	if err != nil {
+		t.Fatalf("OpenModerationCase() error = %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /admin/moderation/cases/{caseID}/resolve", apiCfg.ResolveModerationCaseHandler)
+	mux.HandleFunc("GET /api/warnings", apiCfg.ListWarningsHandler)
+	r := httptest.NewRequest(http.MethodPost, "/admin/moderation/cases/"+moderationCase.ID.String()+"/resolve", strings.NewReader(`{"action":"warn","note":"Keep it civil"}`))
+	r.Header.Set("Authorization", "Bearer "+adminToken)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("resolve = %d %s", w.Code, w.Body)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/api/warnings", nil)
+	r.Header.Set("Authorization", "Bearer "+authorToken)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	warnings := []Warning{}
+	json.Unmarshal(w.Body.Bytes(), &warnings)
+	if w.Code != http.StatusOK || len(warnings) != 1 {
+		t.Fatalf("GET /api/warnings = %d %s, want one warning", w.Code, w.Body)
+	}
+	if warnings[0].Note != "Keep it civil" || warnings[0].ChirpBody != "borderline" || *warnings[0].ChirpID != chirp.ID {
+		t.Errorf("warning = %+v, want the note and the chirp", warnings[0])
+	}
+}
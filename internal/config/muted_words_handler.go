@@ -0,0 +1,246 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const (
+	// mutedWordActionHide leaves matching chirps out altogether.
+	mutedWordActionHide = "hide"
+	// mutedWordActionWarn returns matching chirps flagged as filtered, so
+	// clients can collapse them behind a warning.
+	mutedWordActionWarn = "warn"
+
+	maxMutedPhraseLength = 100
+)
+
+var mutedWordActions = []string{mutedWordActionHide, mutedWordActionWarn}
+
+type MutedWord struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Phrase    string     `json:"phrase"`
+	WholeWord bool       `json:"whole_word"`
+	Action    string     `json:"action"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (apiCfg *ApiConfig) CreateMutedWordHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	type parameters struct {
+		Phrase           string `json:"phrase"`
+		WholeWord        bool   `json:"whole_word"`
+		Action           string `json:"action"`
+		ExpiresInSeconds int64  `json:"expires_in_seconds"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	phrase := strings.TrimSpace(params.Phrase)
+	if phrase == "" || len(phrase) > maxMutedPhraseLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("phrase must be between 1 and %d bytes", maxMutedPhraseLength), nil)
+		return
+	}
+	if params.Action == "" {
+		params.Action = mutedWordActionHide
+	}
+	if !slices.Contains(mutedWordActions, params.Action) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("action must be one of %v", mutedWordActions), nil)
+		return
+	}
+	if params.ExpiresInSeconds < 0 {
+		respondWithError(w, http.StatusBadRequest, "expires_in_seconds can't be negative", nil)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresInSeconds > 0 {
+		expiresAt = sql.NullTime{
+			Time:  time.Now().UTC().Add(time.Duration(params.ExpiresInSeconds) * time.Second),
+			Valid: true,
+		}
+	}
+
+	mutedWord, err := apiCfg.DbQueries.CreateMutedWord(r.Context(), database.CreateMutedWordParams{
+		UserID:    user_id,
+		Phrase:    phrase,
+		WholeWord: params.WholeWord,
+		Action:    params.Action,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create muted word", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, mutedWordFromDB(mutedWord))
+}
+
+// ListMutedWordsHandler lists the caller's muted words that haven't expired.
+func (apiCfg *ApiConfig) ListMutedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsRead)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbMutedWords, err := apiCfg.DbQueries.GetActiveMutedWords(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve muted words", err)
+		return
+	}
+
+	mutedWords := []MutedWord{}
+	for _, dbMutedWord := range dbMutedWords {
+		mutedWords = append(mutedWords, mutedWordFromDB(dbMutedWord))
+	}
+	respondWithJSON(w, http.StatusOK, mutedWords)
+}
+
+func (apiCfg *ApiConfig) DeleteMutedWordHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	wordID, err := uuid.Parse(r.PathValue("wordID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse wordID", err)
+		return
+	}
+
+	deleted, err := apiCfg.DbQueries.DeleteMutedWord(r.Context(), database.DeleteMutedWordParams{
+		ID:     wordID,
+		UserID: user_id,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete muted word", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find muted word", nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// RunMutedWordCleanup deletes expired muted words every interval until ctx
+// is cancelled. Expired words are already ignored when reading chirps.
+func (apiCfg *ApiConfig) RunMutedWordCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		_, err := apiCfg.DbQueries.DeleteExpiredMutedWords(ctx)
+		if err != nil {
+			log.Printf("failed to delete expired muted words: %s", err)
+		}
+	}
+}
+
+// chirpFilter applies a viewer's muted words to the chirps they read.
+type chirpFilter struct {
+	viewer uuid.NullUUID
+	hide   []moderation.MutedWord
+	warn   []moderation.MutedWord
+}
+
+// chirpFilterFor loads the muted words of viewer. Anonymous viewers get a
+// filter that lets everything through.
+func (apiCfg *ApiConfig) chirpFilterFor(ctx context.Context, viewer uuid.NullUUID) (chirpFilter, error) {
+	filter := chirpFilter{viewer: viewer}
+	if !viewer.Valid {
+		return filter, nil
+	}
+
+	dbMutedWords, err := apiCfg.DbQueries.GetActiveMutedWords(ctx, viewer.UUID)
+	if err != nil {
+		return chirpFilter{}, err
+	}
+	for _, dbMutedWord := range dbMutedWords {
+		mutedWord := moderation.MutedWord{Phrase: dbMutedWord.Phrase, WholeWord: dbMutedWord.WholeWord}
+		if dbMutedWord.Action == mutedWordActionWarn {
+			filter.warn = append(filter.warn, mutedWord)
+		} else {
+			filter.hide = append(filter.hide, mutedWord)
+		}
+	}
+	return filter, nil
+}
+
+// apply returns whether chirp should be hidden from the viewer and whether
+// it should be flagged as filtered. Viewers' own chirps are never filtered.
+func (f chirpFilter) apply(chirp database.Chirp) (hide bool, filtered bool) {
+	if f.viewer.Valid && chirp.UserID == f.viewer.UUID {
+		return false, false
+	}
+	for _, mutedWord := range f.hide {
+		if mutedWord.Matches(chirp.Body) {
+			return true, true
+		}
+	}
+	for _, mutedWord := range f.warn {
+		if mutedWord.Matches(chirp.Body) {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func mutedWordFromDB(mutedWord database.MutedWord) MutedWord {
+	response := MutedWord{
+		ID:        mutedWord.ID,
+		CreatedAt: mutedWord.CreatedAt,
+		Phrase:    mutedWord.Phrase,
+		WholeWord: mutedWord.WholeWord,
+		Action:    mutedWord.Action,
+	}
+	if mutedWord.ExpiresAt.Valid {
+		response.ExpiresAt = &mutedWord.ExpiresAt.Time
+	}
+	return response
+}
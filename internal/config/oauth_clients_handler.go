@@ -0,0 +1,172 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	Secret       string    `json:"client_secret,omitempty"`
+}
+
+func (apiCfg *ApiConfig) CreateOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	if params.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Client name is required", nil)
+		return
+	}
+	if len(params.RedirectURIs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one redirect URI is required", nil)
+		return
+	}
+	for _, redirectURI := range params.RedirectURIs {
+		err = validateRedirectURI(redirectURI)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	secret := ""
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create client secret", err)
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := apiCfg.DbQueries.CreateOauthClient(r.Context(), database.CreateOauthClientParams{
+		UserID:       user_id,
+		Name:         params.Name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create OAuth client", err)
+		return
+	}
+
+	response := oauthClientFromDB(client)
+	response.Secret = secret
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (apiCfg *ApiConfig) ListOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbClients, err := apiCfg.DbQueries.GetOauthClientsByUserID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve OAuth clients", err)
+		return
+	}
+
+	clients := []OAuthClient{}
+	for _, dbClient := range dbClients {
+		clients = append(clients, oauthClientFromDB(dbClient))
+	}
+	respondWithJSON(w, http.StatusOK, clients)
+}
+
+func (apiCfg *ApiConfig) DeleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse clientID", err)
+		return
+	}
+
+	deleted, err := apiCfg.DbQueries.DeleteOauthClient(r.Context(), database.DeleteOauthClientParams{
+		ID:     clientID,
+		UserID: user_id,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete OAuth client", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find OAuth client", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func validateRedirectURI(redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("redirect URI %q must be an absolute URL", redirectURI)
+	}
+	if parsed.Fragment != "" {
+		return fmt.Errorf("redirect URI %q must not contain a fragment", redirectURI)
+	}
+	return nil
+}
+
+func oauthClientFromDB(client database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           client.ID,
+		CreatedAt:    client.CreatedAt,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Confidential: client.SecretHash.Valid,
+	}
+}
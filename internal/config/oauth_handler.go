@@ -0,0 +1,512 @@
+package config
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	oauthAccessTokenDuration  = time.Hour
+	oauthRefreshTokenDuration = 60 * 24 * time.Hour
+	oauthCodeDuration         = 5 * time.Minute
+)
+
+var scopeDescriptions = map[string]string{
+	auth.ScopeChirpsRead:  "Read chirps on your behalf",
+	auth.ScopeChirpsWrite: "Post and delete chirps on your behalf",
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<html>
+  <body>
+    <h1>Authorize {{.ClientName}}</h1>
+    <p>{{.ClientName}} would like to:</p>
+    <ul>
+      {{range .Scopes}}<li>{{.}}</li>{{end}}
+    </ul>
+    {{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+    <form method="POST" action="/oauth/authorize">
+      <input type="hidden" name="response_type" value="code">
+      <input type="hidden" name="client_id" value="{{.ClientID}}">
+      <input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+      <input type="hidden" name="scope" value="{{.Scope}}">
+      <input type="hidden" name="state" value="{{.State}}">
+      <input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+      <input type="hidden" name="code_challenge_method" value="S256">
+      <p><label>Email <input type="email" name="email"></label></p>
+      <p><label>Password <input type="password" name="password"></label></p>
+      <p><label>Authentication code (if enabled) <input type="text" name="totp_code" autocomplete="one-time-code"></label></p>
+      <button type="submit" name="decision" value="approve">Allow</button>
+      <button type="submit" name="decision" value="deny">Deny</button>
+    </form>
+  </body>
+</html>`))
+
+// authorizationRequest is a validated OAuth authorization request. Once
+// the client and redirect URI are known to be valid, further errors are
+// reported to the client through the redirect URI.
+type authorizationRequest struct {
+	Client        database.OauthClient
+	RedirectURI   string
+	Scopes        []string
+	State         string
+	CodeChallenge string
+}
+
+// AuthorizeHandler shows the consent screen for an authorization code
+// request.
+func (apiCfg *ApiConfig) AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	request, ok := apiCfg.parseAuthorizationRequest(w, r)
+	if !ok {
+		return
+	}
+	renderConsent(w, http.StatusOK, request, "")
+}
+
+// AuthorizeDecisionHandler handles the consent form. The user signs in on
+// the form itself, so third-party clients never see their password.
+func (apiCfg *ApiConfig) AuthorizeDecisionHandler(w http.ResponseWriter, r *http.Request) {
+	request, ok := apiCfg.parseAuthorizationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if r.PostFormValue("decision") != "approve" {
+		redirectWithOAuthError(w, r, request, "access_denied", "The user denied the request")
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByEmail(r.Context(), r.PostFormValue("email"))
+	if err == nil {
+		err = auth.CheckPasswordHash(user.HashedPassword, r.PostFormValue("password"))
+	}
+	if err != nil {
+		log.Println(err)
+		renderConsent(w, http.StatusUnauthorized, request, "Incorrect email or password")
+		return
+	}
+	if user.TotpEnabled {
+		ok, err := apiCfg.checkTOTPCode(r.Context(), user, r.PostFormValue("totp_code"))
+		if errors.Is(err, errMFALocked) {
+			renderConsent(w, http.StatusTooManyRequests, request, "Too many wrong codes, try again later")
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			redirectWithOAuthError(w, r, request, "server_error", "Couldn't check authentication code")
+			return
+		}
+		if !ok {
+			renderConsent(w, http.StatusUnauthorized, request, "Invalid authentication code")
+			return
+		}
+	}
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		redirectWithOAuthError(w, r, request, "server_error", "Couldn't create authorization code")
+		return
+	}
+	err = apiCfg.DbQueries.CreateOauthAuthorizationCode(r.Context(), database.CreateOauthAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      request.Client.ID,
+		UserID:        user.ID,
+		RedirectUri:   request.RedirectURI,
+		Scopes:        request.Scopes,
+		CodeChallenge: request.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeDuration),
+	})
+	if err != nil {
+		log.Println(err)
+		redirectWithOAuthError(w, r, request, "server_error", "Couldn't save authorization code")
+		return
+	}
+
+	redirectToClient(w, r, request, url.Values{"code": {code}})
+}
+
+// TokenHandler implements the token endpoint for the authorization_code and
+// refresh_token grants.
+func (apiCfg *ApiConfig) TokenHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := apiCfg.authenticateOAuthClient(w, r, false)
+	if !ok {
+		return
+	}
+
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		code, err := apiCfg.DbQueries.ConsumeOauthAuthorizationCode(r.Context(), auth.HashToken(r.PostFormValue("code")))
+		if err != nil {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Unknown or already used authorization code", err)
+			return
+		}
+		if code.ClientID != client.ID || time.Now().After(code.ExpiresAt) {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Authorization code is expired or was issued to another client", nil)
+			return
+		}
+		if code.RedirectUri != r.PostFormValue("redirect_uri") {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri doesn't match the authorization request", nil)
+			return
+		}
+		if !auth.VerifyPKCE(r.PostFormValue("code_verifier"), code.CodeChallenge) {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid code_verifier", nil)
+			return
+		}
+		apiCfg.respondWithOAuthTokens(w, r, code.UserID, client.ID, code.Scopes)
+	case "refresh_token":
+		refreshToken, err := apiCfg.DbQueries.GetRefreshTokenByToken(r.Context(), r.PostFormValue("refresh_token"))
+		if err != nil {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Unknown refresh token", err)
+			return
+		}
+		if !refreshToken.ClientID.Valid || refreshToken.ClientID.UUID != client.ID ||
+			refreshToken.RevokedAt.Valid || time.Now().After(refreshToken.ExpiresAt) {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Refresh token is expired, revoked or was issued to another client", nil)
+			return
+		}
+
+		scopes := refreshToken.Scopes
+		if requested := auth.ParseScope(r.PostFormValue("scope")); len(requested) > 0 {
+			for _, scope := range requested {
+				if !auth.HasScope(refreshToken.Scopes, scope) {
+					respondWithOAuthError(w, http.StatusBadRequest, "invalid_scope", "Requested scope exceeds the original grant", nil)
+					return
+				}
+			}
+			scopes = requested
+		}
+
+		// Refresh tokens are rotated: the old one stops working once a new
+		// pair has been handed out. Revoking it is also what checks it's
+		// still usable, so a token sent twice at once is only exchanged once.
+		rotated, err := apiCfg.DbQueries.RotateRefreshToken(r.Context(), refreshToken.Token)
+		if err != nil {
+			respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't rotate refresh token", err)
+			return
+		}
+		if rotated == 0 {
+			respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "Refresh token is expired, revoked or was issued to another client", nil)
+			return
+		}
+		apiCfg.respondWithOAuthTokens(w, r, refreshToken.UserID, client.ID, scopes)
+	default:
+		respondWithOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token", nil)
+	}
+}
+
+// IntrospectHandler implements RFC 7662 token introspection for
+// confidential clients. Clients can only introspect their own tokens.
+func (apiCfg *ApiConfig) IntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Subject   string `json:"sub,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		IssuedAt  int64  `json:"iat,omitempty"`
+		ExpiresAt int64  `json:"exp,omitempty"`
+	}
+
+	client, ok := apiCfg.authenticateOAuthClient(w, r, true)
+	if !ok {
+		return
+	}
+	token := r.PostFormValue("token")
+	w.Header().Set("Cache-Control", "no-store")
+
+	accessToken, err := auth.ValidateOAuthAccessToken(token, apiCfg.Secret)
+	if err == nil && accessToken.ClientID == client.ID {
+		revoked, err := apiCfg.DbQueries.IsOauthAccessTokenRevoked(r.Context(), accessToken.ID)
+		if err != nil {
+			respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't check token", err)
+			return
+		}
+		if !revoked {
+			respondWithJSON(w, http.StatusOK, response{
+				Active:    true,
+				Scope:     strings.Join(accessToken.Scopes, " "),
+				ClientID:  client.ID.String(),
+				Subject:   accessToken.UserID.String(),
+				TokenType: "access_token",
+				IssuedAt:  accessToken.IssuedAt.Unix(),
+				ExpiresAt: accessToken.ExpiresAt.Unix(),
+			})
+			return
+		}
+	}
+
+	refreshToken, err := apiCfg.DbQueries.GetRefreshTokenByToken(r.Context(), token)
+	if err == nil && refreshToken.ClientID.Valid && refreshToken.ClientID.UUID == client.ID &&
+		!refreshToken.RevokedAt.Valid && time.Now().Before(refreshToken.ExpiresAt) {
+		respondWithJSON(w, http.StatusOK, response{
+			Active:    true,
+			Scope:     strings.Join(refreshToken.Scopes, " "),
+			ClientID:  client.ID.String(),
+			Subject:   refreshToken.UserID.String(),
+			TokenType: "refresh_token",
+			IssuedAt:  refreshToken.CreatedAt.Unix(),
+			ExpiresAt: refreshToken.ExpiresAt.Unix(),
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{Active: false})
+}
+
+// RevokeOAuthTokenHandler implements RFC 7009 token revocation. Unknown
+// tokens are not an error, so the response is always 200.
+func (apiCfg *ApiConfig) RevokeOAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := apiCfg.authenticateOAuthClient(w, r, false)
+	if !ok {
+		return
+	}
+	token := r.PostFormValue("token")
+
+	err := apiCfg.DbQueries.DeleteExpiredOauthRevocations(r.Context())
+	if err != nil {
+		log.Printf("failed to delete expired revocations: %s", err)
+	}
+
+	accessToken, err := auth.ValidateOAuthAccessToken(token, apiCfg.Secret)
+	if err == nil {
+		if accessToken.ClientID == client.ID {
+			err = apiCfg.DbQueries.RevokeOauthAccessToken(r.Context(), database.RevokeOauthAccessTokenParams{
+				ID:        accessToken.ID,
+				ExpiresAt: accessToken.ExpiresAt,
+			})
+			if err != nil {
+				respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't revoke token", err)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	err = apiCfg.DbQueries.RevokeOauthRefreshToken(r.Context(), database.RevokeOauthRefreshTokenParams{
+		Token:    token,
+		ClientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't revoke token", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (apiCfg *ApiConfig) parseAuthorizationRequest(w http.ResponseWriter, r *http.Request) (authorizationRequest, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthPage(w, http.StatusBadRequest, "Malformed authorization request")
+		return authorizationRequest{}, false
+	}
+
+	clientID, err := uuid.Parse(r.Form.Get("client_id"))
+	if err != nil {
+		respondWithOAuthPage(w, http.StatusBadRequest, "Unknown client")
+		return authorizationRequest{}, false
+	}
+	client, err := apiCfg.DbQueries.GetOauthClientByID(r.Context(), clientID)
+	if err != nil {
+		respondWithOAuthPage(w, http.StatusBadRequest, "Unknown client")
+		return authorizationRequest{}, false
+	}
+	redirectURI := r.Form.Get("redirect_uri")
+	if !slices.Contains(client.RedirectUris, redirectURI) {
+		respondWithOAuthPage(w, http.StatusBadRequest, "The redirect URI isn't registered for this client")
+		return authorizationRequest{}, false
+	}
+
+	request := authorizationRequest{
+		Client:        client,
+		RedirectURI:   redirectURI,
+		Scopes:        auth.ParseScope(r.Form.Get("scope")),
+		State:         r.Form.Get("state"),
+		CodeChallenge: r.Form.Get("code_challenge"),
+	}
+
+	if r.Form.Get("response_type") != "code" {
+		redirectWithOAuthError(w, r, request, "unsupported_response_type", "Only the code response type is supported")
+		return authorizationRequest{}, false
+	}
+	if request.CodeChallenge == "" || r.Form.Get("code_challenge_method") != "S256" {
+		redirectWithOAuthError(w, r, request, "invalid_request", "PKCE with code_challenge_method S256 is required")
+		return authorizationRequest{}, false
+	}
+	err = auth.ValidateScopes(request.Scopes)
+	if err != nil {
+		redirectWithOAuthError(w, r, request, "invalid_scope", err.Error())
+		return authorizationRequest{}, false
+	}
+	return request, true
+}
+
+// authenticateOAuthClient identifies the calling client from HTTP basic
+// credentials or form parameters. Public clients, which have no secret,
+// are only accepted when requireSecret is false.
+func (apiCfg *ApiConfig) authenticateOAuthClient(w http.ResponseWriter, r *http.Request, requireSecret bool) (database.OauthClient, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", "Malformed request body", err)
+		return database.OauthClient{}, false
+	}
+
+	rawClientID, secret, ok := r.BasicAuth()
+	if ok {
+		rawClientID, _ = url.QueryUnescape(rawClientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		rawClientID = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	clientID, err := uuid.Parse(rawClientID)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Unknown client", err)
+		return database.OauthClient{}, false
+	}
+	client, err := apiCfg.DbQueries.GetOauthClientByID(r.Context(), clientID)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Unknown client", err)
+		return database.OauthClient{}, false
+	}
+
+	if !client.SecretHash.Valid {
+		if requireSecret {
+			respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Public clients can't use this endpoint", nil)
+			return database.OauthClient{}, false
+		}
+		return client, true
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "Invalid client credentials", nil)
+		return database.OauthClient{}, false
+	}
+	return client, true
+}
+
+func (apiCfg *ApiConfig) respondWithOAuthTokens(w http.ResponseWriter, r *http.Request, userID, clientID uuid.UUID, scopes []string) {
+	type response struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}
+
+	accessToken, _, err := auth.MakeOAuthAccessToken(userID, clientID, scopes, apiCfg.Secret, oauthAccessTokenDuration)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't create access token", err)
+		return
+	}
+
+	refresh_token, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't create refresh token", err)
+		return
+	}
+	refreshToken, err := apiCfg.DbQueries.CreateOauthRefreshToken(r.Context(), database.CreateOauthRefreshTokenParams{
+		Token:     refresh_token,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(oauthRefreshTokenDuration),
+		ClientID:  uuid.NullUUID{UUID: clientID, Valid: true},
+		Scopes:    scopes,
+	})
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "Couldn't save refresh token", err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, response{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(oauthAccessTokenDuration.Seconds()),
+		RefreshToken: refreshToken.Token,
+		Scope:        strings.Join(scopes, " "),
+	})
+}
+
+// respondWithOAuthError writes an error in the RFC 6749 format, which uses
+// a machine readable code in "error" rather than a message.
+func respondWithOAuthError(w http.ResponseWriter, code int, errorCode, description string, err error) {
+	if err != nil {
+		log.Println(err)
+	}
+	type errorResponse struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, errorResponse{
+		Error:            errorCode,
+		ErrorDescription: description,
+	})
+}
+
+// respondWithOAuthPage reports authorization errors that can't safely be
+// sent back to the client's redirect URI.
+func respondWithOAuthPage(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, "<html>\n  <body>\n    <h1>Authorization failed</h1>\n    <p>%s</p>\n  </body>\n</html>", template.HTMLEscapeString(msg))
+}
+
+func renderConsent(w http.ResponseWriter, code int, request authorizationRequest, errorMsg string) {
+	scopes := make([]string, 0, len(request.Scopes))
+	for _, scope := range request.Scopes {
+		scopes = append(scopes, scopeDescriptions[scope])
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.WriteHeader(code)
+	err := consentTemplate.Execute(w, map[string]any{
+		"ClientName":    request.Client.Name,
+		"ClientID":      request.Client.ID,
+		"RedirectURI":   request.RedirectURI,
+		"Scope":         strings.Join(request.Scopes, " "),
+		"Scopes":        scopes,
+		"State":         request.State,
+		"CodeChallenge": request.CodeChallenge,
+		"Error":         errorMsg,
+	})
+	if err != nil {
+		log.Printf("failed to render consent page: %s", err)
+	}
+}
+
+func redirectWithOAuthError(w http.ResponseWriter, r *http.Request, request authorizationRequest, errorCode, description string) {
+	redirectToClient(w, r, request, url.Values{
+		"error":             {errorCode},
+		"error_description": {description},
+	})
+}
+
+func redirectToClient(w http.ResponseWriter, r *http.Request, request authorizationRequest, values url.Values) {
+	target, err := url.Parse(request.RedirectURI)
+	if err != nil {
+		respondWithOAuthPage(w, http.StatusBadRequest, "The redirect URI is invalid")
+		return
+	}
+	query := target.Query()
+	for key, value := range values {
+		query[key] = value
+	}
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+)
+
+func TestRotateRefreshTokenOnce(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	user, _ := createTestUser(t, apiCfg, "alice@example.com")
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      []int64
+	}{
+		{name: "Rotated once", expiresAt: time.Now().Add(time.Hour), want: []int64{1, 0}},
+		{name: "Expired", expiresAt: time.Now().Add(-time.Hour), want: []int64{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshToken, err := apiCfg.DbQueries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+				Token:     "token " + tt.name,
+				UserID:    user.ID,
+				ExpiresAt: tt.expiresAt,
+			})
+			if err != nil {
+				t.Fatalf("CreateRefreshToken() error = %v", err)
+			}
+			for i, want := range tt.want {
+				rotated, err := apiCfg.DbQueries.RotateRefreshToken(ctx, refreshToken.Token)
+				if err != nil || rotated != want {
+					t.Errorf("RotateRefreshToken() #%d = %d (error %v), want %d", i+1, rotated, err, want)
+				}
+			}
+		})
+	}
+}
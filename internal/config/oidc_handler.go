@@ -0,0 +1,194 @@
+package config
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+)
+
+const (
+	// oidcLoginStateDuration is how long a user has to complete sign-in at
+	// the identity provider.
+	oidcLoginStateDuration = 10 * time.Minute
+	// oidcStateCookie ties a sign-in to the browser that started it, so a
+	// callback URL from someone else's sign-in can't log the browser in to
+	// their account.
+	oidcStateCookie = "chirpy_oidc_state"
+)
+
+// OIDCLoginHandler starts a sign-in with the configured identity provider by
+// redirecting the browser to its authorization endpoint.
+func (apiCfg *ApiConfig) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if apiCfg.OIDC == nil {
+		respondWithError(w, http.StatusNotFound, "Single sign-on isn't configured", nil)
+		return
+	}
+
+	state, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create login state", err)
+		return
+	}
+	nonce, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create login state", err)
+		return
+	}
+	codeVerifier, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create login state", err)
+		return
+	}
+
+	err = apiCfg.DbQueries.DeleteExpiredOidcLoginStates(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't clean up login states", err)
+		return
+	}
+	err = apiCfg.DbQueries.CreateOidcLoginState(r.Context(), database.CreateOidcLoginStateParams{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcLoginStateDuration),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save login state", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/oidc",
+		MaxAge:   int(oidcLoginStateDuration.Seconds()),
+		Secure:   strings.HasPrefix(apiCfg.BaseURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, apiCfg.OIDC.AuthCodeURL(state, nonce, auth.PKCEChallenge(codeVerifier)), http.StatusFound)
+}
+
+// OIDCCallbackHandler completes a sign-in with the identity provider. The
+// user is found by their provider identity, linked to an existing account
+// with the same verified email, or created on first login. Users with
+// two-factor authentication still have to provide their second factor.
+func (apiCfg *ApiConfig) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if apiCfg.OIDC == nil {
+		respondWithError(w, http.StatusNotFound, "Single sign-on isn't configured", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	if providerError := query.Get("error"); providerError != "" {
+		respondWithError(w, http.StatusUnauthorized, "Sign-in was rejected by the identity provider", errors.New(providerError))
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err == nil && subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		err = errors.New("state doesn't match the browser's sign-in")
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired login state", err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/api/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	loginState, err := apiCfg.DbQueries.ConsumeOidcLoginState(r.Context(), state)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired login state", err)
+		return
+	}
+
+	rawIDToken, err := apiCfg.OIDC.Exchange(r.Context(), query.Get("code"), loginState.CodeVerifier)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Couldn't exchange authorization code", err)
+		return
+	}
+	claims, err := apiCfg.OIDC.VerifyIDToken(r.Context(), rawIDToken, loginState.Nonce)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't verify ID token", err)
+		return
+	}
+
+	identity, err := apiCfg.DbQueries.GetUserIdentity(r.Context(), database.GetUserIdentityParams{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+	})
+	if err == nil {
+		user, err := apiCfg.DbQueries.GetUserByID(r.Context(), identity.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+			return
+		}
+		apiCfg.respondWithLogin(w, r, user)
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve identity", err)
+		return
+	}
+
+	// Linking by email is only safe when the provider vouches for it.
+	if claims.Email == "" || !claims.EmailVerified {
+		respondWithError(w, http.StatusForbidden, "Identity provider didn't return a verified email", nil)
+		return
+	}
+	user, err := apiCfg.DbQueries.GetUserByEmail(r.Context(), claims.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user, err = apiCfg.createOIDCUser(r, claims.Email)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+		return
+	}
+
+	_, err = apiCfg.DbQueries.CreateUserIdentity(r.Context(), database.CreateUserIdentityParams{
+		UserID:  user.ID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't link identity", err)
+		return
+	}
+
+	apiCfg.respondWithLogin(w, r, user)
+}
+
+// createOIDCUser creates an account for a user signing in through the
+// identity provider for the first time. The account gets a random password
+// nobody knows, so it can only be used through single sign-on until the
+// user sets one.
+func (apiCfg *ApiConfig) createOIDCUser(r *http.Request, email string) (database.User, error) {
+	password, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.User{}, err
+	}
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	created, err := apiCfg.DbQueries.CreateUser(r.Context(), database.CreateUserParams{
+		Email:          email,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	return apiCfg.DbQueries.GetUserByID(r.Context(), created.ID)
+}
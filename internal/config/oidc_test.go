@@ -0,0 +1,35 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OferRavid/chirpy/internal/oidc"
+)
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	apiCfg := &ApiConfig{OIDC: &oidc.Provider{}}
+
+	tests := []struct {
+		name   string
+		cookie string
+	}{
+		{name: "No cookie"},
+		{name: "Another sign-in's state", cookie: "other-state"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/oidc/callback?state=attacker-state&code=code", nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			apiCfg.OIDCCallbackHandler(w, r)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("OIDCCallbackHandler() = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
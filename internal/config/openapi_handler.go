@@ -0,0 +1,19 @@
+package config
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes the API. It's generated from the routes and
+// handlers by internal/openapi, whose tests fail when it falls out of date.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPIHandler serves an OpenAPI 3.1 document describing every route.
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
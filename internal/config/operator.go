@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// moderationActionDeleteChirp is recorded when an operator deletes a chirp
+// from the command line.
+const moderationActionDeleteChirp = "delete_chirp"
+
+var (
+	// ErrAdminAccount is returned when an operator tries to restrict an admin
+	// account.
+	ErrAdminAccount = errors.New("admin accounts can't be restricted")
+	// ErrUnknownPlan is returned when granting a plan that doesn't exist.
+	ErrUnknownPlan = errors.New("unknown plan")
+)
+
+// The methods in this file are what the admin command line does beyond
+// reading the database. They go through the same code paths as the admin
+// endpoints, and record who did what in the moderation audit trail with no
+// moderator.
+
+// SuspendAccount suspends a user, like SuspendUserHandler.
+func (apiCfg *ApiConfig) SuspendAccount(ctx context.Context, userID uuid.UUID, reason string) error {
+	return apiCfg.operatorAccountState(ctx, userID, moderationActionSuspendUser, reason)
+}
+
+func (apiCfg *ApiConfig) UnsuspendAccount(ctx context.Context, userID uuid.UUID, reason string) error {
+	return apiCfg.operatorAccountState(ctx, userID, moderationActionUnsuspendUser, reason)
+}
+
+func (apiCfg *ApiConfig) operatorAccountState(ctx context.Context, userID uuid.UUID, action, reason string) error {
+	user, err := apiCfg.DbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Role == roleAdmin {
+		return ErrAdminAccount
+	}
+	return apiCfg.applyAccountState(ctx, uuid.NullUUID{}, user.ID, action, reason)
+}
+
+// DeleteAccount deletes a user now, skipping the grace period a user gets
+// when they delete their own account. The deletion is backdated so that
+// clock skew between us and the database doesn't leave it to the next run
+// of the deletion job.
+func (apiCfg *ApiConfig) DeleteAccount(ctx context.Context, userID uuid.UUID) error {
+	err := apiCfg.DbQueries.ScheduleUserDeletion(ctx, database.ScheduleUserDeletionParams{
+		ID:                  userID,
+		DeletionScheduledAt: sql.NullTime{Time: time.Now().UTC().Add(-time.Minute), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	deleted, err := apiCfg.deleteAccount(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RemoveChirp deletes a chirp and records it in the moderation audit trail
+// in one transaction, then tells the author's followers on other servers.
+func (apiCfg *ApiConfig) RemoveChirp(ctx context.Context, chirpID uuid.UUID, reason string) error {
+	chirp, err := apiCfg.DbQueries.GetChirpByID(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+	err = apiCfg.inTx(ctx, func(q *database.Queries) error {
+		err := q.DeleteChirp(ctx, chirp.ID)
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+			Action:       moderationActionDeleteChirp,
+			TargetUserID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+			ChirpID:      uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			Note:         reason,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't record moderation action: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	apiCfg.publishChirp(ctx, "Delete", chirp)
+	return nil
+}
+
+// GrantMembership puts a user on plan until endsAt, or indefinitely when
+// endsAt is nil. The payment provider doesn't bill for grants, so
+// reconciliation and its webhooks leave them alone.
+func (apiCfg *ApiConfig) GrantMembership(ctx context.Context, userID uuid.UUID, plan string, endsAt *time.Time) error {
+	entitlements, err := apiCfg.DbQueries.ListPlanEntitlements(ctx)
+	if err != nil {
+		return err
+	}
+	known := slices.ContainsFunc(entitlements, func(entitlement database.PlanEntitlement) bool {
+		return entitlement.Plan == plan
+	})
+	if !known {
+		return fmt.Errorf("%w: %s", ErrUnknownPlan, plan)
+	}
+	return apiCfg.startOrUpdateMembership(ctx, userID, plan, endsAt, membershipSourceAdmin)
+}
+
+// RevokeMembership ends a user's active membership now. It reports whether
+// there was one.
+func (apiCfg *ApiConfig) RevokeMembership(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return apiCfg.endMembership(ctx, userID, membershipStatusRevoked)
+}
+
+// RevokeTokens revokes a user's refresh tokens and personal access tokens
+// and returns how many personal access tokens were revoked. Access tokens
+// already handed out keep working until they expire.
+func (apiCfg *ApiConfig) RevokeTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	err := apiCfg.DbQueries.RevokeRefreshTokensForUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return apiCfg.DbQueries.RevokePersonalAccessTokensForUser(ctx, userID)
+}
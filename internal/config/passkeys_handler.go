@@ -0,0 +1,374 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	ceremonyRegistration   = "registration"
+	ceremonyAuthentication = "authentication"
+
+	passkeyCeremonyTimeout = 5 * time.Minute
+)
+
+type Passkey struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func (apiCfg *ApiConfig) BeginPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		ChallengeID uuid.UUID                `json:"challenge_id"`
+		PublicKey   webauthn.CreationOptions `json:"public_key"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	credentials, err := apiCfg.DbQueries.GetWebauthnCredentialsByUserID(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve passkeys", err)
+		return
+	}
+
+	challenge, err := apiCfg.createPasskeyChallenge(r, ceremonyRegistration, uuid.NullUUID{UUID: user.ID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create passkey challenge", err)
+		return
+	}
+
+	exclude := make([][]byte, 0, len(credentials))
+	for _, credential := range credentials {
+		exclude = append(exclude, credential.CredentialID)
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		ChallengeID: challenge.ID,
+		PublicKey: apiCfg.WebAuthn.CreationOptions(
+			challenge.Challenge,
+			user.ID[:],
+			user.Email,
+			passkeyCeremonyTimeout.Milliseconds(),
+			exclude,
+		),
+	})
+}
+
+func (apiCfg *ApiConfig) FinishPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeID uuid.UUID                     `json:"challenge_id"`
+		Name        string                        `json:"name"`
+		Credential  webauthn.RegistrationResponse `json:"credential"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	challenge, err := apiCfg.consumePasskeyChallenge(r, params.ChallengeID, ceremonyRegistration)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired passkey challenge", err)
+		return
+	}
+	if !challenge.UserID.Valid || challenge.UserID.UUID != user_id {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired passkey challenge",
+			fmt.Errorf("challenge %v wasn't issued to user %v", challenge.ID, user_id))
+		return
+	}
+
+	credential, err := apiCfg.WebAuthn.VerifyRegistration(challenge.Challenge, params.Credential)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't verify passkey registration", err)
+		return
+	}
+
+	name := params.Name
+	if name == "" {
+		name = "Passkey"
+	}
+	passkey, err := apiCfg.DbQueries.CreateWebauthnCredential(r.Context(), database.CreateWebauthnCredentialParams{
+		UserID:       user_id,
+		Name:         name,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save passkey", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, passkeyFromDB(passkey))
+}
+
+func (apiCfg *ApiConfig) BeginPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+	type response struct {
+		ChallengeID uuid.UUID               `json:"challenge_id"`
+		PublicKey   webauthn.RequestOptions `json:"public_key"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	// Without an email the browser offers any discoverable credential. An
+	// unknown email falls back to the same behaviour so the response doesn't
+	// reveal which accounts exist.
+	userID := uuid.NullUUID{}
+	allow := [][]byte{}
+	if params.Email != "" {
+		user, err := apiCfg.DbQueries.GetUserByEmail(r.Context(), params.Email)
+		if err == nil {
+			credentials, err := apiCfg.DbQueries.GetWebauthnCredentialsByUserID(r.Context(), user.ID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve passkeys", err)
+				return
+			}
+			for _, credential := range credentials {
+				allow = append(allow, credential.CredentialID)
+			}
+			userID = uuid.NullUUID{UUID: user.ID, Valid: true}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve user", err)
+			return
+		}
+	}
+
+	challenge, err := apiCfg.createPasskeyChallenge(r, ceremonyAuthentication, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create passkey challenge", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		ChallengeID: challenge.ID,
+		PublicKey:   apiCfg.WebAuthn.RequestOptions(challenge.Challenge, passkeyCeremonyTimeout.Milliseconds(), allow),
+	})
+}
+
+// FinishPasskeyLoginHandler verifies a passkey assertion and issues the same
+// access and refresh token pair as LoginHandler.
+func (apiCfg *ApiConfig) FinishPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeID uuid.UUID                  `json:"challenge_id"`
+		Credential  webauthn.AssertionResponse `json:"credential"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	challenge, err := apiCfg.consumePasskeyChallenge(r, params.ChallengeID, ceremonyAuthentication)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired passkey challenge", err)
+		return
+	}
+
+	passkey, err := apiCfg.DbQueries.GetWebauthnCredentialByCredentialID(r.Context(), params.Credential.RawID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unknown passkey", err)
+		return
+	}
+	if challenge.UserID.Valid && challenge.UserID.UUID != passkey.UserID {
+		respondWithError(w, http.StatusUnauthorized, "Unknown passkey",
+			fmt.Errorf("passkey %v doesn't belong to user %v", passkey.ID, challenge.UserID.UUID))
+		return
+	}
+	userHandle := params.Credential.Response.UserHandle
+	if len(userHandle) > 0 && string(userHandle) != string(passkey.UserID[:]) {
+		respondWithError(w, http.StatusUnauthorized, "Unknown passkey",
+			fmt.Errorf("user handle doesn't match owner of passkey %v", passkey.ID))
+		return
+	}
+
+	signCount, err := apiCfg.WebAuthn.VerifyAssertion(challenge.Challenge, webauthn.Credential{
+		ID:        passkey.CredentialID,
+		PublicKey: passkey.PublicKey,
+		SignCount: uint32(passkey.SignCount),
+	}, params.Credential)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't verify passkey", err)
+		return
+	}
+
+	// The sign count only moves forward, unless the authenticator doesn't
+	// keep one at all. Checking it in the update means two logins racing
+	// with the same assertion can't both get through.
+	updated, err := apiCfg.DbQueries.UpdateWebauthnCredentialSignCount(r.Context(), database.UpdateWebauthnCredentialSignCountParams{
+		ID:        passkey.ID,
+		SignCount: int64(signCount),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update passkey", err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't verify passkey",
+			fmt.Errorf("sign count of passkey %v didn't increase past %d", passkey.ID, signCount))
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), passkey.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find user", err)
+		return
+	}
+
+	apiCfg.respondWithSession(w, r, user)
+}
+
+func (apiCfg *ApiConfig) ListPasskeysHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	credentials, err := apiCfg.DbQueries.GetWebauthnCredentialsByUserID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve passkeys", err)
+		return
+	}
+
+	passkeys := []Passkey{}
+	for _, credential := range credentials {
+		passkeys = append(passkeys, passkeyFromDB(credential))
+	}
+	respondWithJSON(w, http.StatusOK, passkeys)
+}
+
+func (apiCfg *ApiConfig) DeletePasskeyHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	passkeyID, err := uuid.Parse(r.PathValue("passkeyID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse passkeyID", err)
+		return
+	}
+
+	deleted, err := apiCfg.DbQueries.DeleteWebauthnCredential(r.Context(), database.DeleteWebauthnCredentialParams{
+		ID:     passkeyID,
+		UserID: user_id,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete passkey", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find passkey", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (apiCfg *ApiConfig) createPasskeyChallenge(r *http.Request, ceremony string, userID uuid.NullUUID) (database.WebauthnChallenge, error) {
+	err := apiCfg.DbQueries.DeleteExpiredWebauthnChallenges(r.Context())
+	if err != nil {
+		return database.WebauthnChallenge{}, err
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return database.WebauthnChallenge{}, err
+	}
+	return apiCfg.DbQueries.CreateWebauthnChallenge(r.Context(), database.CreateWebauthnChallengeParams{
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Challenge: challenge,
+		ExpiresAt: time.Now().Add(passkeyCeremonyTimeout),
+	})
+}
+
+// consumePasskeyChallenge deletes the challenge as it reads it so that each
+// one can only be answered once.
+func (apiCfg *ApiConfig) consumePasskeyChallenge(r *http.Request, id uuid.UUID, ceremony string) (database.WebauthnChallenge, error) {
+	challenge, err := apiCfg.DbQueries.ConsumeWebauthnChallenge(r.Context(), database.ConsumeWebauthnChallengeParams{
+		ID:       id,
+		Ceremony: ceremony,
+	})
+	if err != nil {
+		return database.WebauthnChallenge{}, err
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return database.WebauthnChallenge{}, fmt.Errorf("challenge %v expired at %v", challenge.ID, challenge.ExpiresAt)
+	}
+	return challenge, nil
+}
+
+func passkeyFromDB(credential database.WebauthnCredential) Passkey {
+	passkey := Passkey{
+		ID:        credential.ID,
+		CreatedAt: credential.CreatedAt,
+		Name:      credential.Name,
+	}
+	if credential.LastUsedAt.Valid {
+		passkey.LastUsedAt = &credential.LastUsedAt.Time
+	}
+	return passkey
+}
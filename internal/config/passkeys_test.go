@@ -0,0 +1,48 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/OferRavid/chirpy/internal/database"
+)
+
+func TestUpdateSignCountOnlyMovesForward(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	user, _ := createTestUser(t, apiCfg, "alice@example.com")
+
+	tests := []struct {
+		name      string
+		signCount int64
+		updates   []int64
+		want      []int64
+	}{
+		{name: "Counter increases", signCount: 1, updates: []int64{2, 2, 1, 3}, want: []int64{1, 0, 0, 1}},
+		{name: "Authenticator without a counter", signCount: 0, updates: []int64{0, 0}, want: []int64{1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			passkey, err := apiCfg.DbQueries.CreateWebauthnCredential(ctx, database.CreateWebauthnCredentialParams{
+				UserID:       user.ID,
+				Name:         tt.name,
+				CredentialID: []byte(tt.name),
+				PublicKey:    []byte("key"),
+				SignCount:    tt.signCount,
+			})
+			if err != nil {
+				t.Fatalf("CreateWebauthnCredential() error = %v", err)
+			}
+			for i, signCount := range tt.updates {
+				updated, err := apiCfg.DbQueries.UpdateWebauthnCredentialSignCount(ctx, database.UpdateWebauthnCredentialSignCountParams{
+					ID:        passkey.ID,
+					SignCount: signCount,
+				})
+				if err != nil || updated != tt.want[i] {
+					t.Errorf("UpdateWebauthnCredentialSignCount(%d) = %d (error %v), want %d", signCount, updated, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/payments"
+	"github.com/google/uuid"
+)
+
+var errPaymentUserNotFound = errors.New("payment event refers to an unknown user")
+
+// UpdateMembershipStatusHandler receives webhook deliveries from the payment
+// provider. Every verified delivery is recorded by the provider's event ID,
+// so retries of an event that was already handled, or is being handled, are
+// acknowledged without being applied twice.
+func (apiCfg *ApiConfig) UpdateMembershipStatusHandler(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadBytes))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read payload", err)
+		return
+	}
+
+	err = apiCfg.Payments.VerifyWebhook(r.Header, payload)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid webhook signature", err)
+		return
+	}
+
+	paymentEvent, err := apiCfg.Payments.ParseEvent(payload)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	event, err := apiCfg.DbQueries.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+		Provider:  apiCfg.Payments.Name(),
+		EventID:   paymentEvent.ID,
+		EventType: paymentEvent.RawType,
+		Payload:   payload,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record webhook event", err)
+		return
+	}
+	// Only one delivery of an event gets to claim it. The others find it
+	// handled or being handled.
+	event, err = apiCfg.DbQueries.ClaimWebhookEvent(r.Context(), event.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJSON(w, http.StatusNoContent, nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim webhook event", err)
+		return
+	}
+
+	err = apiCfg.processWebhookEvent(r.Context(), event)
+	if err != nil {
+		if errors.Is(err, errPaymentUserNotFound) {
+			respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't process webhook event", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// applyPaymentEvent updates the user's membership for a billing event and
+// reports whether anything changed.
+func (apiCfg *ApiConfig) applyPaymentEvent(ctx context.Context, event payments.Event) (bool, error) {
+	switch event.Type {
+	case payments.EventUpgraded, payments.EventRenewed:
+		return true, apiCfg.startOrUpdateMembership(ctx, event.UserID, event.Plan, event.PeriodEnd, membershipSourceProvider)
+	case payments.EventCancelled, payments.EventDowngraded:
+		// The provider can't stop a membership it doesn't bill for.
+		current, err := apiCfg.currentMembership(ctx, event.UserID)
+		if err != nil || current == nil || current.Source != membershipSourceProvider {
+			return false, err
+		}
+		if event.Type == payments.EventCancelled {
+			return true, apiCfg.DbQueries.CancelMembership(ctx, current.ID)
+		}
+		return true, apiCfg.DbQueries.EndMembership(ctx, database.EndMembershipParams{
+			ID:     current.ID,
+			Status: membershipStatusDowngraded,
+		})
+	}
+	return false, nil
+}
+
+func (apiCfg *ApiConfig) startOrUpdateMembership(ctx context.Context, userID uuid.UUID, plan string, periodEnd *time.Time, source string) error {
+	_, err := apiCfg.DbQueries.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %v", errPaymentUserNotFound, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	endsAt := sql.NullTime{}
+	if periodEnd != nil {
+		endsAt = sql.NullTime{Time: *periodEnd, Valid: true}
+	}
+	return apiCfg.upgradeMembership(ctx, userID, plan, endsAt, source)
+}
+
+// RunMembershipReconciliation corrects drift between the payment provider's
+// subscriptions and our memberships every interval until ctx is cancelled,
+// e.g. when webhook deliveries were lost.
+func (apiCfg *ApiConfig) RunMembershipReconciliation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := apiCfg.reconcileMemberships(ctx)
+		if err != nil {
+			log.Printf("failed to reconcile memberships: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (apiCfg *ApiConfig) reconcileMemberships(ctx context.Context) error {
+	remote, err := apiCfg.Payments.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	dbMemberships, err := apiCfg.DbQueries.ListActiveMemberships(ctx)
+	if err != nil {
+		return err
+	}
+
+	local := []payments.Membership{}
+	for _, dbMembership := range dbMemberships {
+		membership := payments.Membership{
+			UserID:    dbMembership.UserID,
+			Plan:      dbMembership.Plan,
+			Cancelled: dbMembership.CancelledAt.Valid,
+			Granted:   dbMembership.Source == membershipSourceAdmin,
+		}
+		if dbMembership.EndsAt.Valid {
+			membership.EndsAt = &dbMembership.EndsAt.Time
+		}
+		local = append(local, membership)
+	}
+
+	for _, correction := range payments.Diff(local, remote) {
+		switch correction.Type {
+		case payments.CorrectionStart, payments.CorrectionUpdate:
+			err = apiCfg.startOrUpdateMembership(ctx, correction.UserID, correction.Plan, correction.PeriodEnd, membershipSourceProvider)
+		case payments.CorrectionCancel:
+			_, err = apiCfg.cancelMembership(ctx, correction.UserID)
+		case payments.CorrectionEnd:
+			_, err = apiCfg.endMembership(ctx, correction.UserID, membershipStatusExpired)
+		}
+		if err != nil {
+			log.Printf("failed to %s membership of user %v: %s", correction.Type, correction.UserID, err)
+			continue
+		}
+		log.Printf("reconciled membership of user %v with %s: %s", correction.UserID, apiCfg.Payments.Name(), correction.Type)
+	}
+	return nil
+}
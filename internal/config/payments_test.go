@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/payments"
+)
+
+// stubProvider is a payment provider that bills the given subscriptions.
+type stubProvider struct {
+	payments.PaymentProvider
+	subscriptions []payments.Subscription
+}
+
+func (p stubProvider) Name() string {
+	return "stub"
+}
+
+func (p stubProvider) ListSubscriptions(ctx context.Context) ([]payments.Subscription, error) {
+	return p.subscriptions, nil
+}
+
+func TestReconcileKeepsGrants(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	apiCfg.Payments = stubProvider{}
+	ctx := context.Background()
+
+	granted, _ := createTestUser(t, apiCfg, "granted@example.com")
+	err := apiCfg.GrantMembership(ctx, granted.ID, payments.DefaultPlan, nil)
+	if err != nil {
+		t.Fatalf("GrantMembership() error = %v", err)
+	}
+	// The provider no longer bills this one, e.g. after a lost webhook.
+	lapsed, _ := createTestUser(t, apiCfg, "lapsed@example.com")
+	err = apiCfg.startOrUpdateMembership(ctx, lapsed.ID, payments.DefaultPlan, nil, membershipSourceProvider)
+	if err != nil {
+		t.Fatalf("startOrUpdateMembership() error = %v", err)
+	}
+
+	err = apiCfg.reconcileMemberships(ctx)
+	if err != nil {
+		t.Fatalf("reconcileMemberships() error = %v", err)
+	}
+
+	active, err := apiCfg.DbQueries.HasActiveMembership(ctx, granted.ID)
+	if err != nil || !active {
+		t.Errorf("granted membership active = %v (error %v), want it kept", active, err)
+	}
+	active, err = apiCfg.DbQueries.HasActiveMembership(ctx, lapsed.ID)
+	if err != nil || active {
+		t.Errorf("lapsed membership active = %v (error %v), want it ended", active, err)
+	}
+}
+
+func TestWebhookEventClaimedOnce(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+
+	event, err := apiCfg.DbQueries.RecordWebhookEvent(ctx, database.RecordWebhookEventParams{
+		Provider:  "stub",
+		EventID:   "evt_1",
+		EventType: "subscription.renewed",
+		Payload:   []byte(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("RecordWebhookEvent() error = %v", err)
+	}
+	_, err = apiCfg.DbQueries.ClaimWebhookEvent(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("ClaimWebhookEvent() error = %v", err)
+	}
+	_, err = apiCfg.DbQueries.ClaimWebhookEvent(ctx, event.ID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second ClaimWebhookEvent() error = %v, want sql.ErrNoRows", err)
+	}
+
+	err = apiCfg.DbQueries.FinishWebhookEvent(ctx, database.FinishWebhookEventParams{
+		ID:     event.ID,
+		Status: webhookStatusFailed,
+	})
+	if err != nil {
+		t.Fatalf("FinishWebhookEvent() error = %v", err)
+	}
+	_, err = apiCfg.DbQueries.ClaimWebhookEvent(ctx, event.ID)
+	if err != nil {
+		t.Errorf("ClaimWebhookEvent() of a failed event error = %v, want it claimed again", err)
+	}
+}
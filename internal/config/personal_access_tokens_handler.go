@@ -0,0 +1,167 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func (apiCfg *ApiConfig) CreatePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name             string   `json:"name"`
+		Scopes           []string `json:"scopes"`
+		ExpiresInSeconds int64    `json:"expires_in_seconds"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	if params.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Token name is required", nil)
+		return
+	}
+	err = auth.ValidateScopes(params.Scopes)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if params.ExpiresInSeconds < 0 {
+		respondWithError(w, http.StatusBadRequest, "expires_in_seconds can't be negative", nil)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresInSeconds > 0 {
+		expiresAt = sql.NullTime{
+			Time:  time.Now().Add(time.Duration(params.ExpiresInSeconds) * time.Second),
+			Valid: true,
+		}
+	}
+
+	plaintext, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create personal access token", err)
+		return
+	}
+	pat, err := apiCfg.DbQueries.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    user_id,
+		Name:      params.Name,
+		TokenHash: auth.HashToken(plaintext),
+		Scopes:    params.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save personal access token", err)
+		return
+	}
+
+	// The plaintext token is only ever shown once, in this response.
+	response := personalAccessTokenFromDB(pat)
+	response.Token = plaintext
+	respondWithJSON(w, http.StatusCreated, response)
+}
+
+func (apiCfg *ApiConfig) ListPersonalAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbTokens, err := apiCfg.DbQueries.GetPersonalAccessTokensByUserID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve personal access tokens", err)
+		return
+	}
+
+	tokens := []PersonalAccessToken{}
+	for _, dbToken := range dbTokens {
+		tokens = append(tokens, personalAccessTokenFromDB(dbToken))
+	}
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+func (apiCfg *ApiConfig) RevokePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse tokenID", err)
+		return
+	}
+
+	revoked, err := apiCfg.DbQueries.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: user_id,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke personal access token", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't find personal access token", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func personalAccessTokenFromDB(pat database.PersonalAccessToken) PersonalAccessToken {
+	token := PersonalAccessToken{
+		ID:        pat.ID,
+		CreatedAt: pat.CreatedAt,
+		Name:      pat.Name,
+		Scopes:    pat.Scopes,
+	}
+	if pat.ExpiresAt.Valid {
+		token.ExpiresAt = &pat.ExpiresAt.Time
+	}
+	if pat.LastUsedAt.Valid {
+		token.LastUsedAt = &pat.LastUsedAt.Time
+	}
+	return token
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/OferRavid/chirpy/internal/database"
+)
+
+// Plan lists what a membership plan entitles its users to. Users without an
+// active membership get the "free" plan.
+type Plan struct {
+	Plan              string `json:"plan"`
+	MaxChirpLength    int32  `json:"max_chirp_length"`
+	EditWindowSeconds int32  `json:"edit_window_seconds"`
+	ChirpsPerMinute   int32  `json:"chirps_per_minute"`
+	CanSchedule       bool   `json:"can_schedule"`
+}
+
+func (apiCfg *ApiConfig) ListPlansHandler(w http.ResponseWriter, r *http.Request) {
+	dbPlans, err := apiCfg.DbQueries.ListPlanEntitlements(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve plans", err)
+		return
+	}
+
+	plans := []Plan{}
+	for _, dbPlan := range dbPlans {
+		plans = append(plans, planFromDB(dbPlan))
+	}
+	respondWithJSON(w, http.StatusOK, plans)
+}
+
+// UpdatePlanHandler creates or replaces the entitlements of a plan.
+func (apiCfg *ApiConfig) UpdatePlanHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := Plan{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	if params.MaxChirpLength < 1 || params.EditWindowSeconds < 0 || params.ChirpsPerMinute < 1 {
+		respondWithError(w, http.StatusBadRequest, "Limits must not be negative, and chirp length and rate must be at least 1", nil)
+		return
+	}
+
+	plan, err := apiCfg.DbQueries.UpsertPlanEntitlements(r.Context(), database.UpsertPlanEntitlementsParams{
+		Plan:              r.PathValue("plan"),
+		MaxChirpLength:    params.MaxChirpLength,
+		EditWindowSeconds: params.EditWindowSeconds,
+		ChirpsPerMinute:   params.ChirpsPerMinute,
+		CanSchedule:       params.CanSchedule,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update plan", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, planFromDB(plan))
+}
+
+func planFromDB(plan database.PlanEntitlement) Plan {
+	return Plan{
+		Plan:              plan.Plan,
+		MaxChirpLength:    plan.MaxChirpLength,
+		EditWindowSeconds: plan.EditWindowSeconds,
+		ChirpsPerMinute:   plan.ChirpsPerMinute,
+		CanSchedule:       plan.CanSchedule,
+	}
+}
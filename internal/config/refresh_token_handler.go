@@ -23,6 +23,10 @@ func (apiCfg *ApiConfig) RefreshTokenHandler(w http.ResponseWriter, r *http.Requ
 		respondWithError(w, http.StatusUnauthorized, "Refresh token already expired", err)
 		return
 	}
+	if refreshToken.ClientID.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Third-party refresh tokens must use the OAuth token endpoint", nil)
+		return
+	}
 
 	token, err := auth.MakeJWT(refreshToken.UserID, apiCfg.Secret, time.Hour)
 	if err != nil {
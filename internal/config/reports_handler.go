@@ -0,0 +1,166 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// reportReasons are the categories a chirp can be reported under.
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "misinformation", "other"}
+
+const maxReportDetailsLength = 500
+
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+}
+
+// CreateReportHandler reports a chirp to the moderators. Reports are grouped
+// into a single case per chirp, and once a case has collected
+// ReportAutoHideThreshold reports the chirp is hidden until a moderator
+// looks at it.
+func (apiCfg *ApiConfig) CreateReportHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+	if !slices.Contains(reportReasons, params.Reason) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("reason must be one of %v", reportReasons), nil)
+		return
+	}
+	if len(params.Details) > maxReportDetailsLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("details must be at most %d bytes", maxReportDetailsLength), nil)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse chirpID", err)
+		return
+	}
+	chirp, err := apiCfg.DbQueries.GetChirpByID(r.Context(), chirpID)
+	if err != nil || chirp.HiddenAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Couldn't find chirp with the given ID", err)
+		return
+	}
+	if chirp.UserID == user_id {
+		respondWithError(w, http.StatusBadRequest, "You can't report your own chirp", nil)
+		return
+	}
+
+	moderationCase, err := apiCfg.DbQueries.OpenModerationCase(r.Context(), database.OpenModerationCaseParams{
+		ChirpID:   uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		AuthorID:  uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		ChirpBody: chirp.Body,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't open moderation case", err)
+		return
+	}
+	report, err := apiCfg.DbQueries.CreateReport(r.Context(), database.CreateReportParams{
+		CaseID:     moderationCase.ID,
+		ChirpID:    uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		ReporterID: user_id,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "You already reported this chirp", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create report", err)
+		return
+	}
+
+	err = apiCfg.autoHideReportedChirp(r, moderationCase, chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't hide reported chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, reportFromDB(report))
+}
+
+// autoHideReportedChirp hides a chirp once its case has enough reports and
+// records that in the audit trail, with no moderator attached, in one
+// transaction.
+func (apiCfg *ApiConfig) autoHideReportedChirp(r *http.Request, moderationCase database.ModerationCase, chirp database.Chirp) error {
+	if apiCfg.ReportAutoHideThreshold <= 0 {
+		return nil
+	}
+	count, err := apiCfg.DbQueries.CountReportsForCase(r.Context(), moderationCase.ID)
+	if err != nil {
+		return err
+	}
+	if count < int64(apiCfg.ReportAutoHideThreshold) {
+		return nil
+	}
+
+	var hidden int64
+	err = apiCfg.inTx(r.Context(), func(q *database.Queries) error {
+		hidden, err = q.HideChirp(r.Context(), chirp.ID)
+		if err != nil || hidden == 0 {
+			return err
+		}
+		_, err = q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			CaseID:       uuid.NullUUID{UUID: moderationCase.ID, Valid: true},
+			Action:       moderationActionAutoHide,
+			TargetUserID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+			ChirpID:      uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			Note:         fmt.Sprintf("Hidden after %d reports", count),
+		})
+		return err
+	})
+	if err != nil || hidden == 0 {
+		return err
+	}
+	apiCfg.publishChirp(r.Context(), "Delete", chirp)
+	return nil
+}
+
+func reportFromDB(report database.Report) Report {
+	result := Report{
+		ID:         report.ID,
+		CreatedAt:  report.CreatedAt,
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+	}
+	if report.ChirpID.Valid {
+		result.ChirpID = &report.ChirpID.UUID
+	}
+	return result
+}
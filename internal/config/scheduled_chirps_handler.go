@@ -0,0 +1,243 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxScheduleAhead is how far in the future a chirp can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+// ListScheduledChirpsHandler returns the user's scheduled chirps, the next
+// one to go live first.
+func (apiCfg *ApiConfig) ListScheduledChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsRead)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbChirps, err := apiCfg.DbQueries.GetScheduledChirpsByUserID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve scheduled chirps", err)
+		return
+	}
+
+	chirps := []Chirp{}
+	for _, dbChirp := range dbChirps {
+		chirps = append(chirps, chirpFromDB(dbChirp))
+	}
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
+// UpdateScheduledChirpHandler changes the body or the publishing time of a
+// chirp that isn't live yet. Fields left out keep their value.
+func (apiCfg *ApiConfig) UpdateScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+
+	type parameters struct {
+		Body      *string    `json:"body"`
+		PublishAt *time.Time `json:"publish_at"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	chirp, err := apiCfg.ownScheduledChirp(w, r, user_id)
+	if err != nil {
+		return
+	}
+
+	entitlements, err := apiCfg.DbQueries.GetUserEntitlements(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve plan", err)
+		return
+	}
+	if params.PublishAt != nil {
+		err = checkPublishAt(w, entitlements, *params.PublishAt)
+		if err != nil {
+			return
+		}
+		chirp.PublishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+	}
+	if params.Body != nil {
+		chirp.Body, err = apiCfg.validateChirp(*params.Body, int(entitlements.MaxChirpLength))
+		if err != nil {
+			respondWithChirpError(w, err)
+			return
+		}
+	}
+
+	chirp, err = apiCfg.DbQueries.UpdateScheduledChirp(r.Context(), database.UpdateScheduledChirpParams{
+		ID:        chirp.ID,
+		Body:      chirp.Body,
+		PublishAt: chirp.PublishAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Chirp was already published", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update scheduled chirp", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirpFromDB(chirp))
+}
+
+// CancelScheduledChirpHandler deletes a chirp that isn't live yet.
+func (apiCfg *ApiConfig) CancelScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	chirp, err := apiCfg.ownScheduledChirp(w, r, user_id)
+	if err != nil {
+		return
+	}
+
+	deleted, err := apiCfg.DbQueries.DeleteScheduledChirp(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't cancel scheduled chirp", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusConflict, "Chirp was already published", nil)
+		return
+	}
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// ownScheduledChirp looks up the scheduled chirp in the path, responding
+// with an error unless it belongs to userID. Other users' scheduled chirps
+// aren't found, since nobody else knows about them yet.
+func (apiCfg *ApiConfig) ownScheduledChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.Chirp, error) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse chirpID", err)
+		return database.Chirp{}, err
+	}
+	chirp, err := apiCfg.DbQueries.GetScheduledChirpByID(r.Context(), chirpID)
+	if err == nil && chirp.UserID != userID {
+		err = fmt.Errorf("scheduled chirp %v belongs to user %v", chirp.ID, chirp.UserID)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find scheduled chirp", err)
+		return database.Chirp{}, err
+	}
+	return chirp, nil
+}
+
+// checkPublishAt checks that the user's plan allows scheduling and that
+// publishAt is in the scheduling window, responding with an error when it
+// isn't.
+func checkPublishAt(w http.ResponseWriter, entitlements database.PlanEntitlement, publishAt time.Time) error {
+	if !entitlements.CanSchedule {
+		err := fmt.Errorf("plan %s doesn't include scheduling", entitlements.Plan)
+		respondWithError(w, http.StatusForbidden, "Your plan doesn't include scheduling chirps", err)
+		return err
+	}
+	now := time.Now()
+	if !publishAt.After(now) {
+		err := fmt.Errorf("publish_at %v is in the past", publishAt)
+		respondWithError(w, http.StatusBadRequest, "publish_at must be in the future", err)
+		return err
+	}
+	if publishAt.After(now.Add(maxScheduleAhead)) {
+		err := fmt.Errorf("publish_at %v is too far ahead", publishAt)
+		respondWithError(w, http.StatusBadRequest, "publish_at must be within a year", err)
+		return err
+	}
+	return nil
+}
+
+// RunChirpPublisher makes scheduled chirps live once they're due, every
+// interval until ctx is cancelled. Each chirp is claimed with SKIP LOCKED
+// and published in a single statement, so several servers can run the
+// publisher without publishing a chirp twice.
+func (apiCfg *ApiConfig) RunChirpPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := apiCfg.publishDueChirps(ctx)
+		if err != nil {
+			log.Printf("failed to publish scheduled chirps: %s", err)
+		} else if published > 0 {
+			log.Printf("published %d scheduled chirps", published)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (apiCfg *ApiConfig) publishDueChirps(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		chirp, err := apiCfg.DbQueries.PublishDueChirp(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return published, nil
+		}
+		if err != nil {
+			return published, err
+		}
+		apiCfg.publishChirp(ctx, "Create", chirp)
+		published++
+	}
+}
+
+// chirpFromDB converts a chirp as its author sees it, with the publishing
+// time of a scheduled chirp.
+func chirpFromDB(chirp database.Chirp) Chirp {
+	response := Chirp{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+	}
+	if chirp.PublishAt.Valid {
+		response.PublishAt = &chirp.PublishAt.Time
+	}
+	return response
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func newScheduledChirpsMux(apiCfg *ApiConfig) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chirps", apiCfg.CreateChirpsHandler)
+	mux.HandleFunc("GET /api/chirps", apiCfg.RetrieveChirpsHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.GetChirpsHandler)
+	mux.HandleFunc("PUT /api/scheduled-chirps/{chirpID}", apiCfg.UpdateScheduledChirpHandler)
+	mux.HandleFunc("DELETE /api/scheduled-chirps/{chirpID}", apiCfg.CancelScheduledChirpHandler)
+	return mux
+}
+
+func TestScheduledChirpsStayHiddenUntilPublished(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	user, token := createTestUser(t, apiCfg, "alice@example.com")
+	err := apiCfg.GrantMembership(ctx, user.ID, "chirpy_red", nil)
+	if err != nil {
+		t.Fatalf("GrantMembership() error = %v", err)
+	}
+
+	mux := newScheduledChirpsMux(apiCfg)
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+	listed := func(id uuid.UUID) bool {
+		w := send(http.MethodGet, "/api/chirps", "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/chirps = %d %s", w.Code, w.Body)
+		}
+		chirps := []Chirp{}
+		json.Unmarshal(w.Body.Bytes(), &chirps)
+		for _, chirp := range chirps {
+			if chirp.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	publishAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	w := send(http.MethodPost, "/api/chirps", token, `{"body":"later","publish_at":"`+publishAt+`"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /api/chirps = %d %s", w.Code, w.Body)
+	}
+	scheduled := Chirp{}
+	json.Unmarshal(w.Body.Bytes(), &scheduled)
+
+	if listed(scheduled.ID) {
+		t.Errorf("GET /api/chirps lists the scheduled chirp")
+	}
+	if w := send(http.MethodGet, "/api/chirps/"+scheduled.ID.String(), "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET /api/chirps/{chirpID} of a scheduled chirp = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	_, err = apiCfg.DB.Exec("UPDATE chirps SET publish_at = NOW() WHERE id = $1", scheduled.ID)
+	if err != nil {
+		t.Fatalf("failed to make the chirp due: %v", err)
+	}
+	published, err := apiCfg.publishDueChirps(ctx)
+	if published != 1 || err != nil {
+		t.Fatalf("publishDueChirps() = %d (error %v), want 1", published, err)
+	}
+	if !listed(scheduled.ID) {
+		t.Errorf("GET /api/chirps doesn't list the published chirp")
+	}
+	if w := send(http.MethodGet, "/api/chirps/"+scheduled.ID.String(), "", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /api/chirps/{chirpID} of a published chirp = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestPublishDueChirpsPublishesEachChirpOnce(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	ctx := context.Background()
+	user, _ := createTestUser(t, apiCfg, "alice@example.com")
+
+	actor, err := apiCfg.DbQueries.UpsertRemoteActor(ctx, database.UpsertRemoteActorParams{
+		Uri:               "https://remote.example/users/bob",
+		PreferredUsername: "bob",
+		Inbox:             "https://remote.example/users/bob/inbox",
+		SharedInbox:       "https://remote.example/inbox",
+		KeyID:             "https://remote.example/users/bob#main-key",
+	})
+	if err != nil {
+		t.Fatalf("UpsertRemoteActor() error = %v", err)
+	}
+	err = apiCfg.DbQueries.CreateRemoteFollower(ctx, database.CreateRemoteFollowerParams{
+		UserID:    user.ID,
+		ActorID:   actor.ID,
+		FollowUri: "https://remote.example/follows/1",
+	})
+	if err != nil {
+		t.Fatalf("CreateRemoteFollower() error = %v", err)
+	}
+
+	const due = 10
+	for i := 0; i < due; i++ {
+		_, err := apiCfg.DbQueries.ScheduleChirp(ctx, database.ScheduleChirpParams{
+			Body:      "due",
+			UserID:    user.ID,
+			PublishAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+		})
+		if err != nil {
+			t.Fatalf("ScheduleChirp() error = %v", err)
+		}
+	}
+	_, err = apiCfg.DbQueries.ScheduleChirp(ctx, database.ScheduleChirpParams{
+		Body:      "not due",
+		UserID:    user.ID,
+		PublishAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("ScheduleChirp() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	published := make([]int, 2)
+	errs := make([]error, 2)
+	for i := range published {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			published[i], errs[i] = apiCfg.publishDueChirps(ctx)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("publishDueChirps() #%d error = %v", i, err)
+		}
+	}
+	if total := published[0] + published[1]; total != due {
+		t.Errorf("publishDueChirps() published %d + %d chirps, want %d in total", published[0], published[1], due)
+	}
+
+	var scheduled int
+	err = apiCfg.DB.QueryRow("SELECT COUNT(*) FROM chirps WHERE publish_at IS NOT NULL").Scan(&scheduled)
+	if scheduled != 1 || err != nil {
+		t.Errorf("scheduled chirps left = %d (error %v), want 1", scheduled, err)
+	}
+	var deliveries int
+	err = apiCfg.DB.QueryRow(
+		"SELECT COUNT(*) FROM federation_deliveries WHERE user_id = $1 AND activity->>'type' = 'Create'",
+		user.ID,
+	).Scan(&deliveries)
+	if deliveries != due || err != nil {
+		t.Errorf("queued Create deliveries = %d (error %v), want %d", deliveries, err, due)
+	}
+}
+
+func TestScheduledChirpChangesRacingThePublisher(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+	}{
+		{name: "Edit", method: http.MethodPut, body: `{"body":"edited"}`},
+		{name: "Cancel", method: http.MethodDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiCfg := newTestConfig(t)
+			ctx := context.Background()
+			user, token := createTestUser(t, apiCfg, "alice@example.com")
+			chirp, err := apiCfg.DbQueries.ScheduleChirp(ctx, database.ScheduleChirpParams{
+				Body:      "due",
+				UserID:    user.ID,
+				PublishAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+			})
+			if err != nil {
+				t.Fatalf("ScheduleChirp() error = %v", err)
+			}
+
+			// Claim the chirp the way the publisher does and hold the row
+			// lock, so the request reads the chirp as scheduled and then
+			// waits on the lock to change it.
+			tx, err := apiCfg.DB.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatalf("BeginTx() error = %v", err)
+			}
+			defer tx.Rollback()
+			_, err = apiCfg.DbQueries.WithTx(tx).PublishDueChirp(ctx)
+			if err != nil {
+				t.Fatalf("PublishDueChirp() error = %v", err)
+			}
+
+			mux := newScheduledChirpsMux(apiCfg)
+			done := make(chan *httptest.ResponseRecorder)
+			go func() {
+				r := httptest.NewRequest(tt.method, "/api/scheduled-chirps/"+chirp.ID.String(), strings.NewReader(tt.body))
+				r.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, r)
+				done <- w
+			}()
+			waitForLockWait(t, apiCfg.DB)
+			err = tx.Commit()
+			if err != nil {
+				t.Fatalf("Commit() error = %v", err)
+			}
+
+			if w := <-done; w.Code != http.StatusConflict {
+				t.Errorf("%s /api/scheduled-chirps/{chirpID} = %d %s, want %d", tt.method, w.Code, w.Body, http.StatusConflict)
+			}
+			published, err := apiCfg.DbQueries.GetChirpByID(ctx, chirp.ID)
+			if err != nil || published.Body != "due" {
+				t.Errorf("GetChirpByID() = %q (error %v), want the chirp published unchanged", published.Body, err)
+			}
+		})
+	}
+}
+
+// waitForLockWait blocks until a statement is waiting on a lock in the test
+// database.
+func waitForLockWait(t *testing.T, db *sql.DB) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var waiting bool
+		err := db.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM pg_stat_activity WHERE datname = current_database() AND wait_event_type = 'Lock')",
+		).Scan(&waiting)
+		if err != nil {
+			t.Fatalf("failed to check for lock waits: %v", err)
+		}
+		if waiting {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no statement waited on the claimed chirp")
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	totpIssuer = "Chirpy"
+
+	// maxMFAAttempts wrong second factors in a row lock a user's second
+	// factor for mfaLockout, so codes can't be guessed.
+	maxMFAAttempts = 5
+	mfaLockout     = 15 * time.Minute
+)
+
+var (
+	errMFALocked          = errors.New("too many wrong second factors")
+	errTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+)
+
+func (apiCfg *ApiConfig) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if user.TotpEnabled {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't generate TOTP secret", err)
+		return
+	}
+
+	err = apiCfg.DbQueries.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save TOTP secret", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+func (apiCfg *ApiConfig) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if user.TotpEnabled {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusBadRequest, "Two-factor enrollment hasn't been started", nil)
+		return
+	}
+	counter, ok := auth.ValidateTOTPCode(user.TotpSecret.String, params.Code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid authentication code", nil)
+		return
+	}
+
+	// The recovery codes and the secret are only saved together. Enabling
+	// records the code's time step, so it can't be used again to log in.
+	var recoveryCodes []string
+	err = apiCfg.inTx(r.Context(), func(q *database.Queries) error {
+		recoveryCodes, err = replaceRecoveryCodes(r.Context(), q, user.ID)
+		if err != nil {
+			return err
+		}
+		enabled, err := q.EnableTOTP(r.Context(), database.EnableTOTPParams{
+			ID:              user.ID,
+			TotpLastCounter: counter,
+		})
+		if err == nil && enabled == 0 {
+			err = errTOTPAlreadyEnabled
+		}
+		return err
+	})
+	if errors.Is(err, errTOTPAlreadyEnabled) {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't enable two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func (apiCfg *ApiConfig) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := auth.ValidateJWT(token, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if !user.TotpEnabled {
+		respondWithError(w, http.StatusBadRequest, "Two-factor authentication isn't enabled", nil)
+		return
+	}
+	ok, err := apiCfg.checkTOTPCode(r.Context(), user, params.Code)
+	if err != nil {
+		respondWithMFAError(w, err)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid authentication code", nil)
+		return
+	}
+
+	// Like enabling, disabling changes the secret and the recovery codes
+	// together.
+	err = apiCfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.DisableTOTP(r.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(r.Context(), user.ID)
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't disable two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// LoginMFAHandler completes a login started by LoginHandler for users with
+// two-factor authentication, exchanging the MFA token and either a TOTP code
+// or an unused recovery code for an access and refresh token pair.
+func (apiCfg *ApiConfig) LoginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user_id, err := auth.ValidateMFAToken(params.MFAToken, apiCfg.Secret)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+
+	user, err := apiCfg.DbQueries.GetUserByID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+	if !user.TotpEnabled {
+		respondWithError(w, http.StatusBadRequest, "Two-factor authentication isn't enabled", nil)
+		return
+	}
+
+	switch {
+	case params.Code != "":
+		ok, err := apiCfg.checkTOTPCode(r.Context(), user, params.Code)
+		if err != nil {
+			respondWithMFAError(w, err)
+			return
+		}
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, "Invalid authentication code", nil)
+			return
+		}
+	case params.RecoveryCode != "":
+		ok, err := apiCfg.checkRecoveryCode(r.Context(), user, params.RecoveryCode)
+		if err != nil {
+			respondWithMFAError(w, err)
+			return
+		}
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, "Invalid recovery code", nil)
+			return
+		}
+	default:
+		respondWithError(w, http.StatusBadRequest, "Missing code or recovery_code", errors.New("no second factor provided"))
+		return
+	}
+
+	apiCfg.respondWithSession(w, r, user)
+}
+
+// checkTOTPCode reports whether code is a valid TOTP code for user. A time
+// step is accepted only once, and never after a later one, so codes can't
+// be replayed. Wrong codes count towards locking the second factor, which
+// is reported as errMFALocked.
+func (apiCfg *ApiConfig) checkTOTPCode(ctx context.Context, user database.User, code string) (bool, error) {
+	if mfaLocked(user) {
+		return false, errMFALocked
+	}
+	counter, ok := auth.ValidateTOTPCode(user.TotpSecret.String, code, time.Now())
+	if ok {
+		accepted, err := apiCfg.DbQueries.AcceptTOTPCounter(ctx, database.AcceptTOTPCounterParams{
+			ID:              user.ID,
+			TotpLastCounter: counter,
+		})
+		if err != nil || accepted == 1 {
+			return accepted == 1, err
+		}
+	}
+	return false, apiCfg.recordMFAFailure(ctx, user.ID)
+}
+
+// checkRecoveryCode uses up one of user's recovery codes, reporting whether
+// code was one of them. Wrong codes count towards the lock like TOTP codes.
+func (apiCfg *ApiConfig) checkRecoveryCode(ctx context.Context, user database.User, code string) (bool, error) {
+	if mfaLocked(user) {
+		return false, errMFALocked
+	}
+	used, err := apiCfg.DbQueries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return false, err
+	}
+	if used == 0 {
+		return false, apiCfg.recordMFAFailure(ctx, user.ID)
+	}
+	return true, apiCfg.DbQueries.ResetMFAFailures(ctx, user.ID)
+}
+
+func (apiCfg *ApiConfig) recordMFAFailure(ctx context.Context, userID uuid.UUID) error {
+	return apiCfg.DbQueries.RecordMFAFailure(ctx, database.RecordMFAFailureParams{
+		ID:          userID,
+		MaxAttempts: maxMFAAttempts,
+		LockedUntil: time.Now().UTC().Add(mfaLockout),
+	})
+}
+
+func mfaLocked(user database.User) bool {
+	return user.MfaLockedUntil.Valid && time.Now().UTC().Before(user.MfaLockedUntil.Time)
+}
+
+func respondWithMFAError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMFALocked) {
+		respondWithError(w, http.StatusTooManyRequests, "Too many wrong codes, try again later", err)
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "Couldn't check authentication code", err)
+}
+
+// replaceRecoveryCodes invalidates any existing recovery codes for the user
+// and stores hashes of a fresh set, returning the plaintext codes.
+func replaceRecoveryCodes(ctx context.Context, q *database.Queries, userID uuid.UUID) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		err = q.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+)
+
+func TestLoginMFA(t *testing.T) {
+	apiCfg := newTestConfig(t)
+	user, token := createTestUser(t, apiCfg, "alice@example.com")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/totp/enroll", apiCfg.EnrollTOTPHandler)
+	mux.HandleFunc("POST /api/totp/confirm", apiCfg.ConfirmTOTPHandler)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.LoginMFAHandler)
+	send := func(path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send("/api/totp/enroll", token, `{}`)
+	enrollment := struct {
+		Secret string `json:"secret"`
+	}{}
+	json.Unmarshal(w.Body.Bytes(), &enrollment)
+	code, err := auth.GenerateTOTPCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateTOTPCode() error = %v", err)
+	}
+	w = send("/api/totp/confirm", token, `{"code":"`+code+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("confirm = %d %s", w.Code, w.Body)
+	}
+	confirmation := struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{}
+	json.Unmarshal(w.Body.Bytes(), &confirmation)
+
+	mfaToken, err := auth.MakeMFAToken(user.ID, apiCfg.Secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeMFAToken() error = %v", err)
+	}
+	login := func(field, value string) int {
+		return send("/api/login/mfa", "", `{"mfa_token":"`+mfaToken+`","`+field+`":"`+value+`"}`).Code
+	}
+
+	if got := login("code", code); got != http.StatusUnauthorized {
+		t.Errorf("login with the confirmation code = %d, want %d", got, http.StatusUnauthorized)
+	}
+	for range maxMFAAttempts - 1 {
+		login("recovery_code", "00000-00000")
+	}
+	if got := login("recovery_code", confirmation.RecoveryCodes[0]); got != http.StatusTooManyRequests {
+		t.Errorf("login after %d wrong codes = %d, want %d", maxMFAAttempts, got, http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Warning is a moderator's warning about one of the user's chirps. ChirpID
+// is null once the chirp is deleted; ChirpBody keeps what it said.
+type Warning struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	ChirpBody string     `json:"chirp_body"`
+	Note      string     `json:"note"`
+}
+
+// ListWarningsHandler lists the warnings moderators gave the caller, newest
+// first.
+func (apiCfg *ApiConfig) ListWarningsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Missing or malformed token", err)
+		return
+	}
+	user_id, err := apiCfg.validateBearerToken(r.Context(), token, auth.ScopeChirpsRead)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
+	}
+
+	dbWarnings, err := apiCfg.DbQueries.GetWarningsByUserID(r.Context(), user_id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve warnings", err)
+		return
+	}
+
+	warnings := []Warning{}
+	for _, dbWarning := range dbWarnings {
+		warnings = append(warnings, warningFromDB(dbWarning))
+	}
+	respondWithJSON(w, http.StatusOK, warnings)
+}
+
+func warningFromDB(dbWarning database.Warning) Warning {
+	warning := Warning{
+		ID:        dbWarning.ID,
+		CreatedAt: dbWarning.CreatedAt,
+		ChirpBody: dbWarning.ChirpBody,
+		Note:      dbWarning.Note,
+	}
+	if dbWarning.ChirpID.Valid {
+		warning.ChirpID = &dbWarning.ChirpID.UUID
+	}
+	return warning
+}
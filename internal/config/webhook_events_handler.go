@@ -0,0 +1,230 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookStatusProcessed = "processed"
+	webhookStatusIgnored   = "ignored"
+	webhookStatusFailed    = "failed"
+
+	maxWebhookPayloadBytes = 64 << 10
+
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+type WebhookEvent struct {
+	ID          uuid.UUID       `json:"id"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	Provider    string          `json:"provider"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int32           `json:"attempts"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
+
+// processWebhookEvent applies a recorded payment event and stores the
+// outcome on the event.
+func (apiCfg *ApiConfig) processWebhookEvent(ctx context.Context, event database.WebhookEvent) error {
+	status, err := apiCfg.applyWebhookEvent(ctx, event)
+	errorMessage := sql.NullString{}
+	if err != nil {
+		status = webhookStatusFailed
+		errorMessage = sql.NullString{String: err.Error(), Valid: true}
+	}
+
+	finishErr := apiCfg.DbQueries.FinishWebhookEvent(ctx, database.FinishWebhookEventParams{
+		ID:     event.ID,
+		Status: status,
+		Error:  errorMessage,
+	})
+	if finishErr != nil {
+		return errors.Join(err, finishErr)
+	}
+	return err
+}
+
+func (apiCfg *ApiConfig) applyWebhookEvent(ctx context.Context, event database.WebhookEvent) (string, error) {
+	if event.Provider != apiCfg.Payments.Name() {
+		return "", fmt.Errorf("event is from %s, not the configured provider %s", event.Provider, apiCfg.Payments.Name())
+	}
+	paymentEvent, err := apiCfg.Payments.ParseEvent(event.Payload)
+	if err != nil {
+		return "", err
+	}
+
+	changed, err := apiCfg.applyPaymentEvent(ctx, paymentEvent)
+	if err != nil {
+		return "", err
+	}
+	if !changed {
+		return webhookStatusIgnored, nil
+	}
+	return webhookStatusProcessed, nil
+}
+
+func (apiCfg *ApiConfig) ListWebhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	var dbEvents []database.WebhookEvent
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		dbEvents, err = apiCfg.DbQueries.ListWebhookEvents(r.Context(), database.ListWebhookEventsParams{
+			Limit:  limit,
+			Offset: offset,
+		})
+	} else {
+		dbEvents, err = apiCfg.DbQueries.ListWebhookEventsByStatus(r.Context(), database.ListWebhookEventsByStatusParams{
+			Status: status,
+			Limit:  limit,
+			Offset: offset,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve webhook events", err)
+		return
+	}
+
+	events := []WebhookEvent{}
+	for _, dbEvent := range dbEvents {
+		events = append(events, webhookEventFromDB(dbEvent))
+	}
+	respondWithJSON(w, http.StatusOK, events)
+}
+
+func (apiCfg *ApiConfig) GetWebhookEventHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	eventID, err := uuid.Parse(r.PathValue("eventID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse eventID", err)
+		return
+	}
+
+	event, err := apiCfg.DbQueries.GetWebhookEvent(r.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Couldn't find webhook event", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve webhook event", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, webhookEventFromDB(event))
+}
+
+// ReplayWebhookEventHandler runs a stored event through processing again,
+// e.g. after fixing whatever made it fail.
+func (apiCfg *ApiConfig) ReplayWebhookEventHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := apiCfg.authenticateAdmin(w, r)
+	if err != nil {
+		return
+	}
+
+	eventID, err := uuid.Parse(r.PathValue("eventID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to parse eventID", err)
+		return
+	}
+
+	_, err = apiCfg.DbQueries.GetWebhookEvent(r.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Couldn't find webhook event", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve webhook event", err)
+		return
+	}
+	event, err := apiCfg.DbQueries.ReclaimWebhookEvent(r.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusConflict, "Webhook event is being processed", err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Couldn't claim webhook event", err)
+		return
+	}
+
+	// A failed replay is recorded on the event, which is returned either way.
+	err = apiCfg.processWebhookEvent(r.Context(), event)
+	if err != nil {
+		log.Printf("replay of webhook event %v failed: %s", event.ID, err)
+	}
+
+	event, err = apiCfg.DbQueries.GetWebhookEvent(r.Context(), eventID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve webhook event", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, webhookEventFromDB(event))
+}
+
+// parsePagination reads the limit and offset query parameters.
+func parsePagination(r *http.Request) (int32, int32, error) {
+	limit := int32(defaultPageLimit)
+	offset := int32(0)
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || parsed < 1 || parsed > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		limit = int32(parsed)
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || parsed < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = int32(parsed)
+	}
+	return limit, offset, nil
+}
+
+func webhookEventFromDB(event database.WebhookEvent) WebhookEvent {
+	response := WebhookEvent{
+		ID:         event.ID,
+		ReceivedAt: event.ReceivedAt,
+		Provider:   event.Provider,
+		EventID:    event.EventID,
+		EventType:  event.EventType,
+		Payload:    event.Payload,
+		Status:     event.Status,
+		Error:      event.Error.String,
+		Attempts:   event.Attempts,
+	}
+	if event.ProcessedAt.Valid {
+		response.ProcessedAt = &event.ProcessedAt.Time
+	}
+	return response
+}
@@ -7,16 +7,43 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
+const countChirpsByUserID = `-- name: CountChirpsByUserID :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND publish_at IS NULL
+`
+
+func (q *Queries) CountChirpsByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsByUserID, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPublicChirpsByUserID = `-- name: CountPublicChirpsByUserID :one
+SELECT COUNT(*) FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = $1 AND chirps.hidden_at IS NULL AND chirps.publish_at IS NULL
+AND users.suspended_at IS NULL AND users.shadow_banned_at IS NULL
+`
+
+func (q *Queries) CountPublicChirpsByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPublicChirpsByUserID, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
 INSERT INTO chirps (id, created_at, updated_at, body, user_id)
 VALUES (
     gen_random_uuid(), NOW(), NOW(), $1, $2
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, publish_at
 `
 
 type CreateChirpParams struct {
@@ -33,6 +60,8 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.PublishAt,
 	)
 	return i, err
 }
@@ -47,9 +76,22 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteScheduledChirp = `-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps
+WHERE id = $1 AND publish_at IS NOT NULL
+`
+
+func (q *Queries) DeleteScheduledChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteScheduledChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, hidden_at, publish_at FROM chirps
+WHERE id = $1 AND publish_at IS NULL
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -61,12 +103,15 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.HiddenAt,
+		&i.PublishAt,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, hidden_at, publish_at FROM chirps
+WHERE hidden_at IS NULL AND publish_at IS NULL
 ORDER BY created_at ASC
 `
 
@@ -85,6 +130,167 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.HiddenAt,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByUserID = `-- name: GetChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, hidden_at, publish_at FROM chirps
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublicChirps = `-- name: GetPublicChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.hidden_at, chirps.publish_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.hidden_at IS NULL AND chirps.publish_at IS NULL
+AND users.suspended_at IS NULL AND users.shadow_banned_at IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT $1
+`
+
+func (q *Queries) GetPublicChirps(ctx context.Context, limit int32) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getPublicChirps, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublicChirpsByUserID = `-- name: GetPublicChirpsByUserID :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.hidden_at, chirps.publish_at FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = $1 AND chirps.hidden_at IS NULL AND chirps.publish_at IS NULL
+AND users.suspended_at IS NULL AND users.shadow_banned_at IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT $2
+`
+
+type GetPublicChirpsByUserIDParams struct {
+	UserID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetPublicChirpsByUserID(ctx context.Context, arg GetPublicChirpsByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getPublicChirpsByUserID, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublishedChirpsByUserID = `-- name: GetPublishedChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, hidden_at, publish_at FROM chirps
+WHERE user_id = $1 AND hidden_at IS NULL AND publish_at IS NULL
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetPublishedChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getPublishedChirpsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.PublishAt,
 		); err != nil {
 			return nil, err
 		}
@@ -98,3 +304,197 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	}
 	return items, nil
 }
+
+const getScheduledChirpByID = `-- name: GetScheduledChirpByID :one
+SELECT id, created_at, updated_at, body, user_id, hidden_at, publish_at FROM chirps
+WHERE id = $1 AND publish_at IS NOT NULL
+`
+
+func (q *Queries) GetScheduledChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledChirpByID, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.PublishAt,
+	)
+	return i, err
+}
+
+const getScheduledChirpsByUserID = `-- name: GetScheduledChirpsByUserID :many
+SELECT id, created_at, updated_at, body, user_id, hidden_at, publish_at FROM chirps
+WHERE user_id = $1 AND publish_at IS NOT NULL
+ORDER BY publish_at ASC
+`
+
+func (q *Queries) GetScheduledChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledChirpsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.HiddenAt,
+			&i.PublishAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const hideChirp = `-- name: HideChirp :execrows
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1 AND hidden_at IS NULL
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, hideChirp, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const publishDueChirp = `-- name: PublishDueChirp :one
+UPDATE chirps
+SET publish_at = NULL, created_at = NOW(), updated_at = NOW()
+WHERE id = (
+    SELECT id FROM chirps
+    WHERE publish_at <= NOW()
+    ORDER BY publish_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, publish_at
+`
+
+func (q *Queries) PublishDueChirp(ctx context.Context) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, publishDueChirp)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.PublishAt,
+	)
+	return i, err
+}
+
+const scheduleChirp = `-- name: ScheduleChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, publish_at)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
+)
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, publish_at
+`
+
+type ScheduleChirpParams struct {
+	Body      string
+	UserID    uuid.UUID
+	PublishAt sql.NullTime
+}
+
+func (q *Queries) ScheduleChirp(ctx context.Context, arg ScheduleChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, scheduleChirp, arg.Body, arg.UserID, arg.PublishAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.PublishAt,
+	)
+	return i, err
+}
+
+const unhideChirp = `-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) UnhideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unhideChirp, id)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, publish_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.PublishAt,
+	)
+	return i, err
+}
+
+const updateScheduledChirp = `-- name: UpdateScheduledChirp :one
+UPDATE chirps
+SET body = $2, publish_at = $3, updated_at = NOW()
+WHERE id = $1 AND publish_at IS NOT NULL
+RETURNING id, created_at, updated_at, body, user_id, hidden_at, publish_at
+`
+
+type UpdateScheduledChirpParams struct {
+	ID        uuid.UUID
+	Body      string
+	PublishAt sql.NullTime
+}
+
+func (q *Queries) UpdateScheduledChirp(ctx context.Context, arg UpdateScheduledChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledChirp, arg.ID, arg.Body, arg.PublishAt)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.HiddenAt,
+		&i.PublishAt,
+	)
+	return i, err
+}
@@ -6,17 +6,204 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ActorKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PublicKeyPem  string
+	PrivateKeyPem string
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	HiddenAt  sql.NullTime
+	PublishAt sql.NullTime
+}
+
+type ContentRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Kind      string
+	Pattern   string
+	Action    string
+}
+
+type Draft struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+}
+
+type Export struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Status      string
+	BlobKey     sql.NullString
+	Error       sql.NullString
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+type FederationDelivery struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Inbox         string
+	Activity      json.RawMessage
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	Error         sql.NullString
+}
+
+type IdempotencyKey struct {
+	UserID          uuid.UUID
+	Key             string
+	CreatedAt       time.Time
+	Fingerprint     string
+	ResponseStatus  sql.NullInt32
+	ResponseHeaders json.RawMessage
+	ResponseBody    []byte
+}
+
+type Membership struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Plan        string
+	Status      string
+	StartedAt   time.Time
+	EndsAt      sql.NullTime
+	CancelledAt sql.NullTime
+	Source      string
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	CaseID       uuid.NullUUID
+	ModeratorID  uuid.NullUUID
+	Action       string
+	TargetUserID uuid.NullUUID
+	ChirpID      uuid.NullUUID
+	Note         string
+}
+
+type ModerationCase struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ChirpID    uuid.NullUUID
+	Status     string
+	ClaimedBy  uuid.NullUUID
+	ClaimedAt  sql.NullTime
+	ResolvedAt sql.NullTime
+	Resolution sql.NullString
+	AuthorID   uuid.NullUUID
+	ChirpBody  string
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type MutedWord struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Phrase    string
+	WholeWord bool
+	Action    string
+	ExpiresAt sql.NullTime
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	CreatedAt     time.Time
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserID       uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+}
+
+type OauthRevokedAccessToken struct {
+	ID        uuid.UUID
+	ExpiresAt time.Time
+}
+
+type OidcLoginState struct {
+	State        string
+	CreatedAt    time.Time
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type PlanEntitlement struct {
+	Plan              string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	MaxChirpLength    int32
+	EditWindowSeconds int32
+	ChirpsPerMinute   int32
+	CanSchedule       bool
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
 }
 
 type RefreshToken struct {
@@ -26,13 +213,123 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	ClientID  uuid.NullUUID
+	Scopes    []string
+}
+
+type RemoteActor struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Uri               string
+	PreferredUsername string
+	Inbox             string
+	SharedInbox       string
+	KeyID             string
+	PublicKeyPem      string
+	FetchedAt         time.Time
+}
+
+type RemoteFollower struct {
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	CreatedAt time.Time
+	FollowUri string
+}
+
+type RemoteNote struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Uri         string
+	ActorID     uuid.UUID
+	Content     string
+	InReplyTo   sql.NullString
+	PublishedAt time.Time
+}
+
+type RemoteNoteRecipient struct {
+	NoteID uuid.UUID
+	UserID uuid.UUID
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	CaseID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Email               string
+	HashedPassword      string
+	TotpSecret          sql.NullString
+	TotpEnabled         bool
+	Role                string
+	SuspendedAt         sql.NullTime
+	ShadowBannedAt      sql.NullTime
+	DeletionScheduledAt sql.NullTime
+	TotpLastCounter     int64
+	MfaFailedAttempts   int32
+	MfaLockedUntil      sql.NullTime
+}
+
+type UserIdentity struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Issuer    string
+	Subject   string
+	Email     string
+}
+
+type Warning struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CaseID    uuid.NullUUID
+	ChirpID   uuid.NullUUID
+	ChirpBody string
+	Note      string
+}
+
+type WebauthnChallenge struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.NullUUID
+	Ceremony  string
+	Challenge []byte
+	ExpiresAt time.Time
+}
+
+type WebauthnCredential struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserID       uuid.UUID
+	Name         string
+	CredentialID []byte
+	PublicKey    []byte
+	SignCount    int64
+	LastUsedAt   sql.NullTime
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID
+	ReceivedAt  time.Time
+	Provider    string
+	EventID     string
+	EventType   string
+	Payload     json.RawMessage
+	Status      string
+	Error       sql.NullString
+	Attempts    int32
+	ProcessedAt sql.NullTime
+	ClaimedAt   sql.NullTime
 }
@@ -7,17 +7,49 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const acceptTOTPCounter = `-- name: AcceptTOTPCounter :execrows
+UPDATE users
+SET totp_last_counter = $2, mfa_failed_attempts = 0, mfa_locked_until = NULL
+WHERE id = $1 AND totp_last_counter < $2
+AND (mfa_locked_until IS NULL OR mfa_locked_until <= NOW())
+`
+
+type AcceptTOTPCounterParams struct {
+	ID              uuid.UUID
+	TotpLastCounter int64
+}
+
+func (q *Queries) AcceptTOTPCounter(ctx context.Context, arg AcceptTOTPCounterParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, acceptTOTPCounter, arg.ID, arg.TotpLastCounter)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const cancelUserDeletion = `-- name: CancelUserDeletion :exec
+UPDATE users
+SET deletion_scheduled_at = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	return err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
     gen_random_uuid(), NOW(), NOW(), $1, $2
 )
-RETURNING id, created_at, updated_at, email, is_chirpy_red
+RETURNING id, created_at, updated_at, email
 `
 
 type CreateUserParams struct {
@@ -26,11 +58,10 @@ type CreateUserParams struct {
 }
 
 type CreateUserRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Email       string
-	IsChirpyRed bool
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Email     string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
@@ -41,11 +72,23 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Email,
-		&i.IsChirpyRed,
 	)
 	return i, err
 }
 
+const deleteUserDueForDeletion = `-- name: DeleteUserDueForDeletion :execrows
+DELETE FROM users
+WHERE id = $1 AND deletion_scheduled_at <= NOW()
+`
+
+func (q *Queries) DeleteUserDueForDeletion(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserDueForDeletion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 DELETE FROM users *
 `
@@ -55,8 +98,93 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const disableTOTP = `-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled = FALSE, totp_last_counter = 0, mfa_failed_attempts = 0, mfa_locked_until = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableTOTP, id)
+	return err
+}
+
+const enableTOTP = `-- name: EnableTOTP :execrows
+UPDATE users
+SET totp_enabled = TRUE, totp_last_counter = $2, mfa_failed_attempts = 0, mfa_locked_until = NULL, updated_at = NOW()
+WHERE id = $1 AND totp_enabled = FALSE
+`
+
+type EnableTOTPParams struct {
+	ID              uuid.UUID
+	TotpLastCounter int64
+}
+
+func (q *Queries) EnableTOTP(ctx context.Context, arg EnableTOTPParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableTOTP, arg.ID, arg.TotpLastCounter)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getRestrictedUserIDs = `-- name: GetRestrictedUserIDs :many
+SELECT id FROM users
+WHERE suspended_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+`
+
+func (q *Queries) GetRestrictedUserIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getRestrictedUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserAccessState = `-- name: GetUserAccessState :one
+SELECT
+    EXISTS (
+        SELECT 1 FROM users
+        WHERE id = $1 AND suspended_at IS NOT NULL
+    ) AS suspended,
+    EXISTS (
+        SELECT 1 FROM users
+        WHERE id = $1 AND deletion_scheduled_at IS NOT NULL
+    ) AS deletion_scheduled
+`
+
+type GetUserAccessStateRow struct {
+	Suspended         bool
+	DeletionScheduled bool
+}
+
+func (q *Queries) GetUserAccessState(ctx context.Context, id uuid.UUID) (GetUserAccessStateRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserAccessState, id)
+	var i GetUserAccessStateRow
+	err := row.Scan(
+		&i.Suspended,
+		&i.DeletionScheduled,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, totp_secret, totp_enabled, role, suspended_at, shadow_banned_at, deletion_scheduled_at, totp_last_counter, mfa_failed_attempts, mfa_locked_until FROM users
 WHERE email = $1
 `
 
@@ -69,19 +197,239 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.ShadowBannedAt,
+		&i.DeletionScheduledAt,
+		&i.TotpLastCounter,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
 	)
 	return i, err
 }
 
-const updateMembership = `-- name: UpdateMembership :exec
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, totp_secret, totp_enabled, role, suspended_at, shadow_banned_at, deletion_scheduled_at, totp_last_counter, mfa_failed_attempts, mfa_locked_until FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.ShadowBannedAt,
+		&i.DeletionScheduledAt,
+		&i.TotpLastCounter,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const getUsersDueForDeletion = `-- name: GetUsersDueForDeletion :many
+SELECT id FROM users
+WHERE deletion_scheduled_at <= NOW()
+`
+
+func (q *Queries) GetUsersDueForDeletion(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersDueForDeletion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isUserSuspended = `-- name: IsUserSuspended :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE id = $1 AND suspended_at IS NOT NULL
+)
+`
+
+func (q *Queries) IsUserSuspended(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isUserSuspended, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, created_at, updated_at, email, hashed_password, totp_secret, totp_enabled, role, suspended_at, shadow_banned_at, deletion_scheduled_at, totp_last_counter, mfa_failed_attempts, mfa_locked_until FROM users
+ORDER BY created_at ASC
+LIMIT $1 OFFSET $2
+`
+
+type ListUsersParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.TotpSecret,
+			&i.TotpEnabled,
+			&i.Role,
+			&i.SuspendedAt,
+			&i.ShadowBannedAt,
+			&i.DeletionScheduledAt,
+			&i.TotpLastCounter,
+			&i.MfaFailedAttempts,
+			&i.MfaLockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordMFAFailure = `-- name: RecordMFAFailure :exec
+UPDATE users
+SET mfa_failed_attempts = CASE WHEN mfa_failed_attempts + 1 >= $2::integer THEN 0 ELSE mfa_failed_attempts + 1 END,
+    mfa_locked_until = CASE WHEN mfa_failed_attempts + 1 >= $2::integer THEN $3::timestamp ELSE mfa_locked_until END
+WHERE id = $1
+`
+
+type RecordMFAFailureParams struct {
+	ID          uuid.UUID
+	MaxAttempts int32
+	LockedUntil time.Time
+}
+
+func (q *Queries) RecordMFAFailure(ctx context.Context, arg RecordMFAFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordMFAFailure, arg.ID, arg.MaxAttempts, arg.LockedUntil)
+	return err
+}
+
+const resetMFAFailures = `-- name: ResetMFAFailures :exec
+UPDATE users
+SET mfa_failed_attempts = 0
+WHERE id = $1
+`
+
+func (q *Queries) ResetMFAFailures(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, resetMFAFailures, id)
+	return err
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :exec
+UPDATE users
+SET deletion_scheduled_at = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type ScheduleUserDeletionParams struct {
+	ID                  uuid.UUID
+	DeletionScheduledAt sql.NullTime
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleUserDeletion, arg.ID, arg.DeletionScheduledAt)
+	return err
+}
+
+const setTOTPSecret = `-- name: SetTOTPSecret :exec
 UPDATE users
-SET is_chirpy_red = TRUE, updated_at = NOW()
+SET totp_secret = $2, totp_enabled = FALSE, updated_at = NOW()
 WHERE id = $1
 `
 
-func (q *Queries) UpdateMembership(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, updateMembership, id)
+type SetTOTPSecretParams struct {
+	ID         uuid.UUID
+	TotpSecret sql.NullString
+}
+
+func (q *Queries) SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setTOTPSecret, arg.ID, arg.TotpSecret)
+	return err
+}
+
+const shadowBanUser = `-- name: ShadowBanUser :exec
+UPDATE users
+SET shadow_banned_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) ShadowBanUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, shadowBanUser, id)
+	return err
+}
+
+const suspendUser = `-- name: SuspendUser :exec
+UPDATE users
+SET suspended_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, suspendUser, id)
+	return err
+}
+
+const unshadowBanUser = `-- name: UnshadowBanUser :exec
+UPDATE users
+SET shadow_banned_at = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) UnshadowBanUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unshadowBanUser, id)
+	return err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :exec
+UPDATE users
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unsuspendUser, id)
 	return err
 }
 
@@ -89,7 +437,7 @@ const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET email = $1, hashed_password = $2, updated_at = NOW()
 WHERE id = $3
-RETURNING id, created_at, updated_at, email, is_chirpy_red
+RETURNING id, created_at, updated_at, email
 `
 
 type UpdateUserParams struct {
@@ -99,11 +447,10 @@ type UpdateUserParams struct {
 }
 
 type UpdateUserRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Email       string
-	IsChirpyRed bool
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Email     string
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserRow, error) {
@@ -114,7 +461,6 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateU
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Email,
-		&i.IsChirpyRed,
 	)
 	return i, err
 }
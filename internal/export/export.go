@@ -0,0 +1,82 @@
+// Package export builds personal data archives: a zip holding each kind of
+// data a user has as a JSON file, plus a CSV file for tabular data.
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// File is one kind of data in an archive.
+type File struct {
+	// Name is the file name without an extension, like "chirps".
+	Name string
+	// Data is written to Name.json.
+	Data any
+	// Header and Rows are also written to Name.csv when Header is set.
+	Header []string
+	Rows   [][]string
+}
+
+// Write writes files to w as a zip archive, stamping each entry with
+// createdAt.
+func Write(w io.Writer, files []File, createdAt time.Time) error {
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		err := writeJSON(archive, file, createdAt)
+		if err != nil {
+			return err
+		}
+		if file.Header == nil {
+			continue
+		}
+		err = writeCSV(archive, file, createdAt)
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeJSON(archive *zip.Writer, file File, createdAt time.Time) error {
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     file.Name + ".json",
+		Method:   zip.Deflate,
+		Modified: createdAt,
+	})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(file.Data)
+	if err != nil {
+		return fmt.Errorf("couldn't encode %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+func writeCSV(archive *zip.Writer, file File, createdAt time.Time) error {
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     file.Name + ".csv",
+		Method:   zip.Deflate,
+		Modified: createdAt,
+	})
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(entry)
+	err = writer.Write(file.Header)
+	if err != nil {
+		return err
+	}
+	err = writer.WriteAll(file.Rows)
+	if err != nil {
+		return fmt.Errorf("couldn't write %s: %w", file.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	files := []File{
+		{Name: "profile", Data: map[string]string{"email": "a@example.com"}},
+		{
+			Name:   "chirps",
+			Data:   []map[string]string{{"body": "hello, world"}},
+			Header: []string{"body"},
+			Rows:   [][]string{{"hello, world"}},
+		},
+	}
+
+	buf := bytes.Buffer{}
+	err := Write(&buf, files, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	want := map[string]string{
+		"profile.json": "{\n  \"email\": \"a@example.com\"\n}\n",
+		"chirps.json":  "[\n  {\n    \"body\": \"hello, world\"\n  }\n]\n",
+		"chirps.csv":   "body\n\"hello, world\"\n",
+	}
+	if len(archive.File) != len(want) {
+		t.Fatalf("archive has %d files, want %d", len(archive.File), len(want))
+	}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", file.Name, err)
+		}
+		got, _ := io.ReadAll(r)
+		r.Close()
+		if string(got) != want[file.Name] {
+			t.Errorf("%s = %q, want %q", file.Name, got, want[file.Name])
+		}
+	}
+}
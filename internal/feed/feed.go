@@ -0,0 +1,173 @@
+// Package feed renders lists of chirps as RSS 2.0 and Atom feeds.
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// titleLength is how many characters of a chirp make up its entry title.
+const titleLength = 50
+
+// Feed is a list of chirps, newest first.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the page the feed is about; SelfLink is the feed itself.
+	Link     string
+	SelfLink string
+	// ID identifies the feed for Atom readers and must never change.
+	ID string
+	// Updated is when any item last changed.
+	Updated time.Time
+	Items   []Item
+}
+
+type Item struct {
+	ID   uuid.UUID
+	Link string
+	// Author names the author in Atom feeds. RSS only allows an email
+	// address there, so it's left out of RSS.
+	Author    string
+	Body      string
+	Published time.Time
+	Updated   time.Time
+}
+
+// GUID is the stable, globally unique ID of an item.
+func (i Item) GUID() string {
+	return "urn:uuid:" + i.ID.String()
+}
+
+// Title shortens the item's body to an entry title.
+func (i Item) Title() string {
+	runes := []rune(i.Body)
+	if len(runes) <= titleLength {
+		return i.Body
+	}
+	return string(runes[:titleLength-1]) + "…"
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders f as an RSS 2.0 document.
+func RSS(f Feed) ([]byte, error) {
+	channel := rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		SelfLink:      atomLink{Href: f.SelfLink, Rel: "self", Type: "application/rss+xml"},
+		LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+		Items:         []rssItem{},
+	}
+	for _, item := range f.Items {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       item.Title(),
+			Link:        item.Link,
+			Description: item.Body,
+			GUID:        rssGUID{IsPermaLink: false, Value: item.GUID()},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return marshal(rss{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: channel})
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Author    *atomAuthor `xml:"author"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Atom renders f as an Atom 1.0 document.
+func Atom(f Feed) ([]byte, error) {
+	feed := atomFeed{
+		ID:      f.ID,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.Link, Rel: "alternate"},
+			{Href: f.SelfLink, Rel: "self", Type: "application/atom+xml"},
+		},
+		Entries: []atomEntry{},
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			ID:        item.GUID(),
+			Title:     item.Title(),
+			Link:      atomLink{Href: item.Link, Rel: "alternate"},
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "text", Value: item.Body},
+		}
+		if item.Author != "" {
+			entry.Author = &atomAuthor{Name: item.Author}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return marshal(feed)
+}
+
+func marshal(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,102 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testFeed() Feed {
+	published := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return Feed{
+		Title:       "Chirpy",
+		Description: "Latest chirps",
+		Link:        "https://chirpy.example/",
+		SelfLink:    "https://chirpy.example/feed.atom",
+		ID:          "https://chirpy.example/feed",
+		Updated:     published.Add(time.Hour),
+		Items: []Item{{
+			ID:        uuid.MustParse("7d5b2e6a-5c2f-4f4b-9d1e-2a6b7c8d9e0f"),
+			Link:      "https://chirpy.example/api/chirps/7d5b2e6a-5c2f-4f4b-9d1e-2a6b7c8d9e0f",
+			Author:    "someone",
+			Body:      "Fish & chips <3",
+			Published: published,
+			Updated:   published.Add(time.Hour),
+		}},
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := RSS(testFeed())
+	if err != nil {
+		t.Fatalf("RSS() error = %v", err)
+	}
+
+	doc := rss{}
+	err = xml.Unmarshal(body, &doc)
+	if err != nil {
+		t.Fatalf("RSS() isn't valid XML: %v", err)
+	}
+	if len(doc.Channel.Items) != 1 {
+		t.Fatalf("RSS() has %d items, want 1", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[0]
+	if item.GUID.Value != "urn:uuid:7d5b2e6a-5c2f-4f4b-9d1e-2a6b7c8d9e0f" || item.GUID.IsPermaLink {
+		t.Errorf("guid = %+v, want the chirp's urn:uuid", item.GUID)
+	}
+	if item.Description != "Fish & chips <3" {
+		t.Errorf("description = %q, want the chirp body", item.Description)
+	}
+	if item.PubDate != "Sun, 01 Mar 2026 12:00:00 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := Atom(testFeed())
+	if err != nil {
+		t.Fatalf("Atom() error = %v", err)
+	}
+
+	doc := atomFeed{}
+	err = xml.Unmarshal(body, &doc)
+	if err != nil {
+		t.Fatalf("Atom() isn't valid XML: %v", err)
+	}
+	if doc.Updated != "2026-03-01T13:00:00Z" {
+		t.Errorf("updated = %q", doc.Updated)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("Atom() has %d entries, want 1", len(doc.Entries))
+	}
+	entry := doc.Entries[0]
+	if entry.ID != "urn:uuid:7d5b2e6a-5c2f-4f4b-9d1e-2a6b7c8d9e0f" {
+		t.Errorf("id = %q", entry.ID)
+	}
+	if entry.Published != "2026-03-01T12:00:00Z" || entry.Updated != "2026-03-01T13:00:00Z" {
+		t.Errorf("published, updated = %q, %q", entry.Published, entry.Updated)
+	}
+}
+
+func TestItemTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "Short", body: "hello", want: "hello"},
+		{name: "Long", body: strings.Repeat("a", 60), want: strings.Repeat("a", 49) + "…"},
+		{name: "Multi-byte", body: strings.Repeat("ש", 60), want: strings.Repeat("ש", 49) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Item{Body: tt.body}).Title(); got != tt.want {
+				t.Errorf("Title() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
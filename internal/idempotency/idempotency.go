@@ -0,0 +1,110 @@
+// Package idempotency records the responses of requests sent with an
+// Idempotency-Key header, so that a client retrying a request after a
+// network failure gets the original response instead of repeating it.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	// Header is the request header carrying the key a client picked for a
+	// request, usually a random UUID.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// ValidKey reports whether key is 1 to 255 printable ASCII characters.
+func ValidKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < ' ' || key[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Fingerprint identifies a request by its method, URI and body, to tell a
+// retry from a different request reusing the same key.
+func Fingerprint(method, requestURI string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(requestURI))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Successful reports whether the request succeeded. Only successful
+// responses are worth replaying: a request that failed changed nothing,
+// so retrying it can run it again.
+func (resp Response) Successful() bool {
+	return resp.Status >= 200 && resp.Status < 300
+}
+
+// Replay writes the response again, marked as replayed.
+func (resp Response) Replay(w http.ResponseWriter) {
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// Recorder is an http.ResponseWriter that passes the response through to
+// the client while recording it.
+type Recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func NewRecorder(w http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w}
+}
+
+func (rec *Recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *Recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Response returns what was written so far.
+func (rec *Recorder) Response() Response {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return Response{
+		Status: status,
+		Header: rec.Header().Clone(),
+		Body:   bytes.Clone(rec.body.Bytes()),
+	}
+}
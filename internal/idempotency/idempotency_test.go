@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "5b1f7a52-6a3f-4d7e-9a43-1b3f0c2a9e10", want: true},
+		{key: "retry me", want: true},
+		{key: strings.Repeat("k", 255), want: true},
+		{key: "", want: false},
+		{key: strings.Repeat("k", 256), want: false},
+		{key: "line\nbreak", want: false},
+		{key: "clé", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.want {
+			t.Errorf("ValidKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint(http.MethodPost, "/api/chirps", []byte(`{"body":"hello"}`))
+	if base != Fingerprint(http.MethodPost, "/api/chirps", []byte(`{"body":"hello"}`)) {
+		t.Error("Fingerprint() differs for the same request")
+	}
+
+	others := map[string]string{
+		"body":   Fingerprint(http.MethodPost, "/api/chirps", []byte(`{"body":"goodbye"}`)),
+		"path":   Fingerprint(http.MethodPost, "/api/blocks", []byte(`{"body":"hello"}`)),
+		"method": Fingerprint(http.MethodPut, "/api/chirps", []byte(`{"body":"hello"}`)),
+		// The separators keep the URI and body from running together.
+		"boundary": Fingerprint(http.MethodPost, "/api/chirps{", []byte(`"body":"hello"}`)),
+	}
+	for name, other := range others {
+		if other == base {
+			t.Errorf("Fingerprint() is the same for a different %s", name)
+		}
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"id":"1",`)
+		io.WriteString(w, `"body":"hello"}`)
+	})
+
+	original := httptest.NewRecorder()
+	recorder := NewRecorder(original)
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/chirps", nil))
+	resp := recorder.Response()
+	if !resp.Successful() {
+		t.Errorf("Successful() = false for status %d", resp.Status)
+	}
+	if original.Code != http.StatusCreated || original.Body.String() != `{"id":"1","body":"hello"}` {
+		t.Errorf("client got %d %q, want the response passed through", original.Code, original.Body)
+	}
+
+	replayed := httptest.NewRecorder()
+	resp.Replay(replayed)
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != original.Body.String() {
+		t.Errorf("Replay() wrote %d %q, want %d %q", replayed.Code, replayed.Body, original.Code, original.Body)
+	}
+	if replayed.Header().Get("Content-Type") != "application/json" || replayed.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("Replay() headers = %v", replayed.Header())
+	}
+}
+
+func TestRecorderStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		wantStatus     int
+		wantSuccessful bool
+	}{
+		{
+			name:           "Implicit OK",
+			handler:        func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") },
+			wantStatus:     http.StatusOK,
+			wantSuccessful: true,
+		},
+		{
+			name:           "Nothing written",
+			handler:        func(w http.ResponseWriter, r *http.Request) {},
+			wantStatus:     http.StatusOK,
+			wantSuccessful: true,
+		},
+		{
+			name: "Rate limited",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "Status written twice",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.WriteHeader(http.StatusOK)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := NewRecorder(httptest.NewRecorder())
+			tt.handler(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+			resp := recorder.Response()
+			if resp.Status != tt.wantStatus || resp.Successful() != tt.wantSuccessful {
+				t.Errorf("Response() status = %d, successful %v, want %d, %v", resp.Status, resp.Successful(), tt.wantStatus, tt.wantSuccessful)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package moderation
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/OferRavid/chirpy/internal/chirptext"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+type wordFilter struct {
+	// words maps normalized words to their rules.
+	words map[string]Rule
+}
+
+func (f wordFilter) Apply(text string) (string, []Violation) {
+	if len(f.words) == 0 {
+		return text, nil
+	}
+
+	violations := []Violation{}
+	var out strings.Builder
+	last := 0
+	for _, token := range tokens(text) {
+		word := text[token.start:token.end]
+		rule, ok := f.match(word)
+		if !ok {
+			continue
+		}
+		violations = append(violations, Violation{RuleID: rule.ID, Kind: RuleWord, Action: rule.Action, Match: word})
+		if rule.Action == ActionMask {
+			out.WriteString(text[last:token.start])
+			out.WriteString(Mask)
+			last = token.end
+		}
+	}
+	out.WriteString(text[last:])
+	return out.String(), violations
+}
+
+func (f wordFilter) match(word string) (Rule, bool) {
+	normalized := normalizeWord(word)
+	if rule, ok := f.words[normalized]; ok {
+		return rule, true
+	}
+	// Drawn out letters are shortened to two rather than one, so that
+	// "kerfufffle" is still "kerfuffle" but "goood" is "good", not "god".
+	if !hasRun(normalized, 3) {
+		return Rule{}, false
+	}
+	shortened := shortenRuns(normalized)
+	for banned, rule := range f.words {
+		if shortenRuns(banned) == shortened {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+type regexFilter struct {
+	rule Rule
+	re   *regexp.Regexp
+}
+
+func (f regexFilter) Apply(text string) (string, []Violation) {
+	matches := f.re.FindAllString(text, -1)
+	if len(matches) == 0 {
+		return text, nil
+	}
+
+	violations := []Violation{}
+	for _, match := range matches {
+		violations = append(violations, Violation{RuleID: f.rule.ID, Kind: RuleRegex, Action: f.rule.Action, Match: match})
+	}
+	if f.rule.Action == ActionMask {
+		text = f.re.ReplaceAllLiteralString(text, Mask)
+	}
+	return text, violations
+}
+
+type linkFilter struct {
+	maxLinks int
+	domains  []Rule
+}
+
+func (f linkFilter) Apply(text string) (string, []Violation) {
+	links := chirptext.FindURLs(text)
+	violations := []Violation{}
+	if len(links) > f.maxLinks {
+		violations = append(violations, Violation{Action: ActionReject, Match: strings.Join(links, " ")})
+	}
+
+	for _, link := range links {
+		// Punctuation right after a link usually belongs to the sentence,
+		// as in "(see https://example.com)."
+		link = strings.TrimRight(link, ".,;:!?)]}'")
+		host := linkHost(link)
+		for _, rule := range f.domains {
+			domain := strings.TrimSuffix(strings.ToLower(rule.Pattern), ".")
+			if host != domain && !strings.HasSuffix(host, "."+domain) {
+				continue
+			}
+			violations = append(violations, Violation{RuleID: rule.ID, Kind: RuleDomain, Action: rule.Action, Match: link})
+			if rule.Action == ActionMask {
+				text = strings.ReplaceAll(text, link, Mask)
+			}
+			break
+		}
+	}
+	return text, violations
+}
+
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+}
+
+type span struct {
+	start, end int
+}
+
+// tokens splits text into words made of letters, digits and combining
+// marks, so punctuation next to a word doesn't hide it.
+func tokens(text string) []span {
+	spans := []span{}
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+var foldAccents = transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFKC)
+
+// normalizeWord lower-cases a word and strips accents and compatibility
+// variants, so "Ｓｈａｒｂｅｒｔ" and "shärbert" compare equal to "sharbert".
+func normalizeWord(word string) string {
+	folded, _, err := transform.String(foldAccents, word)
+	if err != nil {
+		folded = word
+	}
+	return strings.ToLower(folded)
+}
+
+func hasRun(s string, length int) bool {
+	run := 0
+	var previous rune
+	for i, r := range s {
+		if i > 0 && r == previous {
+			run++
+		} else {
+			run = 1
+		}
+		if run >= length {
+			return true
+		}
+		previous = r
+	}
+	return false
+}
+
+// shortenRuns cuts runs of three or more of the same letter down to two.
+func shortenRuns(s string) string {
+	var out strings.Builder
+	run := 0
+	var previous rune = utf8.RuneError
+	for _, r := range s {
+		if r == previous {
+			run++
+		} else {
+			run = 1
+		}
+		if run <= 2 {
+			out.WriteRune(r)
+		}
+		previous = r
+	}
+	return out.String()
+}
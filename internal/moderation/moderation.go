@@ -0,0 +1,149 @@
+// Package moderation checks chirp bodies against content rules: banned
+// words, regular expressions, blocked link domains and link spam.
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Mask replaces text that breaks a masking rule.
+const Mask = "****"
+
+// MaxLinks is how many links a chirp may contain before it's treated as
+// link spam.
+const MaxLinks = 3
+
+type RuleKind string
+
+const (
+	// RuleWord matches a whole word, ignoring case, accents, surrounding
+	// punctuation and drawn-out letters ("kerfuffle!", "KERFUFFFLE").
+	RuleWord RuleKind = "word"
+	// RuleRegex matches a regular expression.
+	RuleRegex RuleKind = "regex"
+	// RuleDomain matches links to a domain or any of its subdomains.
+	RuleDomain RuleKind = "domain"
+)
+
+type Action string
+
+const (
+	// ActionMask replaces the matching text with Mask.
+	ActionMask Action = "mask"
+	// ActionReject refuses the whole chirp.
+	ActionReject Action = "reject"
+)
+
+type Rule struct {
+	ID      uuid.UUID
+	Kind    RuleKind
+	Pattern string
+	Action  Action
+}
+
+// Violation records a rule a chirp broke. RuleID is uuid.Nil for the
+// built-in link spam check.
+type Violation struct {
+	RuleID uuid.UUID
+	Kind   RuleKind
+	Action Action
+	Match  string
+}
+
+// Result is the outcome of running a chirp through a Pipeline.
+type Result struct {
+	// Text is the chirp with masked matches replaced.
+	Text       string
+	Rejected   bool
+	Violations []Violation
+}
+
+// Filter is one stage of a Pipeline. It returns text with its masked
+// matches replaced and the violations it found.
+type Filter interface {
+	Apply(text string) (string, []Violation)
+}
+
+// Pipeline runs a chirp through each of its filters in order.
+type Pipeline struct {
+	filters []Filter
+}
+
+// New compiles rules into a Pipeline. Word rules run first, then regular
+// expressions, then link checks.
+func New(rules []Rule) (*Pipeline, error) {
+	words := wordFilter{words: map[string]Rule{}}
+	regexes := []Filter{}
+	links := linkFilter{maxLinks: MaxLinks}
+
+	for _, rule := range rules {
+		err := rule.Validate()
+		if err != nil {
+			return nil, err
+		}
+		switch rule.Kind {
+		case RuleWord:
+			words.words[normalizeWord(rule.Pattern)] = rule
+		case RuleRegex:
+			regexes = append(regexes, regexFilter{rule: rule, re: regexp.MustCompile(rule.Pattern)})
+		case RuleDomain:
+			links.domains = append(links.domains, rule)
+		}
+	}
+
+	filters := []Filter{words}
+	filters = append(filters, regexes...)
+	filters = append(filters, links)
+	return &Pipeline{filters: filters}, nil
+}
+
+// Check runs text through every filter.
+func (p *Pipeline) Check(text string) Result {
+	result := Result{Text: text}
+	for _, filter := range p.filters {
+		var violations []Violation
+		result.Text, violations = filter.Apply(result.Text)
+		for _, violation := range violations {
+			if violation.Action == ActionReject {
+				result.Rejected = true
+			}
+		}
+		result.Violations = append(result.Violations, violations...)
+	}
+	return result
+}
+
+// Validate reports whether a rule is well formed, e.g. so that admins get an
+// error when saving a regular expression that doesn't compile.
+func (r Rule) Validate() error {
+	if r.Action != ActionMask && r.Action != ActionReject {
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	if strings.TrimSpace(r.Pattern) == "" {
+		return errors.New("pattern is required")
+	}
+
+	switch r.Kind {
+	case RuleWord:
+		if len(tokens(r.Pattern)) != 1 {
+			return fmt.Errorf("word rule %q must be a single word", r.Pattern)
+		}
+	case RuleRegex:
+		_, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	case RuleDomain:
+		if strings.ContainsAny(r.Pattern, "/: ") {
+			return fmt.Errorf("domain rule %q must be a bare domain name", r.Pattern)
+		}
+	default:
+		return fmt.Errorf("unknown rule kind %q", r.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package moderation
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPipelineCheck(t *testing.T) {
+	rules := []Rule{
+		{ID: uuid.New(), Kind: RuleWord, Pattern: "kerfuffle", Action: ActionMask},
+		{ID: uuid.New(), Kind: RuleWord, Pattern: "sharbert", Action: ActionMask},
+		{ID: uuid.New(), Kind: RuleWord, Pattern: "fornax", Action: ActionMask},
+		{ID: uuid.New(), Kind: RuleWord, Pattern: "god", Action: ActionMask},
+		{ID: uuid.New(), Kind: RuleRegex, Pattern: `\b\d{3}-\d{3}-\d{4}\b`, Action: ActionMask},
+		{ID: uuid.New(), Kind: RuleRegex, Pattern: `(?i)buy\s+followers`, Action: ActionReject},
+		{ID: uuid.New(), Kind: RuleDomain, Pattern: "spam.example", Action: ActionReject},
+		{ID: uuid.New(), Kind: RuleDomain, Pattern: "tracker.example", Action: ActionMask},
+	}
+	pipeline, err := New(rules)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		text         string
+		wantText     string
+		wantRejected bool
+	}{
+		{
+			name:     "Clean chirp",
+			text:     "I had something interesting for breakfast",
+			wantText: "I had something interesting for breakfast",
+		},
+		{
+			name:     "Space separated bad word",
+			text:     "This is a kerfuffle opinion I need to share with the world",
+			wantText: "This is a **** opinion I need to share with the world",
+		},
+		{
+			name:     "Punctuation next to bad words",
+			text:     "What a kerfuffle! Sharbert. (fornax)",
+			wantText: "What a ****! ****. (****)",
+		},
+		{
+			name:     "Accents and full-width letters",
+			text:     "shärbert and Ｆｏｒｎａｘ",
+			wantText: "**** and ****",
+		},
+		{
+			name:     "Drawn out letters",
+			text:     "KERFUFFFFLE",
+			wantText: "****",
+		},
+		{
+			name:     "Double letters aren't collapsed",
+			text:     "good morning",
+			wantText: "good morning",
+		},
+		{
+			name:     "Drawn out letters are shortened to doubles",
+			text:     "goood morning",
+			wantText: "goood morning",
+		},
+		{
+			name:     "Bad word inside a longer word",
+			text:     "sharbertson",
+			wantText: "sharbertson",
+		},
+		{
+			name:     "Masking regex",
+			text:     "call me at 555-123-4567",
+			wantText: "call me at ****",
+		},
+		{
+			name:         "Rejecting regex",
+			text:         "Buy  followers cheap",
+			wantText:     "Buy  followers cheap",
+			wantRejected: true,
+		},
+		{
+			name:         "Blocked domain",
+			text:         "look https://www.spam.example/deal",
+			wantText:     "look https://www.spam.example/deal",
+			wantRejected: true,
+		},
+		{
+			name:         "Blocked domain before a comma",
+			text:         "look https://spam.example, it's cheap",
+			wantText:     "look https://spam.example, it's cheap",
+			wantRejected: true,
+		},
+		{
+			name:         "Blocked domain in parentheses",
+			text:         "cheap (https://spam.example)",
+			wantText:     "cheap (https://spam.example)",
+			wantRejected: true,
+		},
+		{
+			name:         "Blocked domain before a semicolon",
+			text:         "www.spam.example; cheap",
+			wantText:     "www.spam.example; cheap",
+			wantRejected: true,
+		},
+		{
+			name:         "Blocked domain at the end of a sentence",
+			text:         "look at https://spam.example.",
+			wantText:     "look at https://spam.example.",
+			wantRejected: true,
+		},
+		{
+			name:         "Blocked domain with a trailing dot and capitals",
+			text:         "look https://SPAM.Example./deal",
+			wantText:     "look https://SPAM.Example./deal",
+			wantRejected: true,
+		},
+		{
+			name:     "Masked domain keeps the punctuation",
+			text:     "tracked (https://tracker.example/p?id=1), sorry",
+			wantText: "tracked (****), sorry",
+		},
+		{
+			name:         "Link spam",
+			text:         "https://a.example https://b.example www.c.example https://d.example",
+			wantText:     "https://a.example https://b.example www.c.example https://d.example",
+			wantRejected: true,
+		},
+		{
+			name:     "A few links are fine",
+			text:     "see https://a.example and www.b.example",
+			wantText: "see https://a.example and www.b.example",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := pipeline.Check(tt.text)
+			if result.Text != tt.wantText || result.Rejected != tt.wantRejected {
+				t.Errorf("Check() = %q, rejected %v, want %q, rejected %v", result.Text, result.Rejected, tt.wantText, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "Word", rule: Rule{Kind: RuleWord, Pattern: "fornax", Action: ActionMask}, wantErr: false},
+		{name: "Several words", rule: Rule{Kind: RuleWord, Pattern: "two words", Action: ActionMask}, wantErr: true},
+		{name: "Invalid regex", rule: Rule{Kind: RuleRegex, Pattern: "(unclosed", Action: ActionMask}, wantErr: true},
+		{name: "Domain with scheme", rule: Rule{Kind: RuleDomain, Pattern: "https://spam.example", Action: ActionReject}, wantErr: true},
+		{name: "Unknown kind", rule: Rule{Kind: "emoji", Pattern: "x", Action: ActionMask}, wantErr: true},
+		{name: "Unknown action", rule: Rule{Kind: RuleWord, Pattern: "x", Action: "shadowban"}, wantErr: true},
+		{name: "Empty pattern", rule: Rule{Kind: RuleRegex, Pattern: " ", Action: ActionMask}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMutedWordMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		muted MutedWord
+		text  string
+		want  bool
+	}{
+		{name: "Substring", muted: MutedWord{Phrase: "cat"}, text: "A new category", want: true},
+		{name: "Whole word", muted: MutedWord{Phrase: "cat", WholeWord: true}, text: "A new category", want: false},
+		{name: "Whole word with punctuation", muted: MutedWord{Phrase: "cat", WholeWord: true}, text: "My cat!", want: true},
+		{name: "Phrase", muted: MutedWord{Phrase: "season finale", WholeWord: true}, text: "The Season  Finale was wild", want: true},
+		{name: "Phrase out of order", muted: MutedWord{Phrase: "season finale", WholeWord: true}, text: "finale of the season", want: false},
+		{name: "Accents", muted: MutedWord{Phrase: "creme brulee"}, text: "Crème brûlée for dessert", want: true},
+		{name: "Empty phrase", muted: MutedWord{Phrase: "  "}, text: "anything", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.muted.Matches(tt.text); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
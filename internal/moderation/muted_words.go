@@ -0,0 +1,49 @@
+package moderation
+
+import "strings"
+
+// MutedWord is a word or phrase a user doesn't want to read about.
+// Matching ignores case, accents and compatibility variants like the word
+// rules do.
+type MutedWord struct {
+	Phrase string
+	// WholeWord only matches the phrase as complete words, so muting "cat"
+	// doesn't hide chirps about "category".
+	WholeWord bool
+}
+
+// Matches reports whether text contains the muted phrase.
+func (m MutedWord) Matches(text string) bool {
+	phrase := normalizeWord(strings.TrimSpace(m.Phrase))
+	if phrase == "" {
+		return false
+	}
+	if !m.WholeWord {
+		return strings.Contains(normalizeWord(text), phrase)
+	}
+
+	want := words(phrase)
+	got := words(text)
+	for i := 0; i+len(want) <= len(got); i++ {
+		match := true
+		for j := range want {
+			if got[i+j] != want[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// words returns the normalized words of text.
+func words(text string) []string {
+	out := []string{}
+	for _, token := range tokens(text) {
+		out = append(out, normalizeWord(text[token.start:token.end]))
+	}
+	return out
+}
@@ -0,0 +1,205 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// standInProvider is a minimal local OpenID provider: it serves discovery,
+// its signing keys and a token endpoint that returns whatever ID token the
+// test queued for the next code.
+type standInProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	idToken string
+	form    url.Values
+}
+
+func newStandInProvider(t *testing.T) *standInProvider {
+	t.Helper()
+	p := &standInProvider{t: t, keys: map[string]*rsa.PrivateKey{}}
+	p.addKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		keys := []map[string]string{}
+		for kid, key := range p.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.form = r.PostForm
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "provider-access-token",
+			"token_type":   "Bearer",
+			"id_token":     p.idToken,
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *standInProvider) addKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		p.t.Fatalf("GenerateKey() error = %v", err)
+	}
+	p.mu.Lock()
+	p.keys[kid] = key
+	p.mu.Unlock()
+}
+
+func (p *standInProvider) sign(kid string, claims jwt.MapClaims) string {
+	p.mu.Lock()
+	key := p.keys[kid]
+	p.mu.Unlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		p.t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func (p *standInProvider) claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            "staff-42",
+		"aud":            "chirpy",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          "staff@example.com",
+		"email_verified": true,
+	}
+}
+
+func newTestProvider(t *testing.T, standIn *standInProvider) *Provider {
+	t.Helper()
+	provider, err := NewProvider(context.Background(), Config{
+		Issuer:       standIn.server.URL,
+		ClientID:     "chirpy",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/api/oidc/callback",
+		Scopes:       []string{"email"},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	return provider
+}
+
+func TestExchangeAndVerify(t *testing.T) {
+	standIn := newStandInProvider(t)
+	provider := newTestProvider(t, standIn)
+
+	authURL, err := url.Parse(provider.AuthCodeURL("state-1", "nonce-1", "challenge"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() returned invalid URL: %v", err)
+	}
+	if got := authURL.Query().Get("code_challenge_method"); got != "S256" {
+		t.Errorf("AuthCodeURL() code_challenge_method = %q, want S256", got)
+	}
+
+	standIn.idToken = standIn.sign("key-1", standIn.claims("nonce-1"))
+	rawIDToken, err := provider.Exchange(context.Background(), "code-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if got := standIn.form.Get("code_verifier"); got != "verifier-1" {
+		t.Errorf("Exchange() sent code_verifier = %q, want %q", got, "verifier-1")
+	}
+
+	claims, err := provider.VerifyIDToken(context.Background(), rawIDToken, "nonce-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	want := Claims{Issuer: standIn.server.URL, Subject: "staff-42", Email: "staff@example.com", EmailVerified: true}
+	if claims != want {
+		t.Errorf("VerifyIDToken() = %+v, want %+v", claims, want)
+	}
+}
+
+func TestVerifyIDTokenErrors(t *testing.T) {
+	standIn := newStandInProvider(t)
+	provider := newTestProvider(t, standIn)
+
+	tests := []struct {
+		name    string
+		modify  func(jwt.MapClaims)
+		nonce   string
+		wantErr error
+	}{
+		{name: "Wrong nonce", modify: func(jwt.MapClaims) {}, nonce: "other", wantErr: ErrNonceMismatch},
+		{name: "Wrong audience", modify: func(c jwt.MapClaims) { c["aud"] = "someone-else" }, nonce: "nonce-1", wantErr: jwt.ErrTokenInvalidAudience},
+		{name: "Wrong issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }, nonce: "nonce-1", wantErr: jwt.ErrTokenInvalidIssuer},
+		{name: "Expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, nonce: "nonce-1", wantErr: jwt.ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := standIn.claims("nonce-1")
+			tt.modify(claims)
+			_, err := provider.VerifyIDToken(context.Background(), standIn.sign("key-1", claims), tt.nonce)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyIDToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenKeyRotation(t *testing.T) {
+	standIn := newStandInProvider(t)
+	provider := newTestProvider(t, standIn)
+
+	// Populate the key cache, then rotate to a key the provider hasn't seen.
+	_, err := provider.VerifyIDToken(context.Background(), standIn.sign("key-1", standIn.claims("n")), "n")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	standIn.addKey("key-2")
+	provider.fetchedAt = time.Now().Add(-2 * jwksRefreshInterval)
+
+	_, err = provider.VerifyIDToken(context.Background(), standIn.sign("key-2", standIn.claims("n")), "n")
+	if err != nil {
+		t.Errorf("VerifyIDToken() with rotated key error = %v", err)
+	}
+}
@@ -0,0 +1,756 @@
+package openapi
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxCallDepth bounds how deep the analysis follows helpers that are
+// handed the response writer or the request.
+const maxCallDepth = 4
+
+const (
+	securityBearer    = "bearerAuth"
+	securityWebhook   = "webhookSignature"
+	securityBasic     = "clientCredentials"
+	securitySignature = "httpSignature"
+)
+
+// responders are the helpers that write JSON responses, with the
+// positions of their arguments. contentType is -1 for helpers that always
+// write application/json.
+var responders = map[string]struct {
+	status, contentType, payload int
+	isError                      bool
+}{
+	"respondWithJSON":         {status: 1, contentType: -1, payload: 2},
+	"respondWithError":        {status: 1, contentType: -1, isError: true},
+	"respondWithActivityJSON": {status: 1, contentType: 2, payload: 3},
+}
+
+type generator struct {
+	pkgs    map[string]*pkg
+	schemas map[string]*Schema
+	opIDs   map[string]bool
+}
+
+// scope is a function being analyzed. Its parameters may be bound to the
+// arguments of the call that led to it, so a helper's status codes and
+// payloads resolve to what the handler passed in.
+type scope struct {
+	pkg      *pkg
+	fn       *ast.FuncDecl
+	params   map[string]ast.Expr
+	bindings map[string]binding
+	locals   map[string]*ast.TypeSpec
+	writers  map[string]bool
+	requests map[string]bool
+}
+
+type binding struct {
+	expr ast.Expr
+	sc   *scope
+}
+
+// typeRef is a type expression and the scope it's written in.
+type typeRef struct {
+	expr ast.Expr
+	sc   *scope
+}
+
+func newScope(p *pkg, fn *ast.FuncDecl, bindings map[string]binding) *scope {
+	sc := &scope{
+		pkg:      p,
+		fn:       fn,
+		params:   map[string]ast.Expr{},
+		bindings: bindings,
+		locals:   map[string]*ast.TypeSpec{},
+		writers:  map[string]bool{},
+		requests: map[string]bool{},
+	}
+	if fn == nil {
+		return sc
+	}
+	fields := fn.Type.Params.List
+	if fn.Recv != nil {
+		fields = append(append([]*ast.Field{}, fn.Recv.List...), fields...)
+	}
+	for _, field := range fields {
+		for _, name := range field.Names {
+			sc.params[name.Name] = field.Type
+			switch exprString(field.Type) {
+			case "http.ResponseWriter":
+				sc.writers[name.Name] = true
+			case "*http.Request":
+				sc.requests[name.Name] = true
+			}
+		}
+	}
+	if fn.Body != nil {
+		ast.Inspect(fn.Body, func(node ast.Node) bool {
+			if spec, ok := node.(*ast.TypeSpec); ok {
+				sc.locals[spec.Name.Name] = spec
+			}
+			return true
+		})
+	}
+	return sc
+}
+
+// pkgScope is the scope of a package's top level declarations.
+func pkgScope(p *pkg) *scope {
+	return newScope(p, nil, nil)
+}
+
+// analysis collects what a handler, and the helpers it calls, read from
+// the request and write to the response.
+type analysis struct {
+	request        *typeRef
+	query          []string
+	form           []string
+	uuidPathParams map[string]bool
+	security       map[string]bool
+	optionalBearer bool
+	responses      map[int]*responseInfo
+	stack          map[*ast.FuncDecl]bool
+}
+
+type responseInfo struct {
+	content  map[string][]*Schema
+	location bool
+}
+
+func newAnalysis() *analysis {
+	return &analysis{
+		uuidPathParams: map[string]bool{},
+		security:       map[string]bool{},
+		responses:      map[int]*responseInfo{},
+		stack:          map[*ast.FuncDecl]bool{},
+	}
+}
+
+func (a *analysis) respond(status int, contentType string, schema *Schema) {
+	info := a.responses[status]
+	if info == nil {
+		info = &responseInfo{content: map[string][]*Schema{}}
+		a.responses[status] = info
+	}
+	if contentType == "" {
+		return
+	}
+	if schema == nil {
+		if _, ok := info.content[contentType]; !ok {
+			info.content[contentType] = nil
+		}
+		return
+	}
+	info.content[contentType] = append(info.content[contentType], schema)
+}
+
+func (info *responseInfo) response(description string) *Response {
+	resp := &Response{Description: description}
+	if info.location {
+		resp.Headers = map[string]Header{
+			"Location": {Description: "Where the client is redirected.", Schema: &Schema{Type: "string", Format: "uri"}},
+		}
+	}
+	for contentType, schemas := range info.content {
+		if resp.Content == nil {
+			resp.Content = map[string]MediaType{}
+		}
+		schemas = uniqueSchemas(schemas)
+		switch len(schemas) {
+		case 0:
+			resp.Content[contentType] = MediaType{}
+		case 1:
+			resp.Content[contentType] = MediaType{Schema: schemas[0]}
+		default:
+			resp.Content[contentType] = MediaType{Schema: &Schema{OneOf: schemas}}
+		}
+	}
+	return resp
+}
+
+func uniqueSchemas(schemas []*Schema) []*Schema {
+	seen := map[string]bool{}
+	out := []*Schema{}
+	for _, schema := range schemas {
+		key, _ := json.Marshal(schema)
+		if !seen[string(key)] {
+			seen[string(key)] = true
+			out = append(out, schema)
+		}
+	}
+	return out
+}
+
+func (a *analysis) securityRequirements() []SecurityRequirement {
+	requirements := []SecurityRequirement{}
+	if a.optionalBearer && !a.security[securityBearer] {
+		requirements = append(requirements, SecurityRequirement{}, SecurityRequirement{securityBearer: {}})
+	}
+	for _, name := range []string{securityBearer, securityWebhook, securityBasic, securitySignature} {
+		if a.security[name] {
+			requirements = append(requirements, SecurityRequirement{name: {}})
+		}
+	}
+	if len(requirements) == 0 {
+		return nil
+	}
+	return requirements
+}
+
+// writes tracks what a single function writes to the response writer
+// directly, rather than through a responder.
+type writes struct {
+	statuses     []int
+	contentTypes []string
+	body         bool
+	serveContent bool
+	bearer       bool
+	authOptional bool
+}
+
+// analyze walks the body of the function in sc, following calls that are
+// passed the response writer or the request.
+func (g *generator) analyze(sc *scope, a *analysis, depth int) {
+	if sc.fn.Body == nil || a.stack[sc.fn] {
+		return
+	}
+	a.stack[sc.fn] = true
+	defer delete(a.stack, sc.fn)
+
+	w := &writes{}
+	unreachable := g.unreachableCases(sc)
+	ast.Inspect(sc.fn.Body, func(node ast.Node) bool {
+		if unreachable[node] {
+			return false
+		}
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		g.analyzeCall(sc, a, w, call, depth)
+		return true
+	})
+
+	if w.bearer {
+		if w.authOptional {
+			a.optionalBearer = true
+		} else {
+			a.security[securityBearer] = true
+		}
+	}
+	if w.serveContent {
+		w.statuses = append(w.statuses, http.StatusOK)
+		a.respond(http.StatusNotModified, "", nil)
+	}
+	for _, status := range w.statuses {
+		if !w.body && !w.serveContent {
+			a.respond(status, "", nil)
+			continue
+		}
+		contentTypes := w.contentTypes
+		if len(contentTypes) == 0 {
+			contentTypes = []string{"text/plain"}
+		}
+		for _, contentType := range contentTypes {
+			a.respond(status, contentType, nil)
+		}
+	}
+}
+
+func (g *generator) analyzeCall(sc *scope, a *analysis, w *writes, call *ast.CallExpr, depth int) {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		if responder, ok := responders[fun.Name]; ok {
+			g.analyzeResponder(sc, a, call, responder.status, responder.contentType, responder.payload, responder.isError)
+			return
+		}
+		if fn := sc.pkg.funcs[fun.Name]; fn != nil {
+			g.follow(sc, a, call, sc.pkg, fn, depth)
+		}
+		return
+	case *ast.SelectorExpr:
+		g.analyzeSelectorCall(sc, a, w, call, fun, depth)
+	}
+}
+
+func (g *generator) analyzeSelectorCall(sc *scope, a *analysis, w *writes, call *ast.CallExpr, fun *ast.SelectorExpr, depth int) {
+	receiver := exprString(fun.X)
+	method := fun.Sel.Name
+	switch {
+	case receiver == "auth" && method == "GetBearerToken":
+		w.bearer = true
+	case method == "VerifyWebhook":
+		a.security[securityWebhook] = true
+	case receiver == "activitypub" && method == "ParseSignature":
+		a.security[securitySignature] = true
+	case receiver == "http" && method == "Redirect" && len(call.Args) == 4:
+		status := g.resolveStatus(call.Args[3], sc)
+		a.respond(status, "", nil)
+		a.responses[status].location = true
+	case receiver == "http" && method == "ServeContent":
+		w.serveContent = true
+	case receiver == "uuid" && method == "Parse" && len(call.Args) == 1:
+		if name, ok := g.requestCall(sc, call.Args[0], "PathValue"); ok {
+			a.uuidPathParams[name] = true
+		}
+	case (receiver == "io" && method == "Copy") || (receiver == "fmt" && strings.HasPrefix(method, "Fprint")):
+		if len(call.Args) > 0 && sc.writers[exprString(call.Args[0])] {
+			w.body = true
+		}
+	case method == "Execute" && len(call.Args) > 0 && sc.writers[exprString(call.Args[0])]:
+		w.body = true
+	case sc.writers[receiver] && method == "WriteHeader" && len(call.Args) == 1:
+		w.statuses = append(w.statuses, g.resolveStatus(call.Args[0], sc))
+	case sc.writers[receiver] && method == "Write":
+		w.body = true
+	case (method == "Set" || method == "Add") && sc.writers[strings.TrimSuffix(receiver, ".Header()")] && len(call.Args) == 2:
+		if name, ok := g.stringValue(call.Args[0], sc); ok && http.CanonicalHeaderKey(name) == "Content-Type" {
+			for _, contentType := range g.stringValues(call.Args[1], sc) {
+				contentType, _, _ = strings.Cut(contentType, ";")
+				w.contentTypes = append(w.contentTypes, contentType)
+			}
+		}
+	case method == "Decode" && len(call.Args) == 1:
+		if g.readsRequestBody(fun.X, sc) {
+			a.request = g.resolveType(call.Args[0], sc)
+		}
+	case receiver == "json" && method == "Unmarshal" && len(call.Args) == 2:
+		if g.readsRequestBody(call.Args[0], sc) {
+			a.request = g.resolveType(call.Args[1], sc)
+		}
+	case method == "Get" && len(call.Args) == 1:
+		name, ok := g.stringValue(call.Args[0], sc)
+		if !ok {
+			return
+		}
+		switch {
+		case g.isRequestExpr(fun.X, sc, "Header"):
+			if name == "Authorization" {
+				w.authOptional = true
+			}
+		case g.isQuery(fun.X, sc):
+			a.query = append(a.query, name)
+		case g.isRequestExpr(fun.X, sc, "Form"):
+			a.form = append(a.form, name)
+		}
+	case sc.requests[receiver] && (method == "PostFormValue" || method == "FormValue") && len(call.Args) == 1:
+		if name, ok := g.stringValue(call.Args[0], sc); ok {
+			a.form = append(a.form, name)
+		}
+	case sc.requests[receiver] && method == "BasicAuth":
+		a.security[securityBasic] = true
+	default:
+		if fn, p := g.method(sc, fun); fn != nil {
+			g.follow(sc, a, call, p, fn, depth)
+		}
+	}
+}
+
+// analyzeResponder records the response a responder call writes.
+func (g *generator) analyzeResponder(sc *scope, a *analysis, call *ast.CallExpr, statusArg, contentTypeArg, payloadArg int, isError bool) {
+	if len(call.Args) <= statusArg {
+		return
+	}
+	status := g.resolveStatus(call.Args[statusArg], sc)
+	if isError {
+		a.respond(status, "application/json", &Schema{Ref: "#/components/schemas/Error"})
+		return
+	}
+
+	contentType := "application/json"
+	if contentTypeArg >= 0 {
+		if value, ok := g.stringValue(call.Args[contentTypeArg], sc); ok {
+			contentType, _, _ = strings.Cut(value, ";")
+		}
+	}
+	ref := g.resolveType(call.Args[payloadArg], sc)
+	if ref == nil {
+		a.respond(status, "", nil)
+		return
+	}
+	a.respond(status, contentType, g.schemaOf(ref.expr, ref.sc))
+}
+
+// follow analyzes a function called from sc when it's handed the response
+// writer or the request, binding its parameters to the call's arguments.
+func (g *generator) follow(sc *scope, a *analysis, call *ast.CallExpr, p *pkg, fn *ast.FuncDecl, depth int) {
+	if depth >= maxCallDepth || p.name != handlerPackage {
+		return
+	}
+	passesHTTP := false
+	for _, arg := range call.Args {
+		name := exprString(arg)
+		if sc.writers[name] || sc.requests[name] {
+			passesHTTP = true
+		}
+	}
+	if !passesHTTP {
+		return
+	}
+
+	bindings := map[string]binding{}
+	i := 0
+	for _, field := range fn.Type.Params.List {
+		for _, name := range field.Names {
+			if i < len(call.Args) {
+				bindings[name.Name] = binding{expr: call.Args[i], sc: sc}
+			}
+			i++
+		}
+	}
+	g.analyze(newScope(p, fn, bindings), a, depth+1)
+}
+
+// method returns the function or method a selector calls, and the package
+// it's declared in.
+func (g *generator) method(sc *scope, fun *ast.SelectorExpr) (*ast.FuncDecl, *pkg) {
+	if ident, ok := fun.X.(*ast.Ident); ok && sc.params[ident.Name] == nil && !g.isLocal(ident.Name, sc) {
+		if p := g.pkgs[ident.Name]; p != nil {
+			return p.funcs[fun.Sel.Name], p
+		}
+	}
+	ref := g.resolveType(fun.X, sc)
+	if ref == nil {
+		return nil, nil
+	}
+	p, name := g.namedType(ref)
+	if p == nil {
+		return nil, nil
+	}
+	return p.methods[name][fun.Sel.Name], p
+}
+
+// isLocal reports whether name is declared inside the function in sc.
+func (g *generator) isLocal(name string, sc *scope) bool {
+	return sc.fn != nil && g.definition(name, sc) != nil
+}
+
+// readsRequestBody reports whether expr is derived from the request body,
+// like a decoder reading it or the bytes read from it.
+func (g *generator) readsRequestBody(expr ast.Expr, sc *scope) bool {
+	found := false
+	var visit func(expr ast.Expr, sc *scope, depth int)
+	visit = func(expr ast.Expr, sc *scope, depth int) {
+		if depth > 3 || expr == nil {
+			return
+		}
+		ast.Inspect(expr, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.SelectorExpr:
+				if node.Sel.Name == "Body" && sc.requests[exprString(node.X)] {
+					found = true
+				}
+			case *ast.Ident:
+				if def := g.definition(node.Name, sc); def != nil {
+					visit(def.expr, sc, depth+1)
+				}
+			}
+			return !found
+		})
+	}
+	visit(expr, sc, 0)
+	return found
+}
+
+// requestCall reports whether expr calls method on the request with a
+// constant string argument, and returns the argument.
+func (g *generator) requestCall(sc *scope, expr ast.Expr, method string) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return "", false
+	}
+	fun, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || fun.Sel.Name != method || !sc.requests[exprString(fun.X)] {
+		return "", false
+	}
+	return g.stringValue(call.Args[0], sc)
+}
+
+// isRequestExpr reports whether expr is the request's field, like r.Form.
+func (g *generator) isRequestExpr(expr ast.Expr, sc *scope, field string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == field && sc.requests[exprString(sel.X)]
+}
+
+// isQuery reports whether expr is the request's query, either r.URL.Query()
+// or a variable holding it.
+func (g *generator) isQuery(expr ast.Expr, sc *scope) bool {
+	if ident, ok := expr.(*ast.Ident); ok {
+		def := g.definition(ident.Name, sc)
+		return def != nil && g.isQuery(def.expr, sc)
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	fun, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || fun.Sel.Name != "Query" {
+		return false
+	}
+	url, ok := fun.X.(*ast.SelectorExpr)
+	return ok && url.Sel.Name == "URL" && sc.requests[exprString(url.X)]
+}
+
+// resolveStatus evaluates a status code argument, returning 0 when it
+// isn't a constant.
+func (g *generator) resolveStatus(expr ast.Expr, sc *scope) int {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		status, _ := strconv.Atoi(expr.Value)
+		return status
+	case *ast.SelectorExpr:
+		if exprString(expr.X) == "http" {
+			return statusCodes[expr.Sel.Name]
+		}
+	case *ast.Ident:
+		if b, ok := sc.bindings[expr.Name]; ok {
+			return g.resolveStatus(b.expr, b.sc)
+		}
+	}
+	return 0
+}
+
+// stringValue evaluates a constant string expression.
+func (g *generator) stringValue(expr ast.Expr, sc *scope) (string, bool) {
+	values := g.stringValues(expr, sc)
+	if len(values) != 1 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// stringValues returns the string constants expr may hold: a literal, a
+// constant, or the literals a variable is assigned.
+func (g *generator) stringValues(expr ast.Expr, sc *scope) []string {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		if expr.Kind == token.STRING {
+			value, err := strconv.Unquote(expr.Value)
+			if err == nil {
+				return []string{value}
+			}
+		}
+	case *ast.SelectorExpr:
+		if p := g.pkgs[exprString(expr.X)]; p != nil && p.consts[expr.Sel.Name] != nil {
+			return g.stringValues(p.consts[expr.Sel.Name], pkgScope(p))
+		}
+	case *ast.Ident:
+		if b, ok := sc.bindings[expr.Name]; ok {
+			return g.stringValues(b.expr, b.sc)
+		}
+		if value := sc.pkg.consts[expr.Name]; value != nil && !g.isLocal(expr.Name, sc) {
+			return g.stringValues(value, pkgScope(sc.pkg))
+		}
+		values := []string{}
+		for _, assigned := range g.assignments(expr.Name, sc) {
+			values = append(values, g.stringValues(assigned, sc)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// definition is where a local variable gets its value: either an
+// expression, or a result of a call assigned to several variables.
+type definition struct {
+	expr   ast.Expr
+	result int
+	typ    ast.Expr
+}
+
+// definition finds the first declaration of the local variable name in
+// the function in sc.
+func (g *generator) definition(name string, sc *scope) *definition {
+	if sc.fn == nil || sc.fn.Body == nil {
+		return nil
+	}
+	var found *definition
+	ast.Inspect(sc.fn.Body, func(node ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			if node.Tok != token.DEFINE {
+				return true
+			}
+			for i, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); !ok || ident.Name != name {
+					continue
+				}
+				if len(node.Rhs) == len(node.Lhs) {
+					found = &definition{expr: node.Rhs[i]}
+				} else {
+					found = &definition{expr: node.Rhs[0], result: i}
+				}
+			}
+		case *ast.ValueSpec:
+			for i, ident := range node.Names {
+				if ident.Name != name {
+					continue
+				}
+				found = &definition{typ: node.Type}
+				if i < len(node.Values) {
+					found.expr = node.Values[i]
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// assignments returns every expression assigned to the local variable
+// name.
+func (g *generator) assignments(name string, sc *scope) []ast.Expr {
+	if sc.fn == nil || sc.fn.Body == nil {
+		return nil
+	}
+	exprs := []ast.Expr{}
+	unreachable := g.unreachableCases(sc)
+	ast.Inspect(sc.fn.Body, func(node ast.Node) bool {
+		if unreachable[node] {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			if len(node.Lhs) != len(node.Rhs) {
+				return true
+			}
+			for i, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name == name {
+					exprs = append(exprs, node.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, ident := range node.Names {
+				if ident.Name == name && i < len(node.Values) {
+					exprs = append(exprs, node.Values[i])
+				}
+			}
+		}
+		return true
+	})
+	return exprs
+}
+
+// unreachableCases returns the cases of switches on a parameter that
+// can't run, because the caller passed a constant the case doesn't list.
+func (g *generator) unreachableCases(sc *scope) map[ast.Node]bool {
+	unreachable := map[ast.Node]bool{}
+	ast.Inspect(sc.fn.Body, func(node ast.Node) bool {
+		stmt, ok := node.(*ast.SwitchStmt)
+		if !ok {
+			return true
+		}
+		tag, ok := stmt.Tag.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		b, ok := sc.bindings[tag.Name]
+		if !ok {
+			return true
+		}
+		value := g.constantName(b.expr, b.sc)
+		if value == "" {
+			return true
+		}
+		for _, clause := range stmt.Body.List {
+			clause := clause.(*ast.CaseClause)
+			if clause.List == nil {
+				continue
+			}
+			matches := false
+			for _, expr := range clause.List {
+				if exprString(expr) == value {
+					matches = true
+				}
+			}
+			if !matches {
+				unreachable[clause] = true
+			}
+		}
+		return true
+	})
+	return unreachable
+}
+
+// constantName returns the name of the constant expr evaluates to, or ""
+// when it isn't one.
+func (g *generator) constantName(expr ast.Expr, sc *scope) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if b, ok := sc.bindings[expr.Name]; ok {
+			return g.constantName(b.expr, b.sc)
+		}
+		if sc.pkg.consts[expr.Name] != nil {
+			return expr.Name
+		}
+	case *ast.SelectorExpr:
+		if p := g.pkgs[exprString(expr.X)]; p != nil && p.consts[expr.Sel.Name] != nil {
+			return exprString(expr)
+		}
+	}
+	return ""
+}
+
+// exprString renders simple expressions like r.URL or http.Request.
+func exprString(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		return exprString(expr.X) + "." + expr.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(expr.X)
+	case *ast.CallExpr:
+		if len(expr.Args) == 0 {
+			return exprString(expr.Fun) + "()"
+		}
+	}
+	return ""
+}
+
+var statusCodes = map[string]int{
+	"StatusContinue":                      http.StatusContinue,
+	"StatusOK":                            http.StatusOK,
+	"StatusCreated":                       http.StatusCreated,
+	"StatusAccepted":                      http.StatusAccepted,
+	"StatusNoContent":                     http.StatusNoContent,
+	"StatusPartialContent":                http.StatusPartialContent,
+	"StatusMovedPermanently":              http.StatusMovedPermanently,
+	"StatusFound":                         http.StatusFound,
+	"StatusSeeOther":                      http.StatusSeeOther,
+	"StatusNotModified":                   http.StatusNotModified,
+	"StatusTemporaryRedirect":             http.StatusTemporaryRedirect,
+	"StatusPermanentRedirect":             http.StatusPermanentRedirect,
+	"StatusBadRequest":                    http.StatusBadRequest,
+	"StatusUnauthorized":                  http.StatusUnauthorized,
+	"StatusPaymentRequired":               http.StatusPaymentRequired,
+	"StatusForbidden":                     http.StatusForbidden,
+	"StatusNotFound":                      http.StatusNotFound,
+	"StatusMethodNotAllowed":              http.StatusMethodNotAllowed,
+	"StatusNotAcceptable":                 http.StatusNotAcceptable,
+	"StatusConflict":                      http.StatusConflict,
+	"StatusGone":                          http.StatusGone,
+	"StatusPreconditionFailed":            http.StatusPreconditionFailed,
+	"StatusRequestEntityTooLarge":         http.StatusRequestEntityTooLarge,
+	"StatusUnsupportedMediaType":          http.StatusUnsupportedMediaType,
+	"StatusUnprocessableEntity":           http.StatusUnprocessableEntity,
+	"StatusPreconditionRequired":          http.StatusPreconditionRequired,
+	"StatusTooManyRequests":               http.StatusTooManyRequests,
+	"StatusRequestHeaderFieldsTooLarge":   http.StatusRequestHeaderFieldsTooLarge,
+	"StatusInternalServerError":           http.StatusInternalServerError,
+	"StatusNotImplemented":                http.StatusNotImplemented,
+	"StatusBadGateway":                    http.StatusBadGateway,
+	"StatusServiceUnavailable":            http.StatusServiceUnavailable,
+	"StatusGatewayTimeout":                http.StatusGatewayTimeout,
+	"StatusNetworkAuthenticationRequired": http.StatusNetworkAuthenticationRequired,
+}
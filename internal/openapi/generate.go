@@ -0,0 +1,466 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// handlerPackage is the package whose functions handle the routes.
+const handlerPackage = "config"
+
+// Route is a route registered in main.go.
+type Route struct {
+	Method string
+	// Path is the route's pattern in OpenAPI form, with wildcards like
+	// {id...} written as {id}.
+	Path string
+	// Handler is the name of the function in internal/config handling the
+	// route, or "" when the handler is built in main.go.
+	Handler string
+}
+
+// ParseRoutes returns the routes main.go registers with mux.HandleFunc and
+// mux.Handle, in the order they're registered.
+func ParseRoutes(mainFile string) ([]Route, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), mainFile, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := []Route{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		fun, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (fun.Sel.Name != "HandleFunc" && fun.Sel.Name != "Handle") {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		pattern, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return true
+		}
+
+		route := Route{Method: http.MethodGet, Path: pattern}
+		if method, path, ok := strings.Cut(pattern, " "); ok {
+			route.Method, route.Path = method, path
+		}
+		route.Path = strings.ReplaceAll(route.Path, "...}", "}")
+		if len(route.Path) > 1 && strings.HasSuffix(route.Path, "/") {
+			// Patterns ending in a slash match everything below them.
+			route.Path += "{path}"
+		}
+		if handler, ok := call.Args[1].(*ast.SelectorExpr); ok {
+			route.Handler = handler.Sel.Name
+		}
+		routes = append(routes, route)
+		return true
+	})
+	return routes, nil
+}
+
+// Generate describes the server in the module at root: the routes
+// registered in root/main.go, handled by the functions in
+// root/internal/config.
+func Generate(root string) (*Document, error) {
+	routes, err := ParseRoutes(filepath.Join(root, "main.go"))
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := loadPackages(filepath.Join(root, "internal"))
+	if err != nil {
+		return nil, err
+	}
+	if pkgs[handlerPackage] == nil {
+		return nil, fmt.Errorf("openapi: no %s package in %s", handlerPackage, root)
+	}
+
+	g := &generator{
+		pkgs:    pkgs,
+		schemas: map[string]*Schema{"Error": errorSchema()},
+		opIDs:   map[string]bool{},
+	}
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info: Info{
+			Title:       "Chirpy API",
+			Version:     "1.0.0",
+			Description: "Generated from the routes in main.go and the handlers in internal/config.",
+		},
+		Paths: map[string]PathItem{},
+		Components: Components{
+			Schemas:         g.schemas,
+			SecuritySchemes: securitySchemes(),
+		},
+	}
+	for _, route := range routes {
+		op, err := g.operation(route)
+		if err != nil {
+			return nil, err
+		}
+		if doc.Paths[route.Path] == nil {
+			doc.Paths[route.Path] = PathItem{}
+		}
+		doc.Paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+	return doc, nil
+}
+
+// requestSchema makes the properties of a request body that accept null
+// optional, since decoding leaves a missing field nil too.
+func requestSchema(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return schema
+	}
+	required := []string{}
+	for _, name := range schema.Required {
+		if !acceptsNull(schema.Properties[name]) {
+			required = append(required, name)
+		}
+	}
+	schema.Required = required
+	return schema
+}
+
+func acceptsNull(schema *Schema) bool {
+	if types, ok := schema.Type.([]string); ok {
+		return slices.Contains(types, "null")
+	}
+	for _, alternative := range schema.AnyOf {
+		if alternative.Type == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+func errorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error": {Type: "string"},
+		},
+		Required: []string{"error"},
+	}
+}
+
+func securitySchemes() map[string]SecurityScheme {
+	return map[string]SecurityScheme{
+		securityBearer: {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "A session JWT, a personal access token or an OAuth access token.",
+		},
+		securityWebhook: {
+			Type:        "apiKey",
+			In:          "header",
+			Name:        "Polka-Signature",
+			Description: "The payment provider's signature of the webhook delivery.",
+		},
+		securityBasic: {
+			Type:        "http",
+			Scheme:      "basic",
+			Description: "OAuth client credentials.",
+		},
+		securitySignature: {
+			Type:        "apiKey",
+			In:          "header",
+			Name:        "Signature",
+			Description: "An HTTP Signature by the sending ActivityPub actor.",
+		},
+	}
+}
+
+// operation describes the route's handler.
+func (g *generator) operation(route Route) (*Operation, error) {
+	config := g.pkgs[handlerPackage]
+	fn := config.funcs[route.Handler]
+	if fn == nil {
+		fn = config.methods["ApiConfig"][route.Handler]
+	}
+	if route.Handler == "" {
+		return g.staticOperation(route), nil
+	}
+	if fn == nil {
+		return nil, fmt.Errorf("openapi: %s %s is handled by %s, which isn't in internal/config", route.Method, route.Path, route.Handler)
+	}
+
+	a := newAnalysis()
+	g.analyze(newScope(config, fn, nil), a, 0)
+
+	op := &Operation{
+		OperationID: g.operationID(strings.TrimSuffix(route.Handler, "Handler")),
+		Tags:        []string{strings.ReplaceAll(strings.TrimSuffix(config.fileOf[fn], "_handler.go"), "_", " ")},
+		Responses:   map[string]*Response{},
+	}
+	op.Summary, op.Description = describe(fn)
+	op.Security = a.securityRequirements()
+
+	for _, name := range pathParams(route.Path) {
+		schema := &Schema{Type: "string"}
+		if a.uuidPathParams[name] {
+			schema.Format = "uuid"
+		}
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	query := a.query
+	if route.Method == http.MethodGet {
+		query = append(query, a.form...)
+	} else if len(a.form) > 0 {
+		form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for _, name := range a.form {
+			form.Properties[name] = &Schema{Type: "string"}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/x-www-form-urlencoded": {Schema: form}},
+		}
+	}
+	for _, name := range unique(query) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+	}
+	if a.request != nil && op.RequestBody == nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: requestSchema(g.schemaOf(a.request.expr, a.request.sc))}},
+		}
+	}
+
+	statuses := make([]int, 0, len(a.responses))
+	for status := range a.responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		key, description := "default", "Unexpected error"
+		if status != 0 {
+			key, description = strconv.Itoa(status), http.StatusText(status)
+		}
+		op.Responses[key] = a.responses[status].response(description)
+	}
+	return op, nil
+}
+
+// staticOperation describes a route whose handler main.go builds itself,
+// which is how it serves the web app's files.
+func (g *generator) staticOperation(route Route) *Operation {
+	op := &Operation{
+		OperationID: g.operationID("StaticFiles"),
+		Summary:     "Serve the web app's static files",
+		Tags:        []string{"app"},
+		Responses: map[string]*Response{
+			"200": {Description: "The requested file", Content: map[string]MediaType{"*/*": {}}},
+			"404": {Description: http.StatusText(http.StatusNotFound)},
+		},
+	}
+	for _, name := range pathParams(route.Path) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	return op
+}
+
+// operationID returns name, made unique among the document's operations
+// for handlers registered on more than one route.
+func (g *generator) operationID(name string) string {
+	id := name
+	for i := 2; g.opIDs[id]; i++ {
+		id = name + strconv.Itoa(i)
+	}
+	g.opIDs[id] = true
+	return id
+}
+
+func pathParams(path string) []string {
+	params := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, segment[1:len(segment)-1])
+		}
+	}
+	return params
+}
+
+// describe turns a handler's doc comment into a summary and a
+// description. Undocumented handlers are summarized from their name.
+func describe(fn *ast.FuncDecl) (string, string) {
+	doc := strings.TrimSpace(fn.Doc.Text())
+	if doc == "" {
+		return humanize(strings.TrimSuffix(fn.Name.Name, "Handler")), ""
+	}
+	if rest, ok := strings.CutPrefix(doc, fn.Name.Name+" "); ok {
+		doc = capitalize(rest)
+	}
+	doc = strings.Join(strings.Fields(doc), " ")
+
+	summary := doc
+	if end := strings.Index(doc, ". "); end >= 0 {
+		summary = doc[:end]
+	}
+	summary = strings.TrimSuffix(summary, ".")
+	if summary == strings.TrimSuffix(doc, ".") {
+		return summary, ""
+	}
+	return summary, doc
+}
+
+// humanize turns an identifier like ListOAuthClients into "List OAuth
+// clients".
+func humanize(name string) string {
+	words := []string{}
+	runes := []rune(name)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+		acronymEnd := unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if lowerToUpper || acronymEnd {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+
+	for i, word := range words {
+		if i > 0 && strings.ToUpper(word) != word {
+			words[i] = strings.ToLower(word)
+		}
+	}
+	return strings.ReplaceAll(strings.Join(words, " "), "O auth", "OAuth")
+}
+
+func capitalize(s string) string {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return s
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func unique(values []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// pkg indexes the declarations of one package.
+type pkg struct {
+	name   string
+	types  map[string]*ast.TypeSpec
+	funcs  map[string]*ast.FuncDecl
+	consts map[string]ast.Expr
+	// methods maps receiver type names to their methods.
+	methods map[string]map[string]*ast.FuncDecl
+	fileOf  map[*ast.FuncDecl]string
+}
+
+// loadPackages parses the packages in the directories under dir, leaving
+// out tests.
+func loadPackages(dir string) (map[string]*pkg, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	pkgs := map[string]*pkg{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(dir, entry.Name(), "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		for _, filename := range files {
+			if strings.HasSuffix(filename, "_test.go") {
+				continue
+			}
+			file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+			if err != nil {
+				return nil, err
+			}
+			p := pkgs[file.Name.Name]
+			if p == nil {
+				p = &pkg{
+					name:    file.Name.Name,
+					types:   map[string]*ast.TypeSpec{},
+					funcs:   map[string]*ast.FuncDecl{},
+					consts:  map[string]ast.Expr{},
+					methods: map[string]map[string]*ast.FuncDecl{},
+					fileOf:  map[*ast.FuncDecl]string{},
+				}
+				pkgs[p.name] = p
+			}
+			p.add(file, filepath.Base(filename))
+		}
+	}
+	return pkgs, nil
+}
+
+func (p *pkg) add(file *ast.File, filename string) {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			p.fileOf[decl] = filename
+			if decl.Recv == nil {
+				p.funcs[decl.Name.Name] = decl
+				continue
+			}
+			receiver := typeName(decl.Recv.List[0].Type)
+			if p.methods[receiver] == nil {
+				p.methods[receiver] = map[string]*ast.FuncDecl{}
+			}
+			p.methods[receiver][decl.Name.Name] = decl
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					p.types[spec.Name.Name] = spec
+				case *ast.ValueSpec:
+					if decl.Tok != token.CONST {
+						continue
+					}
+					for i, name := range spec.Names {
+						if i < len(spec.Values) {
+							p.consts[name.Name] = spec.Values[i]
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// typeName returns the name of a possibly pointer, named type.
+func typeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return typeName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	case *ast.IndexExpr:
+		return typeName(expr.X)
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+)
+
+// specFile is the document served at /api/openapi.json.
+const specFile = "../config/openapi.json"
+
+var update = flag.Bool("update", false, "rewrite "+specFile+" from the routes and handlers")
+
+// TestSpecUpToDate fails when a route, or the types a handler reads or
+// writes, changed without the spec being regenerated.
+func TestSpecUpToDate(t *testing.T) {
+	doc, err := Generate("../..")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	got, err := doc.JSON()
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	if *update {
+		err := os.WriteFile(specFile, got, 0644)
+		if err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := range max(len(gotLines), len(wantLines)) {
+		gotLine, wantLine := line(gotLines, i), line(wantLines, i)
+		if gotLine != wantLine {
+			t.Fatalf("%s is out of date at line %d:\n  generated: %s\n  checked in: %s\nRegenerate it with: go test ./internal/openapi -run TestSpecUpToDate -update", specFile, i+1, gotLine, wantLine)
+		}
+	}
+}
+
+func line(lines []string, i int) string {
+	if i < len(lines) {
+		return strings.TrimSpace(lines[i])
+	}
+	return "<end of file>"
+}
+
+// TestSpecCoversRoutes checks the checked in spec has an operation for
+// every route, and none for routes that are gone.
+func TestSpecCoversRoutes(t *testing.T) {
+	routes, err := ParseRoutes("../../main.go")
+	if err != nil {
+		t.Fatalf("ParseRoutes() error = %v", err)
+	}
+	data, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	doc := Document{}
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	registered := map[string]bool{}
+	for _, route := range routes {
+		method := strings.ToLower(route.Method)
+		registered[method+" "+route.Path] = true
+		if doc.Paths[route.Path][method] == nil {
+			t.Errorf("%s %s isn't in %s", route.Method, route.Path, specFile)
+		}
+	}
+	for path, item := range doc.Paths {
+		for method := range item {
+			if !registered[method+" "+path] {
+				t.Errorf("%s has %s %s, which main.go doesn't register", specFile, strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	doc, err := Generate("testdata/server")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	get := doc.Paths["/api/widgets/{widgetID}"]["get"]
+	if get == nil {
+		t.Fatalf("Paths = %v, want GET /api/widgets/{widgetID}", doc.Paths)
+	}
+	if get.OperationID != "GetWidget" {
+		t.Errorf("OperationID = %q, want GetWidget", get.OperationID)
+	}
+	if get.Summary != "Looks a widget up by its ID" {
+		t.Errorf("Summary = %q, want the first sentence of the doc comment", get.Summary)
+	}
+	if len(get.Parameters) != 2 || get.Parameters[0].Schema.Format != "uuid" || get.Parameters[1].Name != "details" {
+		t.Errorf("Parameters = %+v, want the widgetID uuid and the details query parameter", get.Parameters)
+	}
+	if get.Security != nil {
+		t.Errorf("Security = %v, want none", get.Security)
+	}
+	if ref := get.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/Widget" {
+		t.Errorf("200 schema = %q, want the Widget component", ref)
+	}
+	if ref := get.Responses["400"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/Error" {
+		t.Errorf("400 schema = %q, want the Error component", ref)
+	}
+
+	widget := doc.Components.Schemas["Widget"]
+	if widget == nil {
+		t.Fatalf("Components = %v, want Widget", doc.Components.Schemas)
+	}
+	if strings.Join(widget.Required, ",") != "id,name,parts" {
+		t.Errorf("Widget.Required = %v, want the fields without omitempty", widget.Required)
+	}
+	if _, ok := widget.Properties["secret"]; ok {
+		t.Error("Widget has the unexported secret field")
+	}
+	if widget.Properties["parts"].Items.Ref != "#/components/schemas/Widget" {
+		t.Errorf("parts = %+v, want a list of Widgets", widget.Properties["parts"])
+	}
+	retiredAt := widget.Properties["retired_at"]
+	if retiredAt.Description != "RetiredAt is when the widget stopped being sold." || retiredAt.Format != "date-time" {
+		t.Errorf("retired_at = %+v, want a nullable, documented date-time", retiredAt)
+	}
+
+	post := doc.Paths["/api/widgets"]["post"]
+	if len(post.Security) != 1 || post.Security[0][securityBearer] == nil {
+		t.Errorf("Security = %v, want the bearer token", post.Security)
+	}
+	body := post.RequestBody.Content["application/json"].Schema
+	if body.Properties["name"] == nil || body.Properties["name"].Type != "string" {
+		t.Errorf("request body = %+v, want the local parameters struct", body)
+	}
+	// The 201 is written by a helper the handler passes its status to.
+	if post.Responses["201"] == nil {
+		t.Errorf("Responses = %v, want the 201 written by the helper", post.Responses)
+	}
+
+	files := doc.Paths["/api/files/{path}"]["get"]
+	if files == nil || files.Responses["200"].Content["text/plain"].Schema != nil {
+		t.Errorf("Paths = %v, want GET /api/files/{path} answering with text", doc.Paths)
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "ListOAuthClients", want: "List OAuth clients"},
+		{name: "PublicRSSFeed", want: "Public RSS feed"},
+		{name: "Status", want: "Status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := humanize(tt.name); got != tt.want {
+				t.Errorf("humanize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
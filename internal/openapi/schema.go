@@ -0,0 +1,419 @@
+package openapi
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+// wellKnown are the schemas of types from outside the module.
+var wellKnown = map[string]func() *Schema{
+	"time.Time":       func() *Schema { return &Schema{Type: "string", Format: "date-time"} },
+	"time.Duration":   func() *Schema { return &Schema{Type: "integer"} },
+	"uuid.UUID":       func() *Schema { return &Schema{Type: "string", Format: "uuid"} },
+	"uuid.NullUUID":   func() *Schema { return &Schema{Type: []string{"string", "null"}, Format: "uuid"} },
+	"sql.NullString":  func() *Schema { return &Schema{Type: []string{"string", "null"}} },
+	"sql.NullTime":    func() *Schema { return &Schema{Type: []string{"string", "null"}, Format: "date-time"} },
+	"sql.NullInt32":   func() *Schema { return &Schema{Type: []string{"integer", "null"}, Format: "int32"} },
+	"sql.NullInt64":   func() *Schema { return &Schema{Type: []string{"integer", "null"}, Format: "int64"} },
+	"sql.NullFloat64": func() *Schema { return &Schema{Type: []string{"number", "null"}} },
+	"sql.NullBool":    func() *Schema { return &Schema{Type: []string{"boolean", "null"}} },
+	"json.RawMessage": func() *Schema { return &Schema{} },
+	"url.URL":         func() *Schema { return &Schema{Type: "string", Format: "uri"} },
+}
+
+// basicTypes are the schemas of Go's predeclared types.
+var basicTypes = map[string]func() *Schema{
+	"string":  func() *Schema { return &Schema{Type: "string"} },
+	"bool":    func() *Schema { return &Schema{Type: "boolean"} },
+	"int":     func() *Schema { return &Schema{Type: "integer"} },
+	"int8":    func() *Schema { return &Schema{Type: "integer"} },
+	"int16":   func() *Schema { return &Schema{Type: "integer"} },
+	"int32":   func() *Schema { return &Schema{Type: "integer", Format: "int32"} },
+	"int64":   func() *Schema { return &Schema{Type: "integer", Format: "int64"} },
+	"uint":    func() *Schema { return &Schema{Type: "integer"} },
+	"uint8":   func() *Schema { return &Schema{Type: "integer"} },
+	"uint16":  func() *Schema { return &Schema{Type: "integer"} },
+	"uint32":  func() *Schema { return &Schema{Type: "integer"} },
+	"uint64":  func() *Schema { return &Schema{Type: "integer"} },
+	"byte":    func() *Schema { return &Schema{Type: "integer"} },
+	"rune":    func() *Schema { return &Schema{Type: "integer"} },
+	"float32": func() *Schema { return &Schema{Type: "number"} },
+	"float64": func() *Schema { return &Schema{Type: "number"} },
+	"any":     func() *Schema { return &Schema{} },
+	"error":   func() *Schema { return &Schema{Type: "string"} },
+}
+
+// schemaOf returns the schema of the type expr, written in sc. Structs
+// declared at the top level of a package become components, everything
+// else is inlined.
+func (g *generator) schemaOf(expr ast.Expr, sc *scope) *Schema {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if spec := sc.locals[expr.Name]; spec != nil {
+			return g.schemaOf(spec.Type, sc)
+		}
+		if spec := sc.pkg.types[expr.Name]; spec != nil {
+			return g.named(sc.pkg, expr.Name)
+		}
+		if basic := basicTypes[expr.Name]; basic != nil {
+			return basic()
+		}
+	case *ast.SelectorExpr:
+		if known := wellKnown[exprString(expr)]; known != nil {
+			return known()
+		}
+		if p := g.pkgs[exprString(expr.X)]; p != nil {
+			return g.named(p, expr.Sel.Name)
+		}
+	case *ast.ParenExpr:
+		return g.schemaOf(expr.X, sc)
+	case *ast.StarExpr:
+		return nullable(g.schemaOf(expr.X, sc))
+	case *ast.ArrayType:
+		if ident, ok := expr.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(expr.Elt, sc)}
+	case *ast.MapType:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(expr.Value, sc)}
+	case *ast.StructType:
+		return g.structSchema(expr, sc)
+	}
+	return &Schema{}
+}
+
+// named returns the schema of the type name declared in p. Exported
+// structs are added to the components, keyed by their name, prefixed with
+// their package's unless it's the handlers'.
+func (g *generator) named(p *pkg, name string) *Schema {
+	spec := p.types[name]
+	if spec == nil {
+		return &Schema{}
+	}
+	if _, ok := spec.Type.(*ast.StructType); !ok || !ast.IsExported(name) {
+		return g.schemaOf(spec.Type, pkgScope(p))
+	}
+
+	key := name
+	if p.name != handlerPackage {
+		key = capitalize(p.name) + name
+	}
+	ref := &Schema{Ref: "#/components/schemas/" + key}
+	if g.schemas[key] != nil {
+		return ref
+	}
+	// Add the component before describing it, so that types referring to
+	// themselves end up referring to the component.
+	schema := &Schema{}
+	g.schemas[key] = schema
+	*schema = *g.schemaOf(spec.Type, pkgScope(p))
+	return ref
+}
+
+func (g *generator) structSchema(st *ast.StructType, sc *scope) *Schema {
+	schema := &Schema{Type: "object"}
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+		}
+		name, options, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+
+		if len(field.Names) == 0 && name == "" {
+			// Embedded structs' fields are promoted, like encoding/json
+			// does.
+			if embedded := g.structOf(&typeRef{expr: field.Type, sc: sc}); embedded != nil {
+				inner := g.structSchema(embedded.st, embedded.sc)
+				for property, value := range inner.Properties {
+					setProperty(schema, property, value)
+				}
+				schema.Required = append(schema.Required, inner.Required...)
+			}
+			continue
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(typeName(field.Type))}
+		}
+		for _, ident := range names {
+			if !ident.IsExported() {
+				continue
+			}
+			property := name
+			if property == "" {
+				property = ident.Name
+			}
+			value := g.schemaOf(field.Type, sc)
+			if doc := fieldDoc(field); doc != "" {
+				value.Description = doc
+			}
+			setProperty(schema, property, value)
+			if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") {
+				schema.Required = append(schema.Required, property)
+			}
+		}
+	}
+	return schema
+}
+
+func setProperty(schema *Schema, name string, value *Schema) {
+	if schema.Properties == nil {
+		schema.Properties = map[string]*Schema{}
+	}
+	schema.Properties[name] = value
+}
+
+func fieldDoc(field *ast.Field) string {
+	doc := field.Doc.Text()
+	if doc == "" {
+		doc = field.Comment.Text()
+	}
+	return strings.Join(strings.Fields(doc), " ")
+}
+
+// nullable lets schema also be null, for pointers.
+func nullable(schema *Schema) *Schema {
+	switch t := schema.Type.(type) {
+	case string:
+		schema.Type = []string{t, "null"}
+		return schema
+	case []string:
+		return schema
+	}
+	if schema.Ref == "" && schema.AnyOf == nil && schema.OneOf == nil {
+		// An empty schema already accepts null.
+		return schema
+	}
+	return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+}
+
+type structRef struct {
+	st *ast.StructType
+	sc *scope
+}
+
+// structOf returns the struct underlying the type ref, seeing through
+// pointers and named types.
+func (g *generator) structOf(ref *typeRef) *structRef {
+	for i := 0; ref != nil && i < 8; i++ {
+		switch expr := ref.expr.(type) {
+		case *ast.StructType:
+			return &structRef{st: expr, sc: ref.sc}
+		case *ast.StarExpr:
+			ref = &typeRef{expr: expr.X, sc: ref.sc}
+		case *ast.ParenExpr:
+			ref = &typeRef{expr: expr.X, sc: ref.sc}
+		default:
+			ref = g.underlying(ref)
+		}
+	}
+	return nil
+}
+
+// underlying returns the type a named type is declared as.
+func (g *generator) underlying(ref *typeRef) *typeRef {
+	switch expr := ref.expr.(type) {
+	case *ast.Ident:
+		if spec := ref.sc.locals[expr.Name]; spec != nil {
+			return &typeRef{expr: spec.Type, sc: ref.sc}
+		}
+		if spec := ref.sc.pkg.types[expr.Name]; spec != nil {
+			return &typeRef{expr: spec.Type, sc: pkgScope(ref.sc.pkg)}
+		}
+	case *ast.SelectorExpr:
+		if p := g.pkgs[exprString(expr.X)]; p != nil && p.types[expr.Sel.Name] != nil {
+			return &typeRef{expr: p.types[expr.Sel.Name].Type, sc: pkgScope(p)}
+		}
+	}
+	return nil
+}
+
+// namedType returns the package and name of the named type ref refers to,
+// possibly through a pointer.
+func (g *generator) namedType(ref *typeRef) (*pkg, string) {
+	expr := ref.expr
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if ref.sc.pkg.types[expr.Name] != nil && ref.sc.locals[expr.Name] == nil {
+			return ref.sc.pkg, expr.Name
+		}
+	case *ast.SelectorExpr:
+		if p := g.pkgs[exprString(expr.X)]; p != nil {
+			return p, expr.Sel.Name
+		}
+	}
+	return nil, ""
+}
+
+// resolveType returns the type of the value expr, written in sc, or nil
+// when it's nil or can't be worked out.
+func (g *generator) resolveType(expr ast.Expr, sc *scope) *typeRef {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if expr.Name == "nil" {
+			return nil
+		}
+		if b, ok := sc.bindings[expr.Name]; ok {
+			return g.resolveType(b.expr, b.sc)
+		}
+		if typ := sc.params[expr.Name]; typ != nil {
+			return &typeRef{expr: typ, sc: sc}
+		}
+		def := g.definition(expr.Name, sc)
+		if def == nil {
+			return nil
+		}
+		if def.typ != nil {
+			return &typeRef{expr: def.typ, sc: sc}
+		}
+		if call, ok := def.expr.(*ast.CallExpr); ok {
+			return g.callResult(call, sc, def.result)
+		}
+		if def.result != 0 {
+			return nil
+		}
+		return g.resolveType(def.expr, sc)
+	case *ast.BasicLit:
+		switch expr.Kind {
+		case token.STRING:
+			return &typeRef{expr: ast.NewIdent("string"), sc: sc}
+		case token.INT:
+			return &typeRef{expr: ast.NewIdent("int"), sc: sc}
+		case token.FLOAT:
+			return &typeRef{expr: ast.NewIdent("float64"), sc: sc}
+		}
+	case *ast.CompositeLit:
+		if expr.Type != nil {
+			return &typeRef{expr: expr.Type, sc: sc}
+		}
+	case *ast.ParenExpr:
+		return g.resolveType(expr.X, sc)
+	case *ast.UnaryExpr:
+		if expr.Op == token.AND {
+			return g.resolveType(expr.X, sc)
+		}
+	case *ast.StarExpr:
+		ref := g.resolveType(expr.X, sc)
+		if ref != nil {
+			if star, ok := ref.expr.(*ast.StarExpr); ok {
+				return &typeRef{expr: star.X, sc: ref.sc}
+			}
+		}
+		return ref
+	case *ast.CallExpr:
+		return g.callResult(expr, sc, 0)
+	case *ast.SelectorExpr:
+		if p := g.pkgs[exprString(expr.X)]; p != nil && sc.params[exprString(expr.X)] == nil && !g.isLocal(exprString(expr.X), sc) {
+			return nil
+		}
+		owner := g.resolveType(expr.X, sc)
+		if owner == nil {
+			return nil
+		}
+		return g.field(owner, expr.Sel.Name)
+	case *ast.IndexExpr:
+		owner := g.resolveType(expr.X, sc)
+		for i := 0; owner != nil && i < 8; i++ {
+			switch t := owner.expr.(type) {
+			case *ast.ArrayType:
+				return &typeRef{expr: t.Elt, sc: owner.sc}
+			case *ast.MapType:
+				return &typeRef{expr: t.Value, sc: owner.sc}
+			}
+			owner = g.underlying(owner)
+		}
+	case *ast.SliceExpr:
+		return g.resolveType(expr.X, sc)
+	}
+	return nil
+}
+
+// field returns the type of the named field of the struct owner refers
+// to, looking into embedded structs.
+func (g *generator) field(owner *typeRef, name string) *typeRef {
+	st := g.structOf(owner)
+	if st == nil {
+		return nil
+	}
+	for _, field := range st.st.Fields.List {
+		for _, ident := range field.Names {
+			if ident.Name == name {
+				return &typeRef{expr: field.Type, sc: st.sc}
+			}
+		}
+	}
+	for _, field := range st.st.Fields.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+		if typeName(field.Type) == name {
+			return &typeRef{expr: field.Type, sc: st.sc}
+		}
+		if ref := g.field(&typeRef{expr: field.Type, sc: st.sc}, name); ref != nil {
+			return ref
+		}
+	}
+	return nil
+}
+
+// callResult returns the type of the call's result at index.
+func (g *generator) callResult(call *ast.CallExpr, sc *scope, index int) *typeRef {
+	var fn *ast.FuncDecl
+	var p *pkg
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		switch fun.Name {
+		case "append":
+			if len(call.Args) > 0 {
+				return g.resolveType(call.Args[0], sc)
+			}
+			return nil
+		case "make":
+			if len(call.Args) > 0 {
+				return &typeRef{expr: call.Args[0], sc: sc}
+			}
+			return nil
+		case "new":
+			if len(call.Args) > 0 {
+				return &typeRef{expr: call.Args[0], sc: sc}
+			}
+			return nil
+		case "len", "cap":
+			return &typeRef{expr: ast.NewIdent("int"), sc: sc}
+		}
+		if sc.locals[fun.Name] != nil || sc.pkg.types[fun.Name] != nil || basicTypes[fun.Name] != nil {
+			// A conversion.
+			return &typeRef{expr: fun, sc: sc}
+		}
+		fn, p = sc.pkg.funcs[fun.Name], sc.pkg
+	case *ast.SelectorExpr:
+		if p := g.pkgs[exprString(fun.X)]; p != nil && p.types[fun.Sel.Name] != nil {
+			return &typeRef{expr: fun, sc: sc}
+		}
+		fn, p = g.method(sc, fun)
+	case *ast.ArrayType, *ast.MapType:
+		return &typeRef{expr: fun, sc: sc}
+	}
+	if fn == nil || fn.Type.Results == nil {
+		return nil
+	}
+
+	i := 0
+	for _, result := range fn.Type.Results.List {
+		n := max(len(result.Names), 1)
+		if index < i+n {
+			return &typeRef{expr: result.Type, sc: pkgScope(p)}
+		}
+		i += n
+	}
+	return nil
+}
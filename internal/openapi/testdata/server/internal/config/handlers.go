@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"example.com/server/internal/auth"
+	"github.com/google/uuid"
+)
+
+type ApiConfig struct{}
+
+type Widget struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// RetiredAt is when the widget stopped being sold.
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+	Parts     []Widget   `json:"parts"`
+	secret    string
+}
+
+// GetWidgetHandler looks a widget up by its ID. Retired widgets are only
+// shown with details=full.
+func (apiCfg *ApiConfig) GetWidgetHandler(w http.ResponseWriter, r *http.Request) {
+	widgetID, err := uuid.Parse(r.PathValue("widgetID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid widget ID", err)
+		return
+	}
+	if r.URL.Query().Get("details") == "full" {
+		respondWithJSON(w, http.StatusOK, Widget{ID: widgetID})
+		return
+	}
+	respondWithJSON(w, http.StatusOK, Widget{})
+}
+
+func (apiCfg *ApiConfig) CreateWidgetHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	_, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	apiCfg.created(w, http.StatusCreated)
+}
+
+func (apiCfg *ApiConfig) created(w http.ResponseWriter, status int) {
+	respondWithJSON(w, status, &Widget{})
+}
+
+func FilesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("file"))
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string, err error) {
+	respondWithJSON(w, code, map[string]string{"error": msg})
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	data, _ := json.Marshal(payload)
+	w.WriteHeader(code)
+	w.Write(data)
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"net/http"
+
+	"example.com/server/internal/config"
+)
+
+func main() {
+	apiCfg := &config.ApiConfig{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/widgets/{widgetID}", apiCfg.GetWidgetHandler)
+	mux.HandleFunc("POST /api/widgets", apiCfg.CreateWidgetHandler)
+	mux.HandleFunc("/api/files/{path...}", config.FilesHandler)
+}
@@ -0,0 +1,76 @@
+// Package payments abstracts the provider that bills Chirpy Red memberships.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPlan is assumed when the provider doesn't name a plan.
+const DefaultPlan = "chirpy_red"
+
+// EventType is a provider-independent kind of billing event.
+type EventType string
+
+const (
+	EventUpgraded   EventType = "upgraded"
+	EventRenewed    EventType = "renewed"
+	EventCancelled  EventType = "cancelled"
+	EventDowngraded EventType = "downgraded"
+	// EventUnknown is any event Chirpy doesn't act on.
+	EventUnknown EventType = "unknown"
+)
+
+// SubscriptionStatus is the provider's view of a user's subscription.
+type SubscriptionStatus string
+
+const (
+	SubscriptionActive SubscriptionStatus = "active"
+	// SubscriptionNone covers users who never subscribed as well as ended
+	// subscriptions.
+	SubscriptionNone SubscriptionStatus = "none"
+)
+
+var (
+	ErrInvalidSignature = errors.New("payments: invalid webhook signature")
+	ErrMissingEventID   = errors.New("payments: event has no ID")
+)
+
+// Event is a parsed webhook delivery.
+type Event struct {
+	ID string
+	// RawType is the provider's own name for the event, kept for the event
+	// log even when Type is EventUnknown.
+	RawType   string
+	Type      EventType
+	UserID    uuid.UUID
+	Plan      string
+	PeriodEnd *time.Time
+}
+
+// Subscription is the state of a user's subscription at the provider.
+type Subscription struct {
+	UserID            uuid.UUID
+	Plan              string
+	Status            SubscriptionStatus
+	PeriodEnd         *time.Time
+	CancelAtPeriodEnd bool
+}
+
+// PaymentProvider is implemented by each billing provider Chirpy supports.
+type PaymentProvider interface {
+	// Name identifies the provider in the webhook event log.
+	Name() string
+	// VerifyWebhook checks that a webhook delivery came from the provider.
+	VerifyWebhook(headers http.Header, payload []byte) error
+	// ParseEvent decodes a verified webhook payload.
+	ParseEvent(payload []byte) (Event, error)
+	// GetSubscription fetches a single user's subscription.
+	GetSubscription(ctx context.Context, userID uuid.UUID) (Subscription, error)
+	// ListSubscriptions fetches every active subscription.
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+}
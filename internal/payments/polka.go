@@ -0,0 +1,203 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// PolkaSignatureHeader carries the signature of a Polka webhook delivery.
+const PolkaSignatureHeader = "Polka-Signature"
+
+// polkaWebhookTolerance is how far a delivery's signed timestamp may be from
+// the server clock before it's rejected as a replay.
+const polkaWebhookTolerance = 5 * time.Minute
+
+var errPolkaNotFound = errors.New("payments: not found at Polka")
+
+var polkaEventTypes = map[string]EventType{
+	"user.upgraded":   EventUpgraded,
+	"user.renewed":    EventRenewed,
+	"user.cancelled":  EventCancelled,
+	"user.downgraded": EventDowngraded,
+}
+
+type PolkaConfig struct {
+	WebhookSecret string
+	// APIKey and BaseURL are only needed to query subscriptions.
+	APIKey     string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// Polka is the PaymentProvider for Polka.
+type Polka struct {
+	config PolkaConfig
+}
+
+func NewPolka(config PolkaConfig) *Polka {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return &Polka{config: config}
+}
+
+func (p *Polka) Name() string {
+	return "polka"
+}
+
+func (p *Polka) VerifyWebhook(headers http.Header, payload []byte) error {
+	err := auth.VerifyWebhookSignature(
+		headers.Get(PolkaSignatureHeader),
+		payload,
+		p.config.WebhookSecret,
+		time.Now(),
+		polkaWebhookTolerance,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+func (p *Polka) ParseEvent(payload []byte) (Event, error) {
+	type polkaEvent struct {
+		ID    string `json:"id"`
+		Event string `json:"event"`
+		Data  struct {
+			UserID    uuid.UUID  `json:"user_id"`
+			Plan      string     `json:"plan"`
+			PeriodEnd *time.Time `json:"period_end"`
+		} `json:"data"`
+	}
+
+	params := polkaEvent{}
+	err := json.Unmarshal(payload, &params)
+	if err != nil {
+		return Event{}, fmt.Errorf("payments: couldn't decode Polka event: %w", err)
+	}
+	if params.ID == "" {
+		return Event{}, ErrMissingEventID
+	}
+
+	eventType, ok := polkaEventTypes[params.Event]
+	if !ok {
+		eventType = EventUnknown
+	}
+	return Event{
+		ID:        params.ID,
+		RawType:   params.Event,
+		Type:      eventType,
+		UserID:    params.Data.UserID,
+		Plan:      planOrDefault(params.Data.Plan),
+		PeriodEnd: params.Data.PeriodEnd,
+	}, nil
+}
+
+type polkaSubscription struct {
+	UserID            uuid.UUID  `json:"user_id"`
+	Plan              string     `json:"plan"`
+	Status            string     `json:"status"`
+	CurrentPeriodEnd  *time.Time `json:"current_period_end"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+}
+
+func (s polkaSubscription) toSubscription() Subscription {
+	status := SubscriptionNone
+	if s.Status == "active" {
+		status = SubscriptionActive
+	}
+	return Subscription{
+		UserID:            s.UserID,
+		Plan:              planOrDefault(s.Plan),
+		Status:            status,
+		PeriodEnd:         s.CurrentPeriodEnd,
+		CancelAtPeriodEnd: s.CancelAtPeriodEnd,
+	}
+}
+
+func (p *Polka) GetSubscription(ctx context.Context, userID uuid.UUID) (Subscription, error) {
+	subscription := polkaSubscription{}
+	err := p.get(ctx, "/v1/subscriptions/"+userID.String(), &subscription)
+	if errors.Is(err, errPolkaNotFound) {
+		return Subscription{UserID: userID, Status: SubscriptionNone}, nil
+	}
+	if err != nil {
+		return Subscription{}, err
+	}
+	return subscription.toSubscription(), nil
+}
+
+func (p *Polka) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	type page struct {
+		Data       []polkaSubscription `json:"data"`
+		NextCursor string              `json:"next_cursor"`
+	}
+
+	subscriptions := []Subscription{}
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("status", "active")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		current := page{}
+		err := p.get(ctx, "/v1/subscriptions?"+query.Encode(), &current)
+		if err != nil {
+			return nil, err
+		}
+		for _, subscription := range current.Data {
+			subscriptions = append(subscriptions, subscription.toSubscription())
+		}
+		if current.NextCursor == "" {
+			return subscriptions, nil
+		}
+		cursor = current.NextCursor
+	}
+}
+
+func (p *Polka) get(ctx context.Context, path string, v any) error {
+	if p.config.BaseURL == "" {
+		return errors.New("payments: Polka API URL isn't configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errPolkaNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("payments: Polka returned %d for %s: %s", resp.StatusCode, path, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func planOrDefault(plan string) string {
+	if plan == "" {
+		return DefaultPlan
+	}
+	return plan
+}
@@ -0,0 +1,144 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OferRavid/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// newFakePolka serves the subsets of the Polka API the client uses, with
+// the given subscriptions split into pages of one.
+func newFakePolka(t *testing.T, apiKey string, subscriptions []polkaSubscription) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	authorized := func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer "+apiKey
+	}
+	mux.HandleFunc("GET /v1/subscriptions/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		for _, subscription := range subscriptions {
+			if subscription.UserID.String() == r.PathValue("userID") {
+				json.NewEncoder(w).Encode(subscription)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /v1/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		index := 0
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			for i, subscription := range subscriptions {
+				if subscription.UserID.String() == cursor {
+					index = i
+				}
+			}
+		}
+		page := map[string]any{"data": []polkaSubscription{}, "next_cursor": ""}
+		if index < len(subscriptions) {
+			page["data"] = subscriptions[index : index+1]
+		}
+		if index+1 < len(subscriptions) {
+			page["next_cursor"] = subscriptions[index+1].UserID.String()
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPolkaSubscriptions(t *testing.T) {
+	periodEnd := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	active := polkaSubscription{UserID: uuid.New(), Status: "active", CurrentPeriodEnd: &periodEnd}
+	cancelling := polkaSubscription{UserID: uuid.New(), Plan: "chirpy_red_yearly", Status: "active", CancelAtPeriodEnd: true}
+	server := newFakePolka(t, "polka-key", []polkaSubscription{active, cancelling})
+	polka := NewPolka(PolkaConfig{APIKey: "polka-key", BaseURL: server.URL + "/"})
+
+	subscription, err := polka.GetSubscription(context.Background(), active.UserID)
+	if err != nil {
+		t.Fatalf("GetSubscription() error = %v", err)
+	}
+	if subscription.Status != SubscriptionActive || subscription.Plan != DefaultPlan || !subscription.PeriodEnd.Equal(periodEnd) {
+		t.Errorf("GetSubscription() = %+v, want active %s subscription ending %v", subscription, DefaultPlan, periodEnd)
+	}
+
+	missing := uuid.New()
+	subscription, err = polka.GetSubscription(context.Background(), missing)
+	if err != nil {
+		t.Fatalf("GetSubscription() for unknown user error = %v", err)
+	}
+	if subscription.Status != SubscriptionNone || subscription.UserID != missing {
+		t.Errorf("GetSubscription() for unknown user = %+v, want no subscription", subscription)
+	}
+
+	subscriptions, err := polka.ListSubscriptions(context.Background())
+	if err != nil {
+		t.Fatalf("ListSubscriptions() error = %v", err)
+	}
+	if len(subscriptions) != 2 || subscriptions[1].UserID != cancelling.UserID || !subscriptions[1].CancelAtPeriodEnd {
+		t.Errorf("ListSubscriptions() = %+v, want both pages", subscriptions)
+	}
+
+	unauthorized := NewPolka(PolkaConfig{APIKey: "wrong", BaseURL: server.URL})
+	_, err = unauthorized.ListSubscriptions(context.Background())
+	if err == nil {
+		t.Errorf("ListSubscriptions() with wrong API key succeeded")
+	}
+}
+
+func TestPolkaWebhook(t *testing.T) {
+	polka := NewPolka(PolkaConfig{WebhookSecret: "whsec"})
+	userID := uuid.New()
+	payload := []byte(`{"id":"evt_1","event":"user.cancelled","data":{"user_id":"` + userID.String() + `"}}`)
+
+	headers := http.Header{}
+	headers.Set(PolkaSignatureHeader, auth.SignWebhook(payload, "whsec", time.Now()))
+	err := polka.VerifyWebhook(headers, payload)
+	if err != nil {
+		t.Fatalf("VerifyWebhook() error = %v", err)
+	}
+
+	headers.Set(PolkaSignatureHeader, auth.SignWebhook(payload, "other", time.Now()))
+	err = polka.VerifyWebhook(headers, payload)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyWebhook() with wrong secret error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	event, err := polka.ParseEvent(payload)
+	if err != nil {
+		t.Fatalf("ParseEvent() error = %v", err)
+	}
+	want := Event{ID: "evt_1", RawType: "user.cancelled", Type: EventCancelled, UserID: userID, Plan: DefaultPlan}
+	if event != want {
+		t.Errorf("ParseEvent() = %+v, want %+v", event, want)
+	}
+
+	event, err = polka.ParseEvent([]byte(`{"id":"evt_2","event":"user.paused"}`))
+	if err != nil || event.Type != EventUnknown || event.RawType != "user.paused" {
+		t.Errorf("ParseEvent() of unknown event = %+v, %v", event, err)
+	}
+
+	_, err = polka.ParseEvent([]byte(`{"event":"user.upgraded"}`))
+	if !errors.Is(err, ErrMissingEventID) {
+		t.Errorf("ParseEvent() without ID error = %v, want %v", err, ErrMissingEventID)
+	}
+	_, err = polka.ParseEvent([]byte(strings.Repeat("{", 3)))
+	if err == nil {
+		t.Errorf("ParseEvent() of malformed payload succeeded")
+	}
+}
@@ -0,0 +1,109 @@
+package payments
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Membership is the part of a local membership that reconciliation compares
+// against the provider.
+type Membership struct {
+	UserID    uuid.UUID
+	Plan      string
+	EndsAt    *time.Time
+	Cancelled bool
+	// Granted is set on memberships an operator granted, which the provider
+	// doesn't bill for.
+	Granted bool
+}
+
+// CorrectionType says how a local membership has to change to match the
+// provider.
+type CorrectionType string
+
+const (
+	// CorrectionStart starts a membership the provider knows about but we
+	// don't.
+	CorrectionStart CorrectionType = "start"
+	// CorrectionUpdate changes the plan or period of a membership, or
+	// undoes a cancellation.
+	CorrectionUpdate CorrectionType = "update"
+	// CorrectionCancel marks a membership as not renewing.
+	CorrectionCancel CorrectionType = "cancel"
+	// CorrectionEnd ends a membership the provider no longer bills for.
+	CorrectionEnd CorrectionType = "end"
+)
+
+type Correction struct {
+	Type      CorrectionType
+	UserID    uuid.UUID
+	Plan      string
+	PeriodEnd *time.Time
+}
+
+// Diff compares active local memberships with the provider's active
+// subscriptions and returns the corrections that bring the local state in
+// line with the provider, ordered by user ID. Users with a granted
+// membership are left alone, whether or not they also have a
+// subscription, until the grant ends.
+func Diff(local []Membership, remote []Subscription) []Correction {
+	localByUser := map[uuid.UUID]Membership{}
+	granted := map[uuid.UUID]bool{}
+	for _, membership := range local {
+		if membership.Granted {
+			granted[membership.UserID] = true
+			continue
+		}
+		localByUser[membership.UserID] = membership
+	}
+	remoteByUser := map[uuid.UUID]Subscription{}
+	for _, subscription := range remote {
+		if subscription.Status == SubscriptionActive && !granted[subscription.UserID] {
+			remoteByUser[subscription.UserID] = subscription
+		}
+	}
+
+	corrections := []Correction{}
+	for userID, membership := range localByUser {
+		if _, ok := remoteByUser[userID]; !ok {
+			corrections = append(corrections, Correction{Type: CorrectionEnd, UserID: membership.UserID})
+		}
+	}
+	for userID, subscription := range remoteByUser {
+		correction := Correction{
+			UserID:    userID,
+			Plan:      subscription.Plan,
+			PeriodEnd: subscription.PeriodEnd,
+		}
+		membership, ok := localByUser[userID]
+		switch {
+		case !ok:
+			correction.Type = CorrectionStart
+		case membership.Plan != subscription.Plan || !sameTime(membership.EndsAt, subscription.PeriodEnd):
+			correction.Type = CorrectionUpdate
+		case membership.Cancelled && !subscription.CancelAtPeriodEnd:
+			correction.Type = CorrectionUpdate
+		case !membership.Cancelled && subscription.CancelAtPeriodEnd:
+			correction.Type = CorrectionCancel
+		default:
+			continue
+		}
+		corrections = append(corrections, correction)
+	}
+
+	sort.Slice(corrections, func(i, j int) bool {
+		return corrections[i].UserID.String() < corrections[j].UserID.String()
+	})
+	return corrections
+}
+
+// sameTime compares optional period ends to the second, since the database
+// and the provider store them with different precision.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
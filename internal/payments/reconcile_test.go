@@ -0,0 +1,96 @@
+package payments
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDiff(t *testing.T) {
+	periodEnd := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	nextPeriodEnd := periodEnd.AddDate(0, 1, 0)
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name   string
+		local  []Membership
+		remote []Subscription
+		want   []Correction
+	}{
+		{
+			name:   "In sync",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan, EndsAt: &periodEnd}},
+			remote: []Subscription{{UserID: userID, Plan: DefaultPlan, Status: SubscriptionActive, PeriodEnd: &periodEnd}},
+			want:   []Correction{},
+		},
+		{
+			name:   "Missed upgrade",
+			local:  nil,
+			remote: []Subscription{{UserID: userID, Plan: DefaultPlan, Status: SubscriptionActive, PeriodEnd: &periodEnd}},
+			want:   []Correction{{Type: CorrectionStart, UserID: userID, Plan: DefaultPlan, PeriodEnd: &periodEnd}},
+		},
+		{
+			name:   "Missed downgrade",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan}},
+			remote: nil,
+			want:   []Correction{{Type: CorrectionEnd, UserID: userID}},
+		},
+		{
+			name:   "Inactive remote subscription ends membership",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan}},
+			remote: []Subscription{{UserID: userID, Plan: DefaultPlan, Status: SubscriptionNone}},
+			want:   []Correction{{Type: CorrectionEnd, UserID: userID}},
+		},
+		{
+			name:   "Missed renewal",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan, EndsAt: &periodEnd}},
+			remote: []Subscription{{UserID: userID, Plan: DefaultPlan, Status: SubscriptionActive, PeriodEnd: &nextPeriodEnd}},
+			want:   []Correction{{Type: CorrectionUpdate, UserID: userID, Plan: DefaultPlan, PeriodEnd: &nextPeriodEnd}},
+		},
+		{
+			name:   "Missed cancellation",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan, EndsAt: &periodEnd}},
+			remote: []Subscription{{UserID: userID, Plan: DefaultPlan, Status: SubscriptionActive, PeriodEnd: &periodEnd, CancelAtPeriodEnd: true}},
+			want:   []Correction{{Type: CorrectionCancel, UserID: userID, Plan: DefaultPlan, PeriodEnd: &periodEnd}},
+		},
+		{
+			name:   "Cancellation undone at provider",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan, EndsAt: &periodEnd, Cancelled: true}},
+			remote: []Subscription{{UserID: userID, Plan: DefaultPlan, Status: SubscriptionActive, PeriodEnd: &periodEnd}},
+			want:   []Correction{{Type: CorrectionUpdate, UserID: userID, Plan: DefaultPlan, PeriodEnd: &periodEnd}},
+		},
+		{
+			name:   "Operator grant is left alone",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan, Granted: true}},
+			remote: nil,
+			want:   []Correction{},
+		},
+		{
+			name:   "Subscription doesn't override a grant",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan, Granted: true}},
+			remote: []Subscription{{UserID: userID, Plan: DefaultPlan, Status: SubscriptionActive, PeriodEnd: &periodEnd}},
+			want:   []Correction{},
+		},
+		{
+			name:   "Sub-second difference is ignored",
+			local:  []Membership{{UserID: userID, Plan: DefaultPlan, EndsAt: ptr(periodEnd.Add(300 * time.Millisecond))}},
+			remote: []Subscription{{UserID: userID, Plan: DefaultPlan, Status: SubscriptionActive, PeriodEnd: &periodEnd}},
+			want:   []Correction{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.local, tt.remote)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,61 @@
+// Package ratelimit limits how often a key, such as a user ID, may perform an
+// action within a sliding window.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is an in-memory sliding-window limiter. The limit is passed on
+// every call so that each key can have its own, e.g. per membership plan.
+//
+// Attempts are only counted within the process. Behind a load balancer each
+// instance allows the full limit, so a key can make up to the limit times
+// the number of instances, and a restart forgets every attempt.
+type Limiter struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	events map[string][]time.Time
+}
+
+func New(window time.Duration) *Limiter {
+	return &Limiter{
+		window: window,
+		events: map[string][]time.Time{},
+	}
+}
+
+// Allow records an attempt for key at now and reports whether it's within
+// limit attempts per window. Rejected attempts aren't counted. When it
+// returns false, retryAfter says how long until the next attempt would be
+// allowed.
+func (l *Limiter) Allow(key string, limit int, now time.Time) (allowed bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.prune(key, now)
+	if len(recent) >= limit {
+		if len(recent) == 0 {
+			return false, l.window
+		}
+		return false, recent[len(recent)-limit].Add(l.window).Sub(now)
+	}
+	l.events[key] = append(recent, now)
+	return true, 0
+}
+
+func (l *Limiter) prune(key string, now time.Time) []time.Time {
+	events := l.events[key]
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	events = events[i:]
+	if len(events) == 0 {
+		delete(l.events, key)
+	}
+	return events
+}
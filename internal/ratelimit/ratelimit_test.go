@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		limit          int
+		attempts       []time.Duration
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:        "Under limit",
+			limit:       3,
+			attempts:    []time.Duration{0, time.Second, 2 * time.Second},
+			wantAllowed: true,
+		},
+		{
+			name:           "Over limit",
+			limit:          2,
+			attempts:       []time.Duration{0, 10 * time.Second, 20 * time.Second},
+			wantAllowed:    false,
+			wantRetryAfter: 40 * time.Second,
+		},
+		{
+			name:        "Window slid past old attempts",
+			limit:       2,
+			attempts:    []time.Duration{0, 10 * time.Second, 61 * time.Second},
+			wantAllowed: true,
+		},
+		{
+			name:           "Zero limit",
+			limit:          0,
+			attempts:       []time.Duration{0},
+			wantAllowed:    false,
+			wantRetryAfter: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := New(time.Minute)
+			var allowed bool
+			var retryAfter time.Duration
+			for _, offset := range tt.attempts {
+				allowed, retryAfter = limiter.Allow("user", tt.limit, start.Add(offset))
+			}
+			if allowed != tt.wantAllowed || retryAfter != tt.wantRetryAfter {
+				t.Errorf("Allow() = %v, %v, want %v, %v", allowed, retryAfter, tt.wantAllowed, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+// Package webauthn implements the server side of the WebAuthn registration
+// and authentication ceremonies used for passkey login.
+//
+// Attestation statements are not verified: the relying party asks for
+// "none" attestation, so the authenticator model is never trusted and only
+// the credential public key and flags in the authenticator data matter.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	flagUserPresent            = 0x01
+	flagAttestedCredentialData = 0x40
+
+	authDataMinLength = 37
+)
+
+var (
+	ErrChallengeMismatch   = errors.New("webauthn: challenge mismatch")
+	ErrOriginMismatch      = errors.New("webauthn: origin mismatch")
+	ErrRPIDMismatch        = errors.New("webauthn: relying party ID mismatch")
+	ErrCeremonyMismatch    = errors.New("webauthn: unexpected client data type")
+	ErrUserNotPresent      = errors.New("webauthn: user presence flag not set")
+	ErrInvalidSignature    = errors.New("webauthn: invalid signature")
+	ErrSignCountRegression = errors.New("webauthn: signature counter did not increase, authenticator may be cloned")
+	ErrMalformedAuthData   = errors.New("webauthn: malformed authenticator data")
+)
+
+// RelyingParty describes this server to authenticators. ID is the
+// registrable domain the credentials are scoped to and Origin is the exact
+// origin browsers report in client data.
+type RelyingParty struct {
+	ID     string
+	Name   string
+	Origin string
+}
+
+// Credential is what the relying party stores after a successful
+// registration.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+// URLEncodedBytes marshals to and from unpadded base64url, the encoding the
+// WebAuthn JSON serialization uses for binary values.
+type URLEncodedBytes []byte
+
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("webauthn: invalid base64url value: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          URLEncodedBytes `json:"id"`
+	Name        string          `json:"name"`
+	DisplayName string          `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string          `json:"type"`
+	ID   URLEncodedBytes `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions is the JSON form of PublicKeyCredentialCreationOptions
+// passed to navigator.credentials.create().
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              URLEncodedBytes        `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is the JSON form of PublicKeyCredentialRequestOptions
+// passed to navigator.credentials.get().
+type RequestOptions struct {
+	Challenge        URLEncodedBytes        `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the JSON form of the PublicKeyCredential returned
+// by navigator.credentials.create().
+type RegistrationResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AttestationObject URLEncodedBytes `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the PublicKeyCredential returned by
+// navigator.credentials.get().
+type AssertionResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AuthenticatorData URLEncodedBytes `json:"authenticatorData"`
+		Signature         URLEncodedBytes `json:"signature"`
+		UserHandle        URLEncodedBytes `json:"userHandle"`
+	} `json:"response"`
+}
+
+// NewChallenge returns a fresh random challenge for a ceremony.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// CreationOptions builds the options for registering a new credential for
+// the given user, excluding credentials they have already registered.
+func (rp RelyingParty) CreationOptions(challenge, userHandle []byte, userName string, timeoutMillis int64, exclude [][]byte) CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	return CreationOptions{
+		RP:                 RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:               UserEntity{ID: userHandle, Name: userName, DisplayName: userName},
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            timeoutMillis,
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions builds the options for authenticating with an existing
+// credential. An empty allow list lets the authenticator offer any
+// discoverable credential for this relying party.
+func (rp RelyingParty) RequestOptions(challenge []byte, timeoutMillis int64, allow [][]byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          timeoutMillis,
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: "preferred",
+	}
+}
+
+// VerifyRegistration checks the response to a registration ceremony
+// started with challenge and returns the new credential.
+func (rp RelyingParty) VerifyRegistration(challenge []byte, response RegistrationResponse) (Credential, error) {
+	err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	decoded, _, err := decodeCBOR(response.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, err
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return Credential{}, fmt.Errorf("%w: attestation object is not a map", errCBOR)
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return Credential{}, fmt.Errorf("%w: missing authData", ErrMalformedAuthData)
+	}
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if authData.credentialID == nil {
+		return Credential{}, fmt.Errorf("%w: no attested credential data", ErrMalformedAuthData)
+	}
+	if len(response.RawID) > 0 && !bytes.Equal(response.RawID, authData.credentialID) {
+		return Credential{}, fmt.Errorf("%w: rawId doesn't match attested credential", ErrMalformedAuthData)
+	}
+
+	return Credential{
+		ID:        authData.credentialID,
+		PublicKey: authData.credentialPublicKey,
+		SignCount: authData.signCount,
+	}, nil
+}
+
+// VerifyAssertion checks the response to an authentication ceremony started
+// with challenge against a stored credential and returns the authenticator's
+// new signature counter, which the caller must persist.
+func (rp RelyingParty) VerifyAssertion(challenge []byte, credential Credential, response AssertionResponse) (uint32, error) {
+	err := rp.verifyClientData(response.Response.ClientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.parseAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), response.Response.AuthenticatorData...), clientDataHash[:]...)
+	err = key.verify(signed, response.Response.Signature)
+	if err != nil {
+		return 0, err
+	}
+
+	// Authenticators that don't implement a counter always report zero.
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCountRegression
+	}
+	return authData.signCount, nil
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func (rp RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	data := clientData{}
+	err := json.Unmarshal(raw, &data)
+	if err != nil {
+		return fmt.Errorf("webauthn: invalid client data: %w", err)
+	}
+	if data.Type != ceremony {
+		return ErrCeremonyMismatch
+	}
+	got, err := base64.RawURLEncoding.DecodeString(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if data.Origin != rp.Origin {
+		return ErrOriginMismatch
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	flags               byte
+	signCount           uint32
+	credentialID        []byte
+	credentialPublicKey []byte
+}
+
+func (rp RelyingParty) parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return authenticatorData{}, ErrMalformedAuthData
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(raw[:32], rpIDHash[:]) != 1 {
+		return authenticatorData{}, ErrRPIDMismatch
+	}
+
+	data := authenticatorData{
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if data.flags&flagUserPresent == 0 {
+		return authenticatorData{}, ErrUserNotPresent
+	}
+	if data.flags&flagAttestedCredentialData == 0 {
+		return data, nil
+	}
+
+	// Attested credential data: 16 byte AAGUID, 2 byte length, credential
+	// ID, then the COSE encoded public key.
+	rest := raw[authDataMinLength:]
+	if len(rest) < 18 {
+		return authenticatorData{}, ErrMalformedAuthData
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return authenticatorData{}, ErrMalformedAuthData
+	}
+	data.credentialID = append([]byte(nil), rest[:idLength]...)
+	rest = rest[idLength:]
+
+	decoded, remaining, err := decodeCBOR(rest)
+	if err != nil {
+		return authenticatorData{}, err
+	}
+	if _, err := publicKeyFromMap(decoded); err != nil {
+		return authenticatorData{}, err
+	}
+	data.credentialPublicKey = append([]byte(nil), rest[:len(rest)-len(remaining)]...)
+	return data, nil
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, CredentialDescriptor{Type: "public-key", ID: id})
+	}
+	return list
+}
@@ -0,0 +1,228 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+// softwareAuthenticator is a minimal in-memory authenticator producing the
+// same structures a browser would return, so the ceremonies can be tested
+// end to end.
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+	return &softwareAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *softwareAuthenticator) register(rpID, origin string, challenge []byte) RegistrationResponse {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.PublicKey.X.FillBytes(x)
+	a.key.PublicKey.Y.FillBytes(y)
+	coseKey := encodeCBOR(map[any]any{
+		int64(coseKeyType):   int64(coseKeyTypeEC2),
+		int64(coseAlgorithm): AlgES256,
+		int64(coseCurve):     int64(coseCurveP256),
+		int64(coseX):         x,
+		int64(coseY):         y,
+	})
+
+	authData := a.authData(rpID, flagUserPresent|flagAttestedCredentialData)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, coseKey...)
+
+	response := RegistrationResponse{ID: base64.RawURLEncoding.EncodeToString(a.credentialID), RawID: a.credentialID, Type: "public-key"}
+	response.Response.ClientDataJSON = clientDataJSON("webauthn.create", challenge, origin)
+	response.Response.AttestationObject = encodeCBOR(map[any]any{
+		"fmt":      "none",
+		"attStmt":  map[any]any{},
+		"authData": authData,
+	})
+	return response
+}
+
+func (a *softwareAuthenticator) assert(rpID, origin string, challenge []byte) AssertionResponse {
+	a.signCount++
+	authData := a.authData(rpID, flagUserPresent)
+	clientData := clientDataJSON("webauthn.get", challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, _ := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+
+	response := AssertionResponse{ID: base64.RawURLEncoding.EncodeToString(a.credentialID), RawID: a.credentialID, Type: "public-key"}
+	response.Response.ClientDataJSON = clientData
+	response.Response.AuthenticatorData = authData
+	response.Response.Signature = signature
+	return response
+}
+
+func (a *softwareAuthenticator) authData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+func clientDataJSON(ceremony string, challenge []byte, origin string) []byte {
+	data, _ := json.Marshal(clientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    origin,
+	})
+	return data
+}
+
+func encodeCBOR(value any) []byte {
+	header := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		default:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		}
+	}
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return header(1, uint64(-1-v))
+		}
+		return header(0, uint64(v))
+	case []byte:
+		return append(header(2, uint64(len(v))), v...)
+	case string:
+		return append(header(3, uint64(len(v))), v...)
+	case map[any]any:
+		keys := make([]any, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return string(encodeCBOR(keys[i])) < string(encodeCBOR(keys[j]))
+		})
+		out := header(5, uint64(len(v)))
+		for _, key := range keys {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(v[key])...)
+		}
+		return out
+	}
+	panic("unsupported CBOR value")
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := RelyingParty{ID: "localhost", Name: "Chirpy", Origin: "http://localhost:8080"}
+	authenticator := newSoftwareAuthenticator(t)
+
+	registrationChallenge, _ := NewChallenge()
+	credential, err := rp.VerifyRegistration(registrationChallenge, authenticator.register(rp.ID, rp.Origin, registrationChallenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration() error = %v", err)
+	}
+	if string(credential.ID) != string(authenticator.credentialID) {
+		t.Errorf("VerifyRegistration() credential ID = %x, want %x", credential.ID, authenticator.credentialID)
+	}
+
+	for i := 1; i <= 2; i++ {
+		challenge, _ := NewChallenge()
+		signCount, err := rp.VerifyAssertion(challenge, credential, authenticator.assert(rp.ID, rp.Origin, challenge))
+		if err != nil {
+			t.Fatalf("VerifyAssertion() error = %v", err)
+		}
+		if signCount != uint32(i) {
+			t.Errorf("VerifyAssertion() signCount = %d, want %d", signCount, i)
+		}
+		credential.SignCount = signCount
+	}
+}
+
+func TestVerifyRegistrationErrors(t *testing.T) {
+	rp := RelyingParty{ID: "localhost", Name: "Chirpy", Origin: "http://localhost:8080"}
+	challenge, _ := NewChallenge()
+	otherChallenge, _ := NewChallenge()
+
+	tests := []struct {
+		name      string
+		rpID      string
+		origin    string
+		challenge []byte
+		wantErr   error
+	}{
+		{name: "Wrong challenge", rpID: rp.ID, origin: rp.Origin, challenge: otherChallenge, wantErr: ErrChallengeMismatch},
+		{name: "Wrong origin", rpID: rp.ID, origin: "https://evil.example", challenge: challenge, wantErr: ErrOriginMismatch},
+		{name: "Wrong RP ID", rpID: "evil.example", origin: rp.Origin, challenge: challenge, wantErr: ErrRPIDMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator := newSoftwareAuthenticator(t)
+			_, err := rp.VerifyRegistration(challenge, authenticator.register(tt.rpID, tt.origin, tt.challenge))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyRegistration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionErrors(t *testing.T) {
+	rp := RelyingParty{ID: "localhost", Name: "Chirpy", Origin: "http://localhost:8080"}
+	authenticator := newSoftwareAuthenticator(t)
+	registrationChallenge, _ := NewChallenge()
+	credential, err := rp.VerifyRegistration(registrationChallenge, authenticator.register(rp.ID, rp.Origin, registrationChallenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration() error = %v", err)
+	}
+
+	t.Run("Signature from another key", func(t *testing.T) {
+		challenge, _ := NewChallenge()
+		impostor := newSoftwareAuthenticator(t)
+		_, err := rp.VerifyAssertion(challenge, credential, impostor.assert(rp.ID, rp.Origin, challenge))
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifyAssertion() error = %v, wantErr %v", err, ErrInvalidSignature)
+		}
+	})
+
+	t.Run("Signature counter regression", func(t *testing.T) {
+		challenge, _ := NewChallenge()
+		stored := credential
+		stored.SignCount = 10
+		_, err := rp.VerifyAssertion(challenge, stored, authenticator.assert(rp.ID, rp.Origin, challenge))
+		if !errors.Is(err, ErrSignCountRegression) {
+			t.Errorf("VerifyAssertion() error = %v, wantErr %v", err, ErrSignCountRegression)
+		}
+	})
+
+	t.Run("Registration client data replayed", func(t *testing.T) {
+		challenge, _ := NewChallenge()
+		response := authenticator.assert(rp.ID, rp.Origin, challenge)
+		response.Response.ClientDataJSON = clientDataJSON("webauthn.create", challenge, rp.Origin)
+		_, err := rp.VerifyAssertion(challenge, credential, response)
+		if !errors.Is(err, ErrCeremonyMismatch) {
+			t.Errorf("VerifyAssertion() error = %v, wantErr %v", err, ErrCeremonyMismatch)
+		}
+	})
+}
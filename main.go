@@ -1,14 +1,27 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
 
+	"github.com/OferRavid/chirpy/internal/activitypub"
+	"github.com/OferRavid/chirpy/internal/admin"
+	"github.com/OferRavid/chirpy/internal/blobstore"
 	"github.com/OferRavid/chirpy/internal/config"
 	"github.com/OferRavid/chirpy/internal/database"
+	"github.com/OferRavid/chirpy/internal/oidc"
+	"github.com/OferRavid/chirpy/internal/payments"
+	"github.com/OferRavid/chirpy/internal/ratelimit"
+	"github.com/OferRavid/chirpy/internal/webauthn"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -16,6 +29,15 @@ import (
 func main() {
 	const filepathRoot = "."
 	const port = "8080"
+	const membershipExpiryInterval = 10 * time.Minute
+	const membershipReconciliationInterval = 6 * time.Hour
+	const contentRuleReloadInterval = time.Minute
+	const mutedWordCleanupInterval = time.Hour
+	const accountDeletionInterval = time.Hour
+	const exportInterval = time.Minute
+	const federationDeliveryInterval = 30 * time.Second
+	const idempotencyKeyCleanupInterval = time.Hour
+	const chirpPublisherInterval = 15 * time.Second
 
 	godotenv.Load()
 	dbURL := os.Getenv("DB_URL")
@@ -26,13 +48,14 @@ func main() {
 	if platform == "" {
 		log.Fatal("PLATFORM must be set")
 	}
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET environment variable is not set")
+	baseURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
 	}
-	polka := os.Getenv("POLKA_KEY")
-	if secret == "" {
-		log.Fatal("POLKA_KEY environment variable is not set")
+
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = "exports"
 	}
 
 	db, err := sql.Open("postgres", dbURL)
@@ -41,12 +64,136 @@ func main() {
 	}
 	dbQueries := database.New(db)
 
+	// `chirpy admin ...` runs an operator command against the same database
+	// as the server, instead of serving. The operator commands only need the
+	// database and where exports are kept, so the server's secrets and
+	// identity providers aren't required.
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		runAdmin(&config.ApiConfig{
+			DB:        db,
+			DbQueries: dbQueries,
+			Platform:  platform,
+			Blobs:     &blobstore.FileStore{Dir: exportDir},
+			BaseURL:   baseURL,
+		}, os.Args[2:])
+		return
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		log.Fatal("JWT_SECRET environment variable is not set")
+	}
+	polkaWebhookSecret := os.Getenv("POLKA_WEBHOOK_SECRET")
+	if polkaWebhookSecret == "" {
+		log.Fatal("POLKA_WEBHOOK_SECRET environment variable is not set")
+	}
+	polkaAPIURL := os.Getenv("POLKA_API_URL")
+	reportAutoHideThreshold := 5
+	if value := os.Getenv("REPORT_AUTO_HIDE_THRESHOLD"); value != "" {
+		var err error
+		reportAutoHideThreshold, err = strconv.Atoi(value)
+		if err != nil || reportAutoHideThreshold < 0 {
+			log.Fatal("REPORT_AUTO_HIDE_THRESHOLD must be a non-negative integer")
+		}
+	}
+
+	accountDeletionGracePeriod := 30 * 24 * time.Hour
+	if value := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); value != "" {
+		var err error
+		accountDeletionGracePeriod, err = time.ParseDuration(value)
+		if err != nil || accountDeletionGracePeriod < 0 {
+			log.Fatal("ACCOUNT_DELETION_GRACE_PERIOD must be a non-negative duration like 720h")
+		}
+	}
+
+	idempotencyKeyRetention := 24 * time.Hour
+	if value := os.Getenv("IDEMPOTENCY_KEY_RETENTION"); value != "" {
+		var err error
+		idempotencyKeyRetention, err = time.ParseDuration(value)
+		if err != nil || idempotencyKeyRetention <= 0 {
+			log.Fatal("IDEMPOTENCY_KEY_RETENTION must be a positive duration like 24h")
+		}
+	}
+
+	blobs, err := blobstore.NewFileStore(exportDir)
+	if err != nil {
+		log.Fatalf("failed to set up export storage: %s\n", err)
+	}
+
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	if rpID == "" {
+		rpID = "localhost"
+	}
+	rpOrigin := os.Getenv("WEBAUTHN_RP_ORIGIN")
+	if rpOrigin == "" {
+		rpOrigin = "http://localhost:" + port
+	}
+
+	var oidcProvider *oidc.Provider
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+		if redirectURL == "" {
+			redirectURL = "http://localhost:" + port + "/api/oidc/callback"
+		}
+		var err error
+		oidcProvider, err = oidc.NewProvider(context.Background(), oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"email", "profile"},
+		})
+		if err != nil {
+			log.Fatalf("failed to set up OIDC provider: %s\n", err)
+		}
+	}
+
 	apiCfg := &config.ApiConfig{
 		FileserverHits: atomic.Int32{},
+		DB:             db,
 		DbQueries:      dbQueries,
 		Platform:       platform,
 		Secret:         secret,
-		ApiKey:         polka,
+		Payments: payments.NewPolka(payments.PolkaConfig{
+			WebhookSecret: polkaWebhookSecret,
+			APIKey:        os.Getenv("POLKA_API_KEY"),
+			BaseURL:       polkaAPIURL,
+		}),
+		WebAuthn: webauthn.RelyingParty{
+			ID:     rpID,
+			Name:   "Chirpy",
+			Origin: rpOrigin,
+		},
+		OIDC:                       oidcProvider,
+		ChirpLimiter:               ratelimit.New(time.Minute),
+		ReportAutoHideThreshold:    reportAutoHideThreshold,
+		AccountDeletionGracePeriod: accountDeletionGracePeriod,
+		IdempotencyKeyRetention:    idempotencyKeyRetention,
+		Blobs:                      blobs,
+		BaseURL:                    baseURL,
+		Federation: &activitypub.Client{
+			// Federating over plain http, or with servers on private
+			// addresses, only makes sense between local instances.
+			AllowHTTP:             platform == "dev",
+			AllowPrivateAddresses: platform == "dev",
+			UserAgent:             "Chirpy (+" + baseURL + ")",
+		},
+	}
+
+	err = apiCfg.ReloadContentRules(context.Background())
+	if err != nil {
+		log.Fatalf("failed to load content rules: %s\n", err)
+	}
+	go apiCfg.RunContentRuleReload(context.Background(), contentRuleReloadInterval)
+	go apiCfg.RunMembershipExpiry(context.Background(), membershipExpiryInterval)
+	go apiCfg.RunMutedWordCleanup(context.Background(), mutedWordCleanupInterval)
+	go apiCfg.RunAccountDeletion(context.Background(), accountDeletionInterval)
+	go apiCfg.RunExports(context.Background(), exportInterval)
+	go apiCfg.RunFederationDelivery(context.Background(), federationDeliveryInterval)
+	go apiCfg.RunIdempotencyKeyCleanup(context.Background(), idempotencyKeyCleanupInterval)
+	go apiCfg.RunChirpPublisher(context.Background(), chirpPublisherInterval)
+	if polkaAPIURL != "" {
+		go apiCfg.RunMembershipReconciliation(context.Background(), membershipReconciliationInterval)
 	}
 
 	mux := http.NewServeMux()
@@ -54,27 +201,117 @@ func main() {
 	mux.Handle("/app/", fsHandler)
 
 	mux.HandleFunc("GET /api/healthz", config.StatusHandler)
+	mux.HandleFunc("GET /api/openapi.json", config.OpenAPIHandler)
 	mux.HandleFunc("GET /admin/metrics", apiCfg.MetricsHandler)
 	mux.HandleFunc("GET /api/chirps", apiCfg.RetrieveChirpsHandler)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.GetChirpsHandler)
+	mux.HandleFunc("GET /users/{userID}/feed.rss", apiCfg.UserRSSFeedHandler)
+	mux.HandleFunc("GET /users/{userID}/feed.atom", apiCfg.UserAtomFeedHandler)
+	mux.HandleFunc("GET /feed.rss", apiCfg.PublicRSSFeedHandler)
+	mux.HandleFunc("GET /feed.atom", apiCfg.PublicAtomFeedHandler)
+	mux.HandleFunc("GET /.well-known/webfinger", apiCfg.WebFingerHandler)
+	mux.HandleFunc("GET /users/{userID}", apiCfg.ActorHandler)
+	mux.HandleFunc("GET /users/{userID}/outbox", apiCfg.OutboxHandler)
+	mux.HandleFunc("GET /users/{userID}/followers", apiCfg.FollowersHandler)
+	mux.HandleFunc("GET /users/{userID}/chirps/{chirpID}", apiCfg.NoteHandler)
+	mux.HandleFunc("GET /api/remote-notes", apiCfg.ListRemoteNotesHandler)
+	mux.HandleFunc("GET /api/passkeys", apiCfg.ListPasskeysHandler)
+	mux.HandleFunc("GET /api/tokens", apiCfg.ListPersonalAccessTokensHandler)
+	mux.HandleFunc("GET /api/oauth/clients", apiCfg.ListOAuthClientsHandler)
+	mux.HandleFunc("GET /api/memberships", apiCfg.ListMembershipsHandler)
+	mux.HandleFunc("GET /api/plans", apiCfg.ListPlansHandler)
+	mux.HandleFunc("GET /api/blocks", apiCfg.ListBlocksHandler)
+	mux.HandleFunc("GET /api/mutes", apiCfg.ListMutesHandler)
+	mux.HandleFunc("GET /api/muted-words", apiCfg.ListMutedWordsHandler)
+	mux.HandleFunc("GET /api/exports", apiCfg.ListExportsHandler)
+	mux.HandleFunc("GET /api/exports/{exportID}/download", apiCfg.DownloadExportHandler)
+	mux.HandleFunc("GET /api/scheduled-chirps", apiCfg.ListScheduledChirpsHandler)
+	mux.HandleFunc("GET /api/drafts", apiCfg.ListDraftsHandler)
+	mux.HandleFunc("GET /api/warnings", apiCfg.ListWarningsHandler)
+	mux.HandleFunc("GET /oauth/authorize", apiCfg.AuthorizeHandler)
+	mux.HandleFunc("GET /api/oidc/login", apiCfg.OIDCLoginHandler)
+	mux.HandleFunc("GET /api/oidc/callback", apiCfg.OIDCCallbackHandler)
+	mux.HandleFunc("GET /admin/webhooks/events", apiCfg.ListWebhookEventsHandler)
+	mux.HandleFunc("GET /admin/webhooks/events/{eventID}", apiCfg.GetWebhookEventHandler)
+	mux.HandleFunc("GET /admin/content-rules", apiCfg.ListContentRulesHandler)
+	mux.HandleFunc("GET /admin/moderation/cases", apiCfg.ListModerationCasesHandler)
+	mux.HandleFunc("GET /admin/moderation/cases/{caseID}", apiCfg.GetModerationCaseHandler)
+	mux.HandleFunc("GET /admin/moderation/actions", apiCfg.ListModerationActionsHandler)
 
 	mux.HandleFunc("POST /admin/reset", apiCfg.ResetHandler)
 	mux.HandleFunc("POST /api/users", apiCfg.CreateUsersHandler)
+	mux.HandleFunc("POST /users/{userID}/inbox", apiCfg.InboxHandler)
+	mux.HandleFunc("POST /inbox", apiCfg.InboxHandler)
 	mux.HandleFunc("POST /api/login", apiCfg.LoginHandler)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.LoginMFAHandler)
+	mux.HandleFunc("POST /api/totp/enroll", apiCfg.EnrollTOTPHandler)
+	mux.HandleFunc("POST /api/totp/confirm", apiCfg.ConfirmTOTPHandler)
+	mux.HandleFunc("POST /api/passkeys/register/begin", apiCfg.BeginPasskeyRegistrationHandler)
+	mux.HandleFunc("POST /api/passkeys/register/finish", apiCfg.FinishPasskeyRegistrationHandler)
+	mux.HandleFunc("POST /api/passkeys/login/begin", apiCfg.BeginPasskeyLoginHandler)
+	mux.HandleFunc("POST /api/passkeys/login/finish", apiCfg.FinishPasskeyLoginHandler)
 	mux.HandleFunc("POST /api/chirps", apiCfg.CreateChirpsHandler)
 	mux.HandleFunc("POST /api/refresh", apiCfg.RefreshTokenHandler)
 	mux.HandleFunc("POST /api/revoke", apiCfg.RevokeTokenHandler)
+	mux.HandleFunc("POST /api/tokens", apiCfg.CreatePersonalAccessTokenHandler)
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.CreateOAuthClientHandler)
+	mux.HandleFunc("POST /oauth/authorize", apiCfg.AuthorizeDecisionHandler)
+	mux.HandleFunc("POST /oauth/token", apiCfg.TokenHandler)
+	mux.HandleFunc("POST /oauth/introspect", apiCfg.IntrospectHandler)
+	mux.HandleFunc("POST /oauth/revoke", apiCfg.RevokeOAuthTokenHandler)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.UpdateMembershipStatusHandler)
+	mux.HandleFunc("POST /admin/webhooks/events/{eventID}/replay", apiCfg.ReplayWebhookEventHandler)
+	mux.HandleFunc("POST /admin/content-rules", apiCfg.CreateContentRuleHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", apiCfg.CreateReportHandler)
+	mux.HandleFunc("POST /api/blocks", apiCfg.BlockUserHandler)
+	mux.HandleFunc("POST /api/mutes", apiCfg.MuteUserHandler)
+	mux.HandleFunc("POST /api/muted-words", apiCfg.CreateMutedWordHandler)
+	mux.HandleFunc("POST /api/exports", apiCfg.CreateExportHandler)
+	mux.HandleFunc("POST /api/drafts", apiCfg.CreateDraftHandler)
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.PublishDraftHandler)
+	mux.HandleFunc("POST /admin/moderation/cases/{caseID}/claim", apiCfg.ClaimModerationCaseHandler)
+	mux.HandleFunc("POST /admin/moderation/cases/{caseID}/resolve", apiCfg.ResolveModerationCaseHandler)
+	mux.HandleFunc("POST /admin/users/{userID}/suspension", apiCfg.SuspendUserHandler)
+	mux.HandleFunc("POST /admin/users/{userID}/shadow-ban", apiCfg.ShadowBanUserHandler)
 
 	mux.HandleFunc("PUT /api/users", apiCfg.UpdatePasswordOrEmailHandler)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.EditChirpHandler)
+	mux.HandleFunc("PUT /admin/plans/{plan}", apiCfg.UpdatePlanHandler)
+	mux.HandleFunc("PUT /admin/content-rules/{ruleID}", apiCfg.UpdateContentRuleHandler)
+	mux.HandleFunc("PUT /api/scheduled-chirps/{chirpID}", apiCfg.UpdateScheduledChirpHandler)
+	mux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.UpdateDraftHandler)
 
+	mux.HandleFunc("DELETE /api/users", apiCfg.DeleteUserHandler)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.DeleteChirpsHandler)
+	mux.HandleFunc("DELETE /api/totp", apiCfg.DisableTOTPHandler)
+	mux.HandleFunc("DELETE /api/passkeys/{passkeyID}", apiCfg.DeletePasskeyHandler)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.RevokePersonalAccessTokenHandler)
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientID}", apiCfg.DeleteOAuthClientHandler)
+	mux.HandleFunc("DELETE /admin/content-rules/{ruleID}", apiCfg.DeleteContentRuleHandler)
+	mux.HandleFunc("DELETE /api/blocks/{userID}", apiCfg.UnblockUserHandler)
+	mux.HandleFunc("DELETE /api/mutes/{userID}", apiCfg.UnmuteUserHandler)
+	mux.HandleFunc("DELETE /api/muted-words/{wordID}", apiCfg.DeleteMutedWordHandler)
+	mux.HandleFunc("DELETE /api/scheduled-chirps/{chirpID}", apiCfg.CancelScheduledChirpHandler)
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.DeleteDraftHandler)
+	mux.HandleFunc("DELETE /admin/users/{userID}/suspension", apiCfg.UnsuspendUserHandler)
+	mux.HandleFunc("DELETE /admin/users/{userID}/shadow-ban", apiCfg.UnshadowBanUserHandler)
 
 	server := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: apiCfg.MiddlewareRejectSuspended(apiCfg.MiddlewareIdempotency(mux)),
 	}
 
 	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
 	log.Fatal(server.ListenAndServe())
 }
+
+func runAdmin(apiCfg *config.ApiConfig, args []string) {
+	err := admin.Run(context.Background(), apiCfg, args, os.Stdout, os.Stderr)
+	if errors.Is(err, admin.ErrUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chirpy admin: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// Status checks the API is up.
+func (c *Client) Status(ctx context.Context) error {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/api/healthz"})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) ListPlans(ctx context.Context) ([]Plan, error) {
+	plans := []Plan{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/plans"}, &plans)
+	return plans, err
+}
+
+func (c *Client) ListMemberships(ctx context.Context) ([]Membership, error) {
+	memberships := []Membership{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/memberships", auth: true}, &memberships)
+	return memberships, err
+}
+
+// ListWarnings lists the warnings moderators gave the user, newest first.
+func (c *Client) ListWarnings(ctx context.Context) ([]Warning, error) {
+	warnings := []Warning{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/warnings", auth: true}, &warnings)
+	return warnings, err
+}
+
+func (c *Client) ListExports(ctx context.Context) ([]Export, error) {
+	exports := []Export{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/exports", auth: true}, &exports)
+	return exports, err
+}
+
+// CreateExport starts building an archive of the user's data. Poll
+// ListExports until it's ready, then download it with DownloadExport.
+func (c *Client) CreateExport(ctx context.Context) (Export, error) {
+	export := Export{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/exports", auth: true}, &export)
+	return export, err
+}
+
+// DownloadExport streams a finished export's zip archive. The caller
+// closes it.
+func (c *Client) DownloadExport(ctx context.Context, export Export) (io.ReadCloser, error) {
+	if export.DownloadURL == "" {
+		return nil, errors.New("client: the export has no download link yet")
+	}
+	link, err := url.Parse(export.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: couldn't parse download link: %w", err)
+	}
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: link.Path, query: link.Query()})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ListRemoteNotes lists a page of the posts other servers delivered to
+// the user's inbox, newest first.
+func (c *Client) ListRemoteNotes(ctx context.Context, page Page) ([]RemoteNote, error) {
+	notes := []RemoteNote{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/remote-notes", query: page.query(), auth: true}, &notes)
+	return notes, err
+}
+
+// RemoteNotes iterates over all the remote notes ListRemoteNotes lists.
+func (c *Client) RemoteNotes(ctx context.Context) iter.Seq2[RemoteNote, error] {
+	return paginate(func(page Page) ([]RemoteNote, error) {
+		return c.ListRemoteNotes(ctx, page)
+	})
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// The methods in this file need the client's user to be an admin, or a
+// moderator for the moderation queue.
+
+func (c *Client) ListContentRules(ctx context.Context) ([]ContentRule, error) {
+	rules := []ContentRule{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/content-rules", auth: true}, &rules)
+	return rules, err
+}
+
+func (c *Client) CreateContentRule(ctx context.Context, params ContentRuleParams) (ContentRule, error) {
+	rule := ContentRule{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/admin/content-rules", body: params, auth: true}, &rule)
+	return rule, err
+}
+
+func (c *Client) UpdateContentRule(ctx context.Context, ruleID uuid.UUID, params ContentRuleParams) (ContentRule, error) {
+	rule := ContentRule{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/admin/content-rules/" + ruleID.String(), body: params, auth: true}, &rule)
+	return rule, err
+}
+
+func (c *Client) DeleteContentRule(ctx context.Context, ruleID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/content-rules/" + ruleID.String(), auth: true}, nil)
+}
+
+// ListModerationCases lists a page of the moderation queue, oldest case
+// first. An empty status lists open cases.
+func (c *Client) ListModerationCases(ctx context.Context, status string, page Page) ([]ModerationCase, error) {
+	query := page.query()
+	if status != "" {
+		query.Set("status", status)
+	}
+	cases := []ModerationCase{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/moderation/cases", query: query, auth: true}, &cases)
+	return cases, err
+}
+
+// ModerationCases iterates over all the cases ListModerationCases lists.
+func (c *Client) ModerationCases(ctx context.Context, status string) iter.Seq2[ModerationCase, error] {
+	return paginate(func(page Page) ([]ModerationCase, error) {
+		return c.ListModerationCases(ctx, status, page)
+	})
+}
+
+// GetModerationCase returns a case with its chirp, reports and actions.
+func (c *Client) GetModerationCase(ctx context.Context, caseID uuid.UUID) (ModerationCase, error) {
+	moderationCase := ModerationCase{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/moderation/cases/" + caseID.String(), auth: true}, &moderationCase)
+	return moderationCase, err
+}
+
+// ClaimModerationCase assigns an open case to the client's user.
+func (c *Client) ClaimModerationCase(ctx context.Context, caseID uuid.UUID) (ModerationCase, error) {
+	moderationCase := ModerationCase{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/admin/moderation/cases/" + caseID.String() + "/claim", auth: true}, &moderationCase)
+	return moderationCase, err
+}
+
+// ResolveModerationCase closes a case by taking action, like "dismiss" or
+// "hide_chirp".
+func (c *Client) ResolveModerationCase(ctx context.Context, caseID uuid.UUID, action, note string) (ModerationCase, error) {
+	params := struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}{Action: action, Note: note}
+	moderationCase := ModerationCase{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/admin/moderation/cases/" + caseID.String() + "/resolve", body: params, auth: true}, &moderationCase)
+	return moderationCase, err
+}
+
+// ListModerationActions lists a page of the audit trail of moderation
+// decisions, newest first.
+func (c *Client) ListModerationActions(ctx context.Context, page Page) ([]ModerationAction, error) {
+	actions := []ModerationAction{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/moderation/actions", query: page.query(), auth: true}, &actions)
+	return actions, err
+}
+
+// ModerationActions iterates over all the actions ListModerationActions
+// lists.
+func (c *Client) ModerationActions(ctx context.Context) iter.Seq2[ModerationAction, error] {
+	return paginate(func(page Page) ([]ModerationAction, error) {
+		return c.ListModerationActions(ctx, page)
+	})
+}
+
+func (c *Client) SuspendUser(ctx context.Context, userID uuid.UUID, reason string) (AccountStatus, error) {
+	return c.restrictUser(ctx, http.MethodPost, userID, "suspension", reason)
+}
+
+func (c *Client) UnsuspendUser(ctx context.Context, userID uuid.UUID, reason string) (AccountStatus, error) {
+	return c.restrictUser(ctx, http.MethodDelete, userID, "suspension", reason)
+}
+
+func (c *Client) ShadowBanUser(ctx context.Context, userID uuid.UUID, reason string) (AccountStatus, error) {
+	return c.restrictUser(ctx, http.MethodPost, userID, "shadow-ban", reason)
+}
+
+func (c *Client) UnshadowBanUser(ctx context.Context, userID uuid.UUID, reason string) (AccountStatus, error) {
+	return c.restrictUser(ctx, http.MethodDelete, userID, "shadow-ban", reason)
+}
+
+func (c *Client) restrictUser(ctx context.Context, method string, userID uuid.UUID, restriction, reason string) (AccountStatus, error) {
+	params := struct {
+		Reason string `json:"reason"`
+	}{Reason: reason}
+	status := AccountStatus{}
+	err := c.do(ctx, request{method: method, path: "/admin/users/" + userID.String() + "/" + restriction, body: params, auth: true}, &status)
+	return status, err
+}
+
+// UpdatePlan changes the entitlements of the plan named plan.Plan.
+func (c *Client) UpdatePlan(ctx context.Context, plan Plan) (Plan, error) {
+	updated := Plan{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/admin/plans/" + url.PathEscape(plan.Plan), body: plan, auth: true}, &updated)
+	return updated, err
+}
+
+// ListWebhookEvents lists a page of the payment webhook deliveries, newest
+// first. An empty status lists them all.
+func (c *Client) ListWebhookEvents(ctx context.Context, status string, page Page) ([]WebhookEvent, error) {
+	query := page.query()
+	if status != "" {
+		query.Set("status", status)
+	}
+	events := []WebhookEvent{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/webhooks/events", query: query, auth: true}, &events)
+	return events, err
+}
+
+// WebhookEvents iterates over all the events ListWebhookEvents lists.
+func (c *Client) WebhookEvents(ctx context.Context, status string) iter.Seq2[WebhookEvent, error] {
+	return paginate(func(page Page) ([]WebhookEvent, error) {
+		return c.ListWebhookEvents(ctx, status, page)
+	})
+}
+
+func (c *Client) GetWebhookEvent(ctx context.Context, eventID uuid.UUID) (WebhookEvent, error) {
+	event := WebhookEvent{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/webhooks/events/" + eventID.String(), auth: true}, &event)
+	return event, err
+}
+
+// ReplayWebhookEvent processes a recorded event again.
+func (c *Client) ReplayWebhookEvent(ctx context.Context, eventID uuid.UUID) (WebhookEvent, error) {
+	event := WebhookEvent{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/admin/webhooks/events/" + eventID.String() + "/replay", auth: true}, &event)
+	return event, err
+}
+
+// Reset deletes every user and resets the metrics. It only works on
+// servers running with PLATFORM=dev.
+func (c *Client) Reset(ctx context.Context) error {
+	resp, err := c.send(ctx, request{method: http.MethodPost, path: "/admin/reset"})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
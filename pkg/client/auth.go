@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateUser signs up a new user. It doesn't log them in.
+func (c *Client) CreateUser(ctx context.Context, email, password string) (User, error) {
+	params := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{Email: email, Password: password}
+	user := User{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/users", body: params}, &user)
+	return user, err
+}
+
+// UpdateUser changes the user's email and password.
+func (c *Client) UpdateUser(ctx context.Context, email, password string) (User, error) {
+	params := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{Email: email, Password: password}
+	user := User{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/users", body: params, auth: true}, &user)
+	return user, err
+}
+
+// DeleteUser schedules the user's account for deletion and returns when
+// it'll be deleted. Logging in before then cancels it.
+func (c *Client) DeleteUser(ctx context.Context, password string) (time.Time, error) {
+	params := struct {
+		Password string `json:"password"`
+	}{Password: password}
+	response := struct {
+		DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+	}{}
+	err := c.do(ctx, request{method: http.MethodDelete, path: "/api/users", body: params, auth: true}, &response)
+	return response.DeletionScheduledAt, err
+}
+
+// Login logs the user in with their password and stores the session's
+// tokens. For users with two-factor authentication enabled, it returns an
+// *MFARequiredError to pass to LoginMFA.
+func (c *Client) Login(ctx context.Context, email, password string) (Session, error) {
+	params := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{Email: email, Password: password}
+	response := struct {
+		Session
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/login", body: params}, &response)
+	if err != nil {
+		return Session{}, err
+	}
+	if response.MFARequired {
+		return Session{}, &MFARequiredError{Token: response.MFAToken}
+	}
+	c.SetTokens(response.Token, response.RefreshToken)
+	return response.Session, nil
+}
+
+// LoginMFA finishes logging in with a code from the user's authenticator
+// app and stores the session's tokens.
+func (c *Client) LoginMFA(ctx context.Context, mfaToken, code string) (Session, error) {
+	return c.loginMFA(ctx, mfaToken, code, "")
+}
+
+// LoginRecoveryCode finishes logging in with one of the user's recovery
+// codes, which can't be used again.
+func (c *Client) LoginRecoveryCode(ctx context.Context, mfaToken, recoveryCode string) (Session, error) {
+	return c.loginMFA(ctx, mfaToken, "", recoveryCode)
+}
+
+func (c *Client) loginMFA(ctx context.Context, mfaToken, code, recoveryCode string) (Session, error) {
+	params := struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code,omitempty"`
+		RecoveryCode string `json:"recovery_code,omitempty"`
+	}{MFAToken: mfaToken, Code: code, RecoveryCode: recoveryCode}
+	session := Session{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/login/mfa", body: params}, &session)
+	if err != nil {
+		return Session{}, err
+	}
+	c.SetTokens(session.Token, session.RefreshToken)
+	return session, nil
+}
+
+// Refresh replaces the access token with a new one from the refresh token.
+// The client does this by itself when the access token expires.
+func (c *Client) Refresh(ctx context.Context) (string, error) {
+	accessToken, _ := c.Tokens()
+	return c.refresh(ctx, accessToken)
+}
+
+// Logout revokes the refresh token and forgets the session.
+func (c *Client) Logout(ctx context.Context) error {
+	_, refreshToken := c.Tokens()
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/revoke", token: refreshToken}, nil)
+	if err != nil {
+		return err
+	}
+	c.SetTokens("", "")
+	return nil
+}
+
+// EnrollTOTP starts enabling two-factor authentication. Add the secret to
+// an authenticator app, then confirm it with ConfirmTOTP.
+func (c *Client) EnrollTOTP(ctx context.Context) (TOTPEnrollment, error) {
+	enrollment := TOTPEnrollment{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/totp/enroll", auth: true}, &enrollment)
+	return enrollment, err
+}
+
+// ConfirmTOTP enables two-factor authentication with a code from the
+// authenticator app, and returns the user's recovery codes.
+func (c *Client) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	params := struct {
+		Code string `json:"code"`
+	}{Code: code}
+	response := struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/totp/confirm", body: params, auth: true}, &response)
+	return response.RecoveryCodes, err
+}
+
+func (c *Client) DisableTOTP(ctx context.Context, code string) error {
+	params := struct {
+		Code string `json:"code"`
+	}{Code: code}
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/totp", body: params, auth: true}, nil)
+}
+
+func (c *Client) ListPersonalAccessTokens(ctx context.Context) ([]PersonalAccessToken, error) {
+	tokens := []PersonalAccessToken{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/tokens", auth: true}, &tokens)
+	return tokens, err
+}
+
+// CreatePersonalAccessToken creates a token for scripts and services. Its
+// Token is only returned this once.
+func (c *Client) CreatePersonalAccessToken(ctx context.Context, params PersonalAccessTokenParams) (PersonalAccessToken, error) {
+	token := PersonalAccessToken{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/tokens", body: params, auth: true}, &token)
+	return token, err
+}
+
+func (c *Client) RevokePersonalAccessToken(ctx context.Context, tokenID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/tokens/" + tokenID.String(), auth: true}, nil)
+}
+
+func (c *Client) ListOAuthClients(ctx context.Context) ([]OAuthClient, error) {
+	clients := []OAuthClient{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/oauth/clients", auth: true}, &clients)
+	return clients, err
+}
+
+// CreateOAuthClient registers a third-party app. The ClientSecret of a
+// confidential client is only returned this once.
+func (c *Client) CreateOAuthClient(ctx context.Context, params OAuthClientParams) (OAuthClient, error) {
+	client := OAuthClient{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/oauth/clients", body: params, auth: true}, &client)
+	return client, err
+}
+
+func (c *Client) DeleteOAuthClient(ctx context.Context, clientID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/oauth/clients/" + clientID.String(), auth: true}, nil)
+}
+
+func (c *Client) ListPasskeys(ctx context.Context) ([]Passkey, error) {
+	passkeys := []Passkey{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/passkeys", auth: true}, &passkeys)
+	return passkeys, err
+}
+
+// BeginPasskeyRegistration starts registering a passkey. Pass the
+// challenge's PublicKey options to the authenticator, and its response to
+// FinishPasskeyRegistration.
+func (c *Client) BeginPasskeyRegistration(ctx context.Context) (PasskeyChallenge, error) {
+	challenge := PasskeyChallenge{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/passkeys/register/begin", auth: true}, &challenge)
+	return challenge, err
+}
+
+func (c *Client) FinishPasskeyRegistration(ctx context.Context, challengeID uuid.UUID, name string, credential json.RawMessage) (Passkey, error) {
+	params := struct {
+		ChallengeID uuid.UUID       `json:"challenge_id"`
+		Name        string          `json:"name"`
+		Credential  json.RawMessage `json:"credential"`
+	}{ChallengeID: challengeID, Name: name, Credential: credential}
+	passkey := Passkey{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/passkeys/register/finish", body: params, auth: true}, &passkey)
+	return passkey, err
+}
+
+// BeginPasskeyLogin starts logging in with a passkey. Leave email empty to
+// let the authenticator pick a discoverable credential.
+func (c *Client) BeginPasskeyLogin(ctx context.Context, email string) (PasskeyChallenge, error) {
+	params := struct {
+		Email string `json:"email,omitempty"`
+	}{Email: email}
+	challenge := PasskeyChallenge{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/passkeys/login/begin", body: params}, &challenge)
+	return challenge, err
+}
+
+// FinishPasskeyLogin logs in with the authenticator's assertion and stores
+// the session's tokens.
+func (c *Client) FinishPasskeyLogin(ctx context.Context, challengeID uuid.UUID, credential json.RawMessage) (Session, error) {
+	params := struct {
+		ChallengeID uuid.UUID       `json:"challenge_id"`
+		Credential  json.RawMessage `json:"credential"`
+	}{ChallengeID: challengeID, Credential: credential}
+	session := Session{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/passkeys/login/finish", body: params}, &session)
+	if err != nil {
+		return Session{}, err
+	}
+	c.SetTokens(session.Token, session.RefreshToken)
+	return session, nil
+}
+
+func (c *Client) DeletePasskey(ctx context.Context, passkeyID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/passkeys/" + passkeyID.String(), auth: true}, nil)
+}
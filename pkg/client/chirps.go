@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ListChirpsParams struct {
+	// AuthorID only lists the chirps of one user.
+	AuthorID uuid.UUID
+	// Newest lists the newest chirps first, rather than the oldest.
+	Newest bool
+}
+
+// ListChirps lists chirps. When the client is logged in, the chirps of
+// users the viewer blocked or muted are left out and muted words apply.
+func (c *Client) ListChirps(ctx context.Context, params ListChirpsParams) ([]Chirp, error) {
+	query := url.Values{}
+	if params.AuthorID != uuid.Nil {
+		query.Set("author_id", params.AuthorID.String())
+	}
+	if params.Newest {
+		query.Set("sort", "desc")
+	}
+	chirps := []Chirp{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/chirps", query: query, auth: true}, &chirps)
+	return chirps, err
+}
+
+func (c *Client) GetChirp(ctx context.Context, chirpID uuid.UUID) (Chirp, error) {
+	chirp := Chirp{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/chirps/" + chirpID.String(), auth: true}, &chirp)
+	return chirp, err
+}
+
+// CreateChirp posts a chirp. When the user is posting too fast, the
+// *APIError matches ErrRateLimited and says when to try again.
+func (c *Client) CreateChirp(ctx context.Context, body string) (Chirp, error) {
+	return c.CreateChirpWithKey(ctx, "", body)
+}
+
+// CreateChirpWithKey is CreateChirp with an idempotency key, like a random
+// UUID. Calling it again with the same key and body, e.g. after a network
+// error, returns the chirp the first call posted instead of posting it
+// twice. Reusing a key for a different body fails.
+func (c *Client) CreateChirpWithKey(ctx context.Context, idempotencyKey, body string) (Chirp, error) {
+	params := struct {
+		Body string `json:"body"`
+	}{Body: body}
+	chirp := Chirp{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/chirps", body: params, auth: true, idempotencyKey: idempotencyKey}, &chirp)
+	return chirp, err
+}
+
+// EditChirp changes a chirp's body, within the edit window of the user's
+// plan.
+func (c *Client) EditChirp(ctx context.Context, chirpID uuid.UUID, body string) (Chirp, error) {
+	params := struct {
+		Body string `json:"body"`
+	}{Body: body}
+	chirp := Chirp{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/chirps/" + chirpID.String(), body: params, auth: true}, &chirp)
+	return chirp, err
+}
+
+func (c *Client) DeleteChirp(ctx context.Context, chirpID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/chirps/" + chirpID.String(), auth: true}, nil)
+}
+
+// ScheduleChirp posts a chirp that goes live at publishAt. Scheduling needs
+// a plan that includes it.
+func (c *Client) ScheduleChirp(ctx context.Context, body string, publishAt time.Time) (Chirp, error) {
+	params := struct {
+		Body      string    `json:"body"`
+		PublishAt time.Time `json:"publish_at"`
+	}{Body: body, PublishAt: publishAt}
+	chirp := Chirp{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/chirps", body: params, auth: true}, &chirp)
+	return chirp, err
+}
+
+// ListScheduledChirps lists the user's chirps that aren't live yet, the next
+// one to go live first.
+func (c *Client) ListScheduledChirps(ctx context.Context) ([]Chirp, error) {
+	chirps := []Chirp{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/scheduled-chirps", auth: true}, &chirps)
+	return chirps, err
+}
+
+// UpdateScheduledChirp changes the body or the publishing time of a chirp
+// that isn't live yet. An empty body or a zero publishAt keeps the current
+// value.
+func (c *Client) UpdateScheduledChirp(ctx context.Context, chirpID uuid.UUID, body string, publishAt time.Time) (Chirp, error) {
+	params := struct {
+		Body      *string    `json:"body,omitempty"`
+		PublishAt *time.Time `json:"publish_at,omitempty"`
+	}{}
+	if body != "" {
+		params.Body = &body
+	}
+	if !publishAt.IsZero() {
+		params.PublishAt = &publishAt
+	}
+	chirp := Chirp{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/scheduled-chirps/" + chirpID.String(), body: params, auth: true}, &chirp)
+	return chirp, err
+}
+
+// CancelScheduledChirp deletes a chirp that isn't live yet.
+func (c *Client) CancelScheduledChirp(ctx context.Context, chirpID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/scheduled-chirps/" + chirpID.String(), auth: true}, nil)
+}
+
+func (c *Client) CreateDraft(ctx context.Context, body string) (Draft, error) {
+	params := struct {
+		Body string `json:"body"`
+	}{Body: body}
+	draft := Draft{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/drafts", body: params, auth: true}, &draft)
+	return draft, err
+}
+
+// ListDrafts lists the user's drafts, the most recently saved first.
+func (c *Client) ListDrafts(ctx context.Context) ([]Draft, error) {
+	drafts := []Draft{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/drafts", auth: true}, &drafts)
+	return drafts, err
+}
+
+// SaveDraft replaces a draft's body.
+func (c *Client) SaveDraft(ctx context.Context, draftID uuid.UUID, body string) (Draft, error) {
+	params := struct {
+		Body string `json:"body"`
+	}{Body: body}
+	draft := Draft{}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/api/drafts/" + draftID.String(), body: params, auth: true}, &draft)
+	return draft, err
+}
+
+func (c *Client) DeleteDraft(ctx context.Context, draftID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/drafts/" + draftID.String(), auth: true}, nil)
+}
+
+// PublishDraft posts a draft as a chirp and deletes the draft. A non-zero
+// publishAt schedules the chirp instead, like ScheduleChirp. Publishing
+// fails with a conflict if the draft is saved again meanwhile.
+func (c *Client) PublishDraft(ctx context.Context, draftID uuid.UUID, publishAt time.Time) (Chirp, error) {
+	params := struct {
+		PublishAt *time.Time `json:"publish_at,omitempty"`
+	}{}
+	if !publishAt.IsZero() {
+		params.PublishAt = &publishAt
+	}
+	chirp := Chirp{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/drafts/" + draftID.String() + "/publish", body: params, auth: true}, &chirp)
+	return chirp, err
+}
+
+// ReportChirp reports a chirp to the moderators.
+func (c *Client) ReportChirp(ctx context.Context, chirpID uuid.UUID, reason, details string) (Report, error) {
+	params := struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}{Reason: reason, Details: details}
+	report := Report{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/chirps/" + chirpID.String() + "/reports", body: params, auth: true}, &report)
+	return report, err
+}
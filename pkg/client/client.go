@@ -0,0 +1,219 @@
+// Package client is a Go client for the Chirpy API.
+//
+// A Client holds the session of one user. Logging in stores the access
+// token and refresh token it returns, and requests that fail because the
+// access token expired are retried once with a new access token from
+// /api/refresh:
+//
+//	c := client.New("https://chirpy.example")
+//	_, err := c.Login(ctx, "alice@example.com", "password")
+//	var mfa *client.MFARequiredError
+//	if errors.As(err, &mfa) {
+//		_, err = c.LoginMFA(ctx, mfa.Token, code)
+//	}
+//	chirp, err := c.CreateChirp(ctx, "Hello, world")
+//
+// Errors the API responds with are *APIError values, which match the
+// sentinel errors like ErrNotFound with errors.Is.
+//
+// The client covers the endpoints people and services call on behalf of a
+// user. The ActivityPub, OAuth provider and payment webhook endpoints, the
+// feeds and the admin metrics page are meant for other servers, browsers
+// and feed readers, and aren't wrapped.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client calls the Chirpy API at BaseURL. It's safe for concurrent use.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// OnRefresh, if set, is called with the new access token whenever the
+	// client refreshes it, so that callers can persist the session.
+	OnRefresh func(accessToken string)
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetTokens sets the session the client authenticates with. The access
+// token may be a session JWT or a personal access token; without a refresh
+// token, the client doesn't refresh it.
+func (c *Client) SetTokens(accessToken, refreshToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessToken = accessToken
+	c.refreshToken = refreshToken
+}
+
+// Tokens returns the session's current access token and refresh token.
+func (c *Client) Tokens() (accessToken, refreshToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.accessToken, c.refreshToken
+}
+
+// request describes an API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// body is encoded as JSON unless it's nil.
+	body any
+	// auth sends the session's access token.
+	auth bool
+	// token is sent as the bearer token instead of the access token.
+	token string
+	// idempotencyKey lets the server recognize a retry of the request.
+	idempotencyKey string
+}
+
+// do sends req and decodes the JSON response into out, unless out is nil.
+// When an authenticated request is rejected with 401 and the client has a
+// refresh token, it refreshes the access token and tries once more.
+func (c *Client) do(ctx context.Context, req request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, out)
+}
+
+// send sends req, refreshing the access token if it expired, and returns
+// the successful response.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("client: couldn't encode request: %w", err)
+		}
+	}
+
+	accessToken, refreshToken := c.Tokens()
+	resp, err := c.roundTrip(ctx, req, body, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized || !req.auth || req.token != "" || refreshToken == "" {
+		return checkResponse(resp)
+	}
+	resp.Body.Close()
+
+	accessToken, err = c.refresh(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = c.roundTrip(ctx, req, body, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	return checkResponse(resp)
+}
+
+func (c *Client) roundTrip(ctx context.Context, req request, body []byte, accessToken string) (*http.Response, error) {
+	target := c.BaseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if req.idempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", req.idempotencyKey)
+	}
+	switch {
+	case req.token != "":
+		httpReq.Header.Set("Authorization", "Bearer "+req.token)
+	case req.auth && accessToken != "":
+		httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("client: %s %s: %w", req.method, req.path, err)
+	}
+	return resp, nil
+}
+
+// refresh gets a new access token to replace expired. When another call
+// already replaced it, that token is used instead.
+func (c *Client) refresh(ctx context.Context, expired string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken != expired {
+		return c.accessToken, nil
+	}
+
+	response := struct {
+		Token string `json:"token"`
+	}{}
+	resp, err := c.roundTrip(ctx, request{method: http.MethodPost, path: "/api/refresh", token: c.refreshToken}, nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	err = decodeResponse(resp, &response)
+	if err != nil {
+		return "", fmt.Errorf("client: couldn't refresh the access token: %w", err)
+	}
+
+	c.accessToken = response.Token
+	if c.OnRefresh != nil {
+		c.OnRefresh(response.Token)
+	}
+	return response.Token, nil
+}
+
+// checkResponse returns resp if it succeeded, and its error otherwise.
+func checkResponse(resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, newAPIError(resp)
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	if resp.StatusCode >= 400 {
+		return newAPIError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	err := json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("client: couldn't decode response: %w", err)
+	}
+	return nil
+}
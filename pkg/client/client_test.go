@@ -0,0 +1,322 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// server is a stand-in for the API that hands out access tokens which are
+// rejected until the client refreshes them.
+type server struct {
+	*httptest.Server
+	mu          sync.Mutex
+	validToken  string
+	refreshes   atomic.Int32
+	mfaRequired bool
+}
+
+func newServer(t *testing.T) *server {
+	s := &server{validToken: "access-1"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/login", func(w http.ResponseWriter, r *http.Request) {
+		if s.mfaRequired {
+			writeJSON(w, http.StatusOK, map[string]any{"mfa_required": true, "mfa_token": "mfa"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"email": "a@example.com", "token": "access-0", "refresh_token": "refresh"})
+	})
+	mux.HandleFunc("POST /api/login/mfa", func(w http.ResponseWriter, r *http.Request) {
+		params := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&params)
+		if params["mfa_token"] != "mfa" || params["code"] != "123456" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid code"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"email": "a@example.com", "token": "access-1", "refresh_token": "refresh"})
+	})
+	mux.HandleFunc("POST /api/refresh", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer refresh" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Refresh token doesn't exist"})
+			return
+		}
+		s.refreshes.Add(1)
+		// Give concurrent requests time to pile up behind the refresh.
+		time.Sleep(10 * time.Millisecond)
+		writeJSON(w, http.StatusOK, map[string]string{"token": "access-1"})
+	})
+	mux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Couldn't validate token"})
+			return
+		}
+		params := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&params)
+		writeJSON(w, http.StatusCreated, map[string]string{"id": uuid.NewString(), "body": params["body"]})
+	})
+	mux.HandleFunc("GET /api/remote-notes", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		notes := []RemoteNote{}
+		for i := offset; i < min(offset+limit, 230); i++ {
+			notes = append(notes, RemoteNote{Content: strconv.Itoa(i)})
+		}
+		writeJSON(w, http.StatusOK, notes)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *server) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return r.Header.Get("Authorization") == "Bearer "+s.validToken
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func TestRefreshesExpiredAccessToken(t *testing.T) {
+	s := newServer(t)
+	c := New(s.URL)
+	refreshed := ""
+	c.OnRefresh = func(accessToken string) { refreshed = accessToken }
+
+	session, err := c.Login(context.Background(), "a@example.com", "password")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if session.Token != "access-0" {
+		t.Errorf("Token = %q, want access-0", session.Token)
+	}
+
+	// The server only accepts access-1, so the chirp is posted after
+	// refreshing.
+	chirp, err := c.CreateChirp(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	if chirp.Body != "hello" {
+		t.Errorf("Body = %q, want hello", chirp.Body)
+	}
+	if accessToken, _ := c.Tokens(); accessToken != "access-1" || refreshed != "access-1" {
+		t.Errorf("access token = %q, refreshed %q, want access-1", accessToken, refreshed)
+	}
+	if got := s.refreshes.Load(); got != 1 {
+		t.Errorf("refreshes = %d, want 1", got)
+	}
+}
+
+func TestRefreshesOnceForConcurrentRequests(t *testing.T) {
+	s := newServer(t)
+	c := New(s.URL)
+	c.SetTokens("expired", "refresh")
+
+	wg := sync.WaitGroup{}
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.CreateChirp(context.Background(), "hello")
+			if err != nil {
+				t.Errorf("CreateChirp() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := s.refreshes.Load(); got != 1 {
+		t.Errorf("refreshes = %d, want 1", got)
+	}
+}
+
+func TestRefreshFailure(t *testing.T) {
+	s := newServer(t)
+	c := New(s.URL)
+	c.SetTokens("expired", "revoked")
+
+	_, err := c.CreateChirp(context.Background(), "hello")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("CreateChirp() error = %v, want ErrUnauthorized", err)
+	}
+
+	// Without a refresh token, the first 401 is returned as it is.
+	c.SetTokens("expired", "")
+	_, err = c.CreateChirp(context.Background(), "hello")
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) || apiErr.Message != "Couldn't validate token" {
+		t.Errorf("CreateChirp() error = %v, want the server's message", err)
+	}
+	if got := s.refreshes.Load(); got != 0 {
+		t.Errorf("refreshes = %d, want 0", got)
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	s := newServer(t)
+	s.mfaRequired = true
+	c := New(s.URL)
+
+	_, err := c.Login(context.Background(), "a@example.com", "password")
+	var mfa *MFARequiredError
+	if !errors.As(err, &mfa) {
+		t.Fatalf("Login() error = %v, want an *MFARequiredError", err)
+	}
+	if accessToken, _ := c.Tokens(); accessToken != "" {
+		t.Errorf("access token = %q before the second factor", accessToken)
+	}
+
+	_, err = c.LoginMFA(context.Background(), mfa.Token, "000000")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("LoginMFA() error = %v, want ErrUnauthorized", err)
+	}
+	_, err = c.LoginMFA(context.Background(), mfa.Token, "123456")
+	if err != nil {
+		t.Fatalf("LoginMFA() error = %v", err)
+	}
+	if accessToken, refreshToken := c.Tokens(); accessToken != "access-1" || refreshToken != "refresh" {
+		t.Errorf("Tokens() = %q, %q, want the session's tokens", accessToken, refreshToken)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		header         http.Header
+		body           string
+		wantErr        error
+		wantMessage    string
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:        "Not found",
+			status:      http.StatusNotFound,
+			body:        `{"error":"Couldn't find chirp"}`,
+			wantErr:     ErrNotFound,
+			wantMessage: "Couldn't find chirp",
+		},
+		{
+			name:           "Rate limited",
+			status:         http.StatusTooManyRequests,
+			header:         http.Header{"Retry-After": {"12"}},
+			body:           `{"error":"Too many chirps"}`,
+			wantErr:        ErrRateLimited,
+			wantMessage:    "Too many chirps",
+			wantRetryAfter: 12 * time.Second,
+		},
+		{
+			name:        "Not JSON",
+			status:      http.StatusBadGateway,
+			body:        "<html>upstream down</html>",
+			wantErr:     ErrServer,
+			wantMessage: "Bad Gateway",
+		},
+		{
+			name:        "No sentinel",
+			status:      http.StatusGone,
+			body:        `{"error":"Gone"}`,
+			wantErr:     nil,
+			wantMessage: "Gone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for key, values := range tt.header {
+					w.Header()[key] = values
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			_, err := New(srv.URL).GetChirp(context.Background(), uuid.New())
+			apiErr := &APIError{}
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("GetChirp() error = %v, want an *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMessage || apiErr.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("APIError = %+v, want status %d, message %q, retry after %v", apiErr, tt.status, tt.wantMessage, tt.wantRetryAfter)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetChirp() error = %v, want %v", err, tt.wantErr)
+			}
+			if apiErr.Unwrap() == nil && tt.wantErr != nil {
+				t.Errorf("Unwrap() = nil, want %v", tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPagination(t *testing.T) {
+	s := newServer(t)
+	c := New(s.URL)
+
+	count := 0
+	for note, err := range c.RemoteNotes(context.Background()) {
+		if err != nil {
+			t.Fatalf("RemoteNotes() error = %v", err)
+		}
+		if note.Content != strconv.Itoa(count) {
+			t.Fatalf("note %d = %q, want them in order", count, note.Content)
+		}
+		count++
+	}
+	if count != 230 {
+		t.Errorf("RemoteNotes() yielded %d notes, want 230", count)
+	}
+
+	count = 0
+	for _, err := range c.RemoteNotes(context.Background()) {
+		if err != nil {
+			t.Fatalf("RemoteNotes() error = %v", err)
+		}
+		count++
+		if count == 150 {
+			break
+		}
+	}
+	if count != 150 {
+		t.Errorf("RemoteNotes() yielded %d notes after breaking, want 150", count)
+	}
+}
+
+func TestPaginationError(t *testing.T) {
+	calls := 0
+	notes := paginate(func(page Page) ([]int, error) {
+		calls++
+		if page.Offset > 0 {
+			return nil, fmt.Errorf("page at %d failed", page.Offset)
+		}
+		return make([]int, page.Limit), nil
+	})
+
+	count := 0
+	var gotErr error
+	for _, err := range notes {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		count++
+	}
+	if count != pageSize || gotErr == nil || calls != 2 {
+		t.Errorf("yielded %d items, error %v after %d calls, want %d items then an error", count, gotErr, calls, pageSize)
+	}
+}
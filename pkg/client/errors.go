@@ -0,0 +1,88 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The errors an *APIError matches with errors.Is, by its status code.
+var (
+	ErrBadRequest   = errors.New("client: bad request")
+	ErrUnauthorized = errors.New("client: unauthorized")
+	ErrForbidden    = errors.New("client: forbidden")
+	ErrNotFound     = errors.New("client: not found")
+	ErrConflict     = errors.New("client: conflict")
+	ErrRateLimited  = errors.New("client: rate limited")
+	ErrServer       = errors.New("client: server error")
+)
+
+// maxErrorSize bounds how much of an error response is read.
+const maxErrorSize = 64 << 10
+
+// APIError is an error response from the API.
+type APIError struct {
+	StatusCode int
+	// Message is the "error" the API responded with, or the response's
+	// status text when it didn't send one.
+	Message string
+	// RetryAfter is how long the API asked the client to wait before
+	// trying again, if it did.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("client: %d %s", e.StatusCode, e.Message)
+}
+
+// Unwrap returns the sentinel error for e's status code, if there is one.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// newAPIError reads the error response the API's respondWithError writes.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	body := struct {
+		Error string `json:"error"`
+	}{}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorSize))
+	if err == nil && json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Message = body.Error
+	}
+	return apiErr
+}
+
+// MFARequiredError is returned by Login for users with two-factor
+// authentication enabled. Finish logging in with LoginMFA.
+type MFARequiredError struct {
+	Token string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "client: a second factor is required to log in"
+}
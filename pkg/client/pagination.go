@@ -0,0 +1,61 @@
+package client
+
+import (
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// pageSize is how many items iterators fetch at a time, the most the API
+// returns in one page.
+const pageSize = 100
+
+// Page selects a page of a list. The API returns 50 items by default, and
+// at most 100.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+func (p Page) query() url.Values {
+	query := url.Values{}
+	if p.Limit > 0 {
+		query.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Offset > 0 {
+		query.Set("offset", strconv.Itoa(p.Offset))
+	}
+	return query
+}
+
+// paginate iterates over every item of a paginated list, fetching pages
+// until one comes back short. It stops after yielding the first error:
+//
+//	for note, err := range c.RemoteNotes(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func paginate[T any](list func(page Page) ([]T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		page := Page{Limit: pageSize}
+		for {
+			items, err := list(page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(items) < page.Limit {
+				return
+			}
+			page.Offset += len(items)
+		}
+	}
+}
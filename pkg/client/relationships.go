@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func (c *Client) ListBlocks(ctx context.Context) ([]UserRelationship, error) {
+	return c.listRelationships(ctx, "/api/blocks")
+}
+
+// BlockUser hides the user's chirps from the client's user, and theirs
+// from the user.
+func (c *Client) BlockUser(ctx context.Context, userID uuid.UUID) error {
+	return c.addRelationship(ctx, "/api/blocks", userID)
+}
+
+func (c *Client) UnblockUser(ctx context.Context, userID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/blocks/" + userID.String(), auth: true}, nil)
+}
+
+func (c *Client) ListMutes(ctx context.Context) ([]UserRelationship, error) {
+	return c.listRelationships(ctx, "/api/mutes")
+}
+
+// MuteUser hides the user's chirps from the client's user without them
+// knowing.
+func (c *Client) MuteUser(ctx context.Context, userID uuid.UUID) error {
+	return c.addRelationship(ctx, "/api/mutes", userID)
+}
+
+func (c *Client) UnmuteUser(ctx context.Context, userID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/mutes/" + userID.String(), auth: true}, nil)
+}
+
+func (c *Client) listRelationships(ctx context.Context, path string) ([]UserRelationship, error) {
+	relationships := []UserRelationship{}
+	err := c.do(ctx, request{method: http.MethodGet, path: path, auth: true}, &relationships)
+	return relationships, err
+}
+
+func (c *Client) addRelationship(ctx context.Context, path string, userID uuid.UUID) error {
+	params := struct {
+		UserID uuid.UUID `json:"user_id"`
+	}{UserID: userID}
+	return c.do(ctx, request{method: http.MethodPost, path: path, body: params, auth: true}, nil)
+}
+
+func (c *Client) ListMutedWords(ctx context.Context) ([]MutedWord, error) {
+	words := []MutedWord{}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/api/muted-words", auth: true}, &words)
+	return words, err
+}
+
+func (c *Client) CreateMutedWord(ctx context.Context, params MutedWordParams) (MutedWord, error) {
+	word := MutedWord{}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/api/muted-words", body: params, auth: true}, &word)
+	return word, err
+}
+
+func (c *Client) DeleteMutedWord(ctx context.Context, wordID uuid.UUID) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/api/muted-words/" + wordID.String(), auth: true}, nil)
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type User struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+// Session is a logged in user, with the tokens the client authenticates
+// with from then on.
+type Session struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type Chirp struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+	UserID    uuid.UUID `json:"user_id"`
+	// Filtered is set on chirps that match one of the viewer's muted words
+	// with the "warn" action.
+	Filtered bool `json:"filtered,omitempty"`
+	// PublishAt is set on the user's own chirps that aren't live yet.
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+type Draft struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+// Report is a report about a chirp. ChirpID is nil once the chirp has been
+// deleted.
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Warning is a moderator's warning about one of the user's chirps.
+type Warning struct {
+	ID        uuid.UUID  `json:"id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	ChirpBody string     `json:"chirp_body"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// UserRelationship is a user the client's user blocked or muted.
+type UserRelationship struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MutedWord struct {
+	ID        uuid.UUID  `json:"id"`
+	Phrase    string     `json:"phrase"`
+	WholeWord bool       `json:"whole_word"`
+	Action    string     `json:"action"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type MutedWordParams struct {
+	Phrase    string `json:"phrase"`
+	WholeWord bool   `json:"whole_word"`
+	// Action is "hide" or "warn". The API hides matching chirps by
+	// default.
+	Action           string `json:"action,omitempty"`
+	ExpiresInSeconds int64  `json:"expires_in_seconds,omitempty"`
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	// Token is only returned when the token is created.
+	Token string `json:"token,omitempty"`
+}
+
+type PersonalAccessTokenParams struct {
+	Name             string   `json:"name"`
+	Scopes           []string `json:"scopes"`
+	ExpiresInSeconds int64    `json:"expires_in_seconds,omitempty"`
+}
+
+type OAuthClient struct {
+	ClientID     uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+	// ClientSecret is only returned when a confidential client is
+	// registered.
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type OAuthClientParams struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Confidential bool     `json:"confidential"`
+}
+
+type Passkey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// PasskeyChallenge is a WebAuthn ceremony started by the API. PublicKey
+// holds the options to pass to the authenticator.
+type PasskeyChallenge struct {
+	ChallengeID uuid.UUID       `json:"challenge_id"`
+	PublicKey   json.RawMessage `json:"public_key"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type Export struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Error       string     `json:"error,omitempty"`
+	// DownloadURL is a signed link to the archive, relative to the API.
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+type Membership struct {
+	ID          uuid.UUID  `json:"id"`
+	Plan        string     `json:"plan"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	CancelledAt *time.Time `json:"cancelled_at"`
+}
+
+type Plan struct {
+	Plan              string `json:"plan"`
+	MaxChirpLength    int32  `json:"max_chirp_length"`
+	EditWindowSeconds int32  `json:"edit_window_seconds"`
+	ChirpsPerMinute   int32  `json:"chirps_per_minute"`
+	CanSchedule       bool   `json:"can_schedule"`
+}
+
+// RemoteNote is a post from an account on another server that the
+// client's user follows.
+type RemoteNote struct {
+	ID            uuid.UUID `json:"id"`
+	URI           string    `json:"uri"`
+	ActorURI      string    `json:"actor_uri"`
+	ActorUsername string    `json:"actor_username"`
+	Content       string    `json:"content"`
+	InReplyTo     string    `json:"in_reply_to,omitempty"`
+	PublishedAt   time.Time `json:"published_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type ContentRule struct {
+	ID        uuid.UUID `json:"id"`
+	Kind      string    `json:"kind"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ContentRuleParams struct {
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+}
+
+// ModerationCase is a reported chirp's reports and what was decided.
+// ChirpID is nil once the chirp has been deleted; AuthorID and ChirpBody
+// keep who wrote it and what it said when it was first reported.
+type ModerationCase struct {
+	ID          uuid.UUID          `json:"id"`
+	ChirpID     *uuid.UUID         `json:"chirp_id"`
+	AuthorID    *uuid.UUID         `json:"author_id"`
+	ChirpBody   string             `json:"chirp_body"`
+	Status      string             `json:"status"`
+	ReportCount int64              `json:"report_count"`
+	ClaimedBy   *uuid.UUID         `json:"claimed_by"`
+	ClaimedAt   *time.Time         `json:"claimed_at"`
+	ResolvedAt  *time.Time         `json:"resolved_at"`
+	Resolution  string             `json:"resolution,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	Chirp       *Chirp             `json:"chirp,omitempty"`
+	Reports     []Report           `json:"reports,omitempty"`
+	Actions     []ModerationAction `json:"actions,omitempty"`
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID  `json:"id"`
+	CaseID       *uuid.UUID `json:"case_id"`
+	ModeratorID  *uuid.UUID `json:"moderator_id"`
+	Action       string     `json:"action"`
+	TargetUserID *uuid.UUID `json:"target_user_id"`
+	ChirpID      *uuid.UUID `json:"chirp_id"`
+	Note         string     `json:"note"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type AccountStatus struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	SuspendedAt    *time.Time `json:"suspended_at"`
+	ShadowBannedAt *time.Time `json:"shadow_banned_at"`
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID       `json:"id"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	Provider    string          `json:"provider"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	Error       string          `json:"error,omitempty"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
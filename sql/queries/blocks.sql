@@ -0,0 +1,26 @@
+-- name: CreateBlock :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetBlocksByBlockerID :many
+SELECT * FROM blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteBlock :execrows
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE blocker_id = $1 AND blocked_id = $2
+);
+
+-- name: GetHiddenAuthorIDs :many
+SELECT blocked_id AS user_id FROM blocks WHERE blocks.blocker_id = $1
+UNION
+SELECT blocker_id AS user_id FROM blocks WHERE blocks.blocked_id = $1
+UNION
+SELECT muted_id AS user_id FROM mutes WHERE mutes.muter_id = $1;
@@ -7,12 +7,103 @@ RETURNING *;
 
 -- name: GetChirps :many
 SELECT * FROM chirps
+WHERE hidden_at IS NULL AND publish_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetChirpByID :one
 SELECT * FROM chirps
-WHERE id = $1;
+WHERE id = $1 AND publish_at IS NULL;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps *
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: HideChirp :execrows
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1 AND hidden_at IS NULL;
+
+-- name: UnhideChirp :exec
+UPDATE chirps
+SET hidden_at = NULL
+WHERE id = $1;
+
+-- name: GetChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetPublicChirps :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.hidden_at IS NULL AND chirps.publish_at IS NULL
+AND users.suspended_at IS NULL AND users.shadow_banned_at IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT $1;
+
+-- name: GetPublicChirpsByUserID :many
+SELECT chirps.* FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = $1 AND chirps.hidden_at IS NULL AND chirps.publish_at IS NULL
+AND users.suspended_at IS NULL AND users.shadow_banned_at IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT $2;
+
+-- name: GetPublishedChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND hidden_at IS NULL AND publish_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: CountPublicChirpsByUserID :one
+SELECT COUNT(*) FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = $1 AND chirps.hidden_at IS NULL AND chirps.publish_at IS NULL
+AND users.suspended_at IS NULL AND users.shadow_banned_at IS NULL;
+
+-- name: CountChirpsByUserID :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND publish_at IS NULL;
+
+-- name: ScheduleChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, publish_at)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetScheduledChirpsByUserID :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND publish_at IS NOT NULL
+ORDER BY publish_at ASC;
+
+-- name: GetScheduledChirpByID :one
+SELECT * FROM chirps
+WHERE id = $1 AND publish_at IS NOT NULL;
+
+-- name: UpdateScheduledChirp :one
+UPDATE chirps
+SET body = $2, publish_at = $3, updated_at = NOW()
+WHERE id = $1 AND publish_at IS NOT NULL
+RETURNING *;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps
+WHERE id = $1 AND publish_at IS NOT NULL;
+
+-- name: PublishDueChirp :one
+UPDATE chirps
+SET publish_at = NULL, created_at = NOW(), updated_at = NOW()
+WHERE id = (
+    SELECT id FROM chirps
+    WHERE publish_at <= NOW()
+    ORDER BY publish_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
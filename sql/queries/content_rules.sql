@@ -0,0 +1,20 @@
+-- name: CreateContentRule :one
+INSERT INTO content_rules (id, created_at, updated_at, kind, pattern, action)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetContentRules :many
+SELECT * FROM content_rules
+ORDER BY created_at ASC;
+
+-- name: UpdateContentRule :one
+UPDATE content_rules
+SET kind = $2, pattern = $3, action = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteContentRule :execrows
+DELETE FROM content_rules
+WHERE id = $1;
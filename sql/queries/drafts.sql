@@ -0,0 +1,35 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, body, user_id)
+SELECT gen_random_uuid(), NOW(), NOW(), $1, $2
+WHERE (SELECT COUNT(*) FROM drafts WHERE user_id = $2) < sqlc.arg(max_drafts)::integer
+RETURNING *;
+
+-- name: GetDraftsByUserID :many
+SELECT * FROM drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC;
+
+-- name: GetDraftByID :one
+SELECT * FROM drafts
+WHERE id = $1;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDraft :exec
+DELETE FROM drafts
+WHERE id = $1;
+
+-- name: PublishDraft :one
+WITH draft AS (
+    DELETE FROM drafts
+    WHERE drafts.id = $1 AND drafts.updated_at = $2
+    RETURNING drafts.user_id
+)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, publish_at)
+SELECT gen_random_uuid(), NOW(), NOW(), $3, draft.user_id, $4
+FROM draft
+RETURNING *;
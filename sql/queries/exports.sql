@@ -0,0 +1,48 @@
+-- name: CreateExport :one
+INSERT INTO exports (id, created_at, updated_at, user_id, status)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, 'pending'
+)
+ON CONFLICT (user_id) WHERE status IN ('pending', 'processing') DO NOTHING
+RETURNING *;
+
+-- name: ClaimPendingExport :one
+UPDATE exports
+SET status = 'processing', updated_at = NOW()
+WHERE id = (
+    SELECT id FROM exports
+    WHERE status = 'pending'
+    OR (status = 'processing' AND updated_at < NOW() - INTERVAL '1 hour')
+    ORDER BY created_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishExport :exec
+UPDATE exports
+SET status = 'ready', blob_key = $2, expires_at = $3, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailExport :exec
+UPDATE exports
+SET status = 'failed', error = $2, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetExport :one
+SELECT * FROM exports
+WHERE id = $1;
+
+-- name: GetExportsByUserID :many
+SELECT * FROM exports
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteExpiredExports :many
+DELETE FROM exports
+WHERE expires_at <= NOW()
+RETURNING blob_key;
+
+-- name: GetExportBlobKeysByUserID :many
+SELECT blob_key FROM exports
+WHERE user_id = $1 AND blob_key IS NOT NULL;
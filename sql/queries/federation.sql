@@ -0,0 +1,136 @@
+-- name: GetActorKey :one
+SELECT * FROM actor_keys
+WHERE user_id = $1;
+
+-- name: CreateActorKey :one
+INSERT INTO actor_keys (user_id, created_at, public_key_pem, private_key_pem)
+VALUES ($1, NOW(), $2, $3)
+ON CONFLICT (user_id) DO NOTHING
+RETURNING *;
+
+-- name: GetRemoteActorByURI :one
+SELECT * FROM remote_actors
+WHERE uri = $1;
+
+-- name: GetRemoteActorByKeyID :one
+SELECT * FROM remote_actors
+WHERE key_id = $1;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO remote_actors (id, created_at, updated_at, uri, preferred_username, inbox, shared_inbox, key_id, public_key_pem, fetched_at)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, NOW()
+)
+ON CONFLICT (uri) DO UPDATE
+SET preferred_username = EXCLUDED.preferred_username,
+    inbox = EXCLUDED.inbox,
+    shared_inbox = EXCLUDED.shared_inbox,
+    key_id = EXCLUDED.key_id,
+    public_key_pem = EXCLUDED.public_key_pem,
+    fetched_at = NOW(),
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteRemoteActor :execrows
+DELETE FROM remote_actors
+WHERE id = $1;
+
+-- name: CreateRemoteFollower :exec
+INSERT INTO remote_followers (user_id, actor_id, created_at, follow_uri)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (user_id, actor_id) DO UPDATE
+SET follow_uri = EXCLUDED.follow_uri;
+
+-- name: DeleteRemoteFollower :execrows
+DELETE FROM remote_followers
+WHERE user_id = $1 AND actor_id = $2;
+
+-- name: DeleteRemoteFollowerByFollowURI :execrows
+DELETE FROM remote_followers
+WHERE actor_id = $1 AND follow_uri = $2;
+
+-- name: CountRemoteFollowers :one
+SELECT COUNT(*) FROM remote_followers
+WHERE user_id = $1;
+
+-- name: GetRemoteFollowerInboxes :many
+SELECT DISTINCT remote_actors.shared_inbox FROM remote_followers
+JOIN remote_actors ON remote_actors.id = remote_followers.actor_id
+WHERE remote_followers.user_id = $1;
+
+-- name: UpsertRemoteNote :one
+INSERT INTO remote_notes (id, created_at, updated_at, uri, actor_id, content, in_reply_to, published_at)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5
+)
+ON CONFLICT (uri) DO UPDATE
+SET content = EXCLUDED.content, updated_at = NOW()
+WHERE remote_notes.actor_id = EXCLUDED.actor_id
+RETURNING *;
+
+-- name: AddRemoteNoteRecipient :exec
+INSERT INTO remote_note_recipients (note_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteRemoteNote :execrows
+DELETE FROM remote_notes
+WHERE uri = $1 AND actor_id = $2;
+
+-- name: GetRemoteNotesForUser :many
+SELECT remote_notes.id, remote_notes.uri, remote_notes.content, remote_notes.in_reply_to,
+    remote_notes.published_at, remote_notes.updated_at, remote_actors.uri AS actor_uri,
+    remote_actors.preferred_username
+FROM remote_notes
+JOIN remote_note_recipients ON remote_note_recipients.note_id = remote_notes.id
+JOIN remote_actors ON remote_actors.id = remote_notes.actor_id
+WHERE remote_note_recipients.user_id = $1
+ORDER BY remote_notes.published_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CreateFederationDelivery :exec
+INSERT INTO federation_deliveries (id, created_at, updated_at, user_id, inbox, activity, status, next_attempt_at)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, 'pending', NOW()
+);
+
+-- name: ClaimFederationDelivery :one
+UPDATE federation_deliveries
+SET status = 'delivering', updated_at = NOW()
+WHERE id = (
+    SELECT id FROM federation_deliveries
+    WHERE (status = 'pending' AND next_attempt_at <= NOW())
+    OR (status = 'delivering' AND updated_at < NOW() - INTERVAL '10 minutes')
+    ORDER BY next_attempt_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishFederationDelivery :exec
+UPDATE federation_deliveries
+SET status = 'delivered', attempts = attempts + 1, error = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryFederationDelivery :exec
+UPDATE federation_deliveries
+SET status = 'pending', attempts = attempts + 1, next_attempt_at = $2, error = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailFederationDelivery :exec
+UPDATE federation_deliveries
+SET status = 'failed', attempts = attempts + 1, error = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFinishedFederationDeliveries :execrows
+DELETE FROM federation_deliveries
+WHERE status IN ('delivered', 'failed') AND updated_at < NOW() - INTERVAL '7 days';
+
+-- name: DeleteOrphanedActorKeys :execrows
+DELETE FROM actor_keys
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE users.id = actor_keys.user_id)
+AND NOT EXISTS (
+    SELECT 1 FROM federation_deliveries
+    WHERE federation_deliveries.user_id = actor_keys.user_id
+    AND federation_deliveries.status IN ('pending', 'delivering')
+);
@@ -0,0 +1,24 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (user_id, key, created_at, fingerprint)
+VALUES (
+    $1, $2, NOW(), $3
+)
+ON CONFLICT (user_id, key) DO NOTHING
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE user_id = $1 AND key = $2;
+
+-- name: SaveIdempotentResponse :exec
+UPDATE idempotency_keys
+SET response_status = $3, response_headers = $4, response_body = $5
+WHERE user_id = $1 AND key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND key = $2 AND created_at = $3;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1;
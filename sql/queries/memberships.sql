@@ -0,0 +1,48 @@
+-- name: CreateMembership :one
+INSERT INTO memberships (id, created_at, updated_at, user_id, plan, status, started_at, ends_at, cancelled_at, source)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, 'active', NOW(), $3, null, $4
+)
+RETURNING *;
+
+-- name: GetCurrentMembership :one
+SELECT * FROM memberships
+WHERE user_id = $1 AND status = 'active';
+
+-- name: GetMembershipsByUserID :many
+SELECT * FROM memberships
+WHERE user_id = $1
+ORDER BY started_at DESC;
+
+-- name: HasActiveMembership :one
+SELECT EXISTS (
+    SELECT 1 FROM memberships
+    WHERE user_id = $1 AND status = 'active' AND (ends_at IS NULL OR ends_at > NOW())
+);
+
+-- name: UpdateMembershipPeriod :exec
+UPDATE memberships
+SET plan = $2, ends_at = $3, source = $4, cancelled_at = null, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CancelMembership :exec
+UPDATE memberships
+SET cancelled_at = NOW(),
+    status = CASE WHEN ends_at IS NULL OR ends_at <= NOW() THEN 'cancelled' ELSE status END,
+    ends_at = COALESCE(ends_at, NOW()),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: EndMembership :exec
+UPDATE memberships
+SET status = $2, ends_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: ExpireMemberships :execrows
+UPDATE memberships
+SET status = CASE WHEN cancelled_at IS NULL THEN 'expired' ELSE 'cancelled' END, updated_at = NOW()
+WHERE status = 'active' AND ends_at <= NOW();
+
+-- name: ListActiveMemberships :many
+SELECT * FROM memberships
+WHERE status = 'active';
@@ -0,0 +1,83 @@
+-- name: OpenModerationCase :one
+INSERT INTO moderation_cases (id, created_at, updated_at, chirp_id, status, author_id, chirp_body)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, 'open', $2, $3
+)
+ON CONFLICT (chirp_id) WHERE status <> 'resolved'
+DO UPDATE SET updated_at = NOW()
+RETURNING *;
+
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, case_id, chirp_id, reporter_id, reason, details)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5
+)
+ON CONFLICT (case_id, reporter_id) DO NOTHING
+RETURNING *;
+
+-- name: CountReportsForCase :one
+SELECT COUNT(*) FROM reports
+WHERE case_id = $1;
+
+-- name: GetReportsByCaseID :many
+SELECT * FROM reports
+WHERE case_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetModerationCase :one
+SELECT * FROM moderation_cases
+WHERE id = $1;
+
+-- name: ListModerationCases :many
+SELECT moderation_cases.*, (
+    SELECT COUNT(*) FROM reports WHERE reports.case_id = moderation_cases.id
+) AS report_count
+FROM moderation_cases
+WHERE status = $1
+ORDER BY created_at ASC
+LIMIT $2 OFFSET $3;
+
+-- name: ClaimModerationCase :one
+UPDATE moderation_cases
+SET status = 'claimed', claimed_by = $2, claimed_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'open'
+RETURNING *;
+
+-- name: ResolveModerationCase :one
+UPDATE moderation_cases
+SET status = 'resolved', resolution = $2, resolved_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status <> 'resolved'
+RETURNING *;
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, case_id, moderator_id, action, target_user_id, chirp_id, note)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetModerationActionsByCaseID :many
+SELECT * FROM moderation_actions
+WHERE case_id = $1
+ORDER BY created_at ASC;
+
+-- name: ListModerationActions :many
+SELECT * FROM moderation_actions
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: GetReportsByReporterID :many
+SELECT * FROM reports
+WHERE reporter_id = $1
+ORDER BY created_at ASC;
+
+-- name: CreateWarning :exec
+INSERT INTO warnings (id, created_at, user_id, case_id, chirp_id, chirp_body, note)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5
+);
+
+-- name: GetWarningsByUserID :many
+SELECT * FROM warnings
+WHERE user_id = $1
+ORDER BY created_at DESC;
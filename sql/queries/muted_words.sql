@@ -0,0 +1,19 @@
+-- name: CreateMutedWord :one
+INSERT INTO muted_words (id, created_at, user_id, phrase, whole_word, action, expires_at)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetActiveMutedWords :many
+SELECT * FROM muted_words
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at DESC;
+
+-- name: DeleteMutedWord :execrows
+DELETE FROM muted_words
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteExpiredMutedWords :execrows
+DELETE FROM muted_words
+WHERE expires_at <= NOW();
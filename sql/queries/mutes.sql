@@ -0,0 +1,13 @@
+-- name: CreateMute :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetMutesByMuterID :many
+SELECT * FROM mutes
+WHERE muter_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteMute :execrows
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
@@ -0,0 +1,45 @@
+-- name: CreateOauthClient :one
+INSERT INTO oauth_clients (id, created_at, updated_at, user_id, name, secret_hash, redirect_uris)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetOauthClientByID :one
+SELECT * FROM oauth_clients
+WHERE id = $1;
+
+-- name: GetOauthClientsByUserID :many
+SELECT * FROM oauth_clients
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteOauthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateOauthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, created_at, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES (
+    $1, NOW(), $2, $3, $4, $5, $6, $7
+);
+
+-- name: ConsumeOauthAuthorizationCode :one
+DELETE FROM oauth_authorization_codes
+WHERE code_hash = $1
+RETURNING *;
+
+-- name: RevokeOauthAccessToken :exec
+INSERT INTO oauth_revoked_access_tokens (id, expires_at)
+VALUES ($1, $2)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: IsOauthAccessTokenRevoked :one
+SELECT EXISTS (
+    SELECT 1 FROM oauth_revoked_access_tokens
+    WHERE id = $1
+);
+
+-- name: DeleteExpiredOauthRevocations :exec
+DELETE FROM oauth_revoked_access_tokens
+WHERE expires_at < NOW();
@@ -0,0 +1,30 @@
+-- name: CreateOidcLoginState :exec
+INSERT INTO oidc_login_states (state, created_at, nonce, code_verifier, expires_at)
+VALUES (
+    $1, NOW(), $2, $3, $4
+);
+
+-- name: ConsumeOidcLoginState :one
+DELETE FROM oidc_login_states
+WHERE state = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredOidcLoginStates :exec
+DELETE FROM oidc_login_states
+WHERE expires_at < NOW();
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (id, created_at, user_id, issuer, subject, email)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE issuer = $1 AND subject = $2;
+
+-- name: GetUserIdentitiesByUserID :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at ASC;
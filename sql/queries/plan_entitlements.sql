@@ -0,0 +1,26 @@
+-- name: GetUserEntitlements :one
+SELECT * FROM plan_entitlements
+WHERE plan = 'free' OR plan IN (
+    SELECT memberships.plan FROM memberships
+    WHERE memberships.user_id = $1 AND memberships.status = 'active'
+    AND (memberships.ends_at IS NULL OR memberships.ends_at > NOW())
+)
+ORDER BY plan = 'free'
+LIMIT 1;
+
+-- name: ListPlanEntitlements :many
+SELECT * FROM plan_entitlements
+ORDER BY max_chirp_length ASC;
+
+-- name: UpsertPlanEntitlements :one
+INSERT INTO plan_entitlements (plan, created_at, updated_at, max_chirp_length, edit_window_seconds, chirps_per_minute, can_schedule)
+VALUES (
+    $1, NOW(), NOW(), $2, $3, $4, $5
+)
+ON CONFLICT (plan) DO UPDATE
+SET max_chirp_length = EXCLUDED.max_chirp_length,
+    edit_window_seconds = EXCLUDED.edit_window_seconds,
+    chirps_per_minute = EXCLUDED.chirps_per_minute,
+    can_schedule = EXCLUDED.can_schedule,
+    updated_at = NOW()
+RETURNING *;
@@ -18,3 +18,37 @@ WHERE token = $1;
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
 WHERE token = $1;
+
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW();
+
+-- name: RevokeOauthRefreshToken :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE token = $1 AND client_id = $2 AND revoked_at IS NULL;
+
+-- name: CreateOauthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, client_id, scopes)
+VALUES (
+    $1,
+    NOW(),
+    NOW(),
+    $2,
+    $3,
+    null,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: RevokeRefreshTokensForUser :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: GetRefreshTokensByUserID :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,12 @@
+-- name: GetInstanceStats :one
+SELECT
+    (SELECT COUNT(*) FROM users) AS users,
+    (SELECT COUNT(*) FROM users WHERE suspended_at IS NOT NULL) AS suspended_users,
+    (SELECT COUNT(*) FROM users WHERE deletion_scheduled_at IS NOT NULL) AS users_pending_deletion,
+    (SELECT COUNT(*) FROM chirps WHERE publish_at IS NULL) AS chirps,
+    (SELECT COUNT(*) FROM chirps WHERE publish_at IS NULL AND created_at > NOW() - INTERVAL '24 hours') AS chirps_last_day,
+    (SELECT COUNT(*) FROM chirps WHERE publish_at IS NOT NULL) AS scheduled_chirps,
+    (SELECT COUNT(*) FROM memberships WHERE status = 'active') AS active_memberships,
+    (SELECT COUNT(*) FROM moderation_cases WHERE status = 'open') AS open_moderation_cases,
+    (SELECT COUNT(*) FROM remote_followers) AS remote_followers,
+    (SELECT COUNT(*) FROM federation_deliveries WHERE status = 'pending') AS pending_federation_deliveries;
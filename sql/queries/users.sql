@@ -3,7 +3,7 @@ INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
     gen_random_uuid(), NOW(), NOW(), $1, $2
 )
-RETURNING id, created_at, updated_at, email, is_chirpy_red;
+RETURNING id, created_at, updated_at, email;
 
 -- name: DeleteUsers :exec
 DELETE FROM users *;
@@ -16,9 +16,104 @@ WHERE email = $1;
 UPDATE users
 SET email = $1, hashed_password = $2, updated_at = NOW()
 WHERE id = $3
-RETURNING id, created_at, updated_at, email, is_chirpy_red;
+RETURNING id, created_at, updated_at, email;
 
--- name: UpdateMembership :exec
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled = FALSE, updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableTOTP :execrows
+UPDATE users
+SET totp_enabled = TRUE, totp_last_counter = $2, mfa_failed_attempts = 0, mfa_locked_until = NULL, updated_at = NOW()
+WHERE id = $1 AND totp_enabled = FALSE;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled = FALSE, totp_last_counter = 0, mfa_failed_attempts = 0, mfa_locked_until = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: AcceptTOTPCounter :execrows
+UPDATE users
+SET totp_last_counter = $2, mfa_failed_attempts = 0, mfa_locked_until = NULL
+WHERE id = $1 AND totp_last_counter < $2
+AND (mfa_locked_until IS NULL OR mfa_locked_until <= NOW());
+
+-- name: RecordMFAFailure :exec
+UPDATE users
+SET mfa_failed_attempts = CASE WHEN mfa_failed_attempts + 1 >= sqlc.arg(max_attempts)::integer THEN 0 ELSE mfa_failed_attempts + 1 END,
+    mfa_locked_until = CASE WHEN mfa_failed_attempts + 1 >= sqlc.arg(max_attempts)::integer THEN sqlc.arg(locked_until)::timestamp ELSE mfa_locked_until END
+WHERE id = $1;
+
+-- name: ResetMFAFailures :exec
+UPDATE users
+SET mfa_failed_attempts = 0
+WHERE id = $1;
+
+-- name: SuspendUser :exec
+UPDATE users
+SET suspended_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UnsuspendUser :exec
 UPDATE users
-SET is_chirpy_red = TRUE, updated_at = NOW()
-WHERE id = $1;
\ No newline at end of file
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: ShadowBanUser :exec
+UPDATE users
+SET shadow_banned_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UnshadowBanUser :exec
+UPDATE users
+SET shadow_banned_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: IsUserSuspended :one
+SELECT EXISTS (
+    SELECT 1 FROM users
+    WHERE id = $1 AND suspended_at IS NOT NULL
+);
+
+-- name: GetRestrictedUserIDs :many
+SELECT id FROM users
+WHERE suspended_at IS NOT NULL OR shadow_banned_at IS NOT NULL;
+
+-- name: ScheduleUserDeletion :exec
+UPDATE users
+SET deletion_scheduled_at = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetUserAccessState :one
+SELECT
+    EXISTS (
+        SELECT 1 FROM users
+        WHERE id = $1 AND suspended_at IS NOT NULL
+    ) AS suspended,
+    EXISTS (
+        SELECT 1 FROM users
+        WHERE id = $1 AND deletion_scheduled_at IS NOT NULL
+    ) AS deletion_scheduled;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET deletion_scheduled_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetUsersDueForDeletion :many
+SELECT id FROM users
+WHERE deletion_scheduled_at <= NOW();
+
+-- name: DeleteUserDueForDeletion :execrows
+DELETE FROM users
+WHERE id = $1 AND deletion_scheduled_at <= NOW();
+
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY created_at ASC
+LIMIT $1 OFFSET $2;
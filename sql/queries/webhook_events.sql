@@ -0,0 +1,42 @@
+-- name: RecordWebhookEvent :one
+INSERT INTO webhook_events (id, received_at, provider, event_id, event_type, payload, status, attempts)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, 'pending', 1
+)
+ON CONFLICT (provider, event_id) DO UPDATE
+SET attempts = webhook_events.attempts + 1
+RETURNING *;
+
+-- name: ClaimWebhookEvent :one
+UPDATE webhook_events
+SET status = 'processing', claimed_at = NOW()
+WHERE id = $1
+AND (status IN ('pending', 'failed') OR (status = 'processing' AND claimed_at < NOW() - INTERVAL '10 minutes'))
+RETURNING *;
+
+-- name: ReclaimWebhookEvent :one
+UPDATE webhook_events
+SET status = 'processing', claimed_at = NOW(), attempts = attempts + 1
+WHERE id = $1
+AND (status <> 'processing' OR claimed_at < NOW() - INTERVAL '10 minutes')
+RETURNING *;
+
+-- name: FinishWebhookEvent :exec
+UPDATE webhook_events
+SET status = $2, error = $3, processed_at = NOW()
+WHERE id = $1;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = $1;
+
+-- name: ListWebhookEvents :many
+SELECT * FROM webhook_events
+ORDER BY received_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ListWebhookEventsByStatus :many
+SELECT * FROM webhook_events
+WHERE status = $1
+ORDER BY received_at DESC
+LIMIT $2 OFFSET $3;
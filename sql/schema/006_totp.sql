@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN totp_secret TEXT,
+ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE recovery_codes(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT not null,
+    used_at TIMESTAMP,
+    UNIQUE(user_id, code_hash)
+);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN totp_enabled,
+DROP COLUMN totp_secret;
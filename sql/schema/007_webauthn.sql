@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE webauthn_credentials(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT not null,
+    credential_id BYTEA unique not null,
+    public_key BYTEA not null,
+    sign_count BIGINT not null,
+    last_used_at TIMESTAMP
+);
+
+CREATE TABLE webauthn_challenges(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    ceremony TEXT not null,
+    challenge BYTEA not null,
+    expires_at TIMESTAMP not null
+);
+
+-- +goose Down
+DROP TABLE webauthn_challenges;
+DROP TABLE webauthn_credentials;
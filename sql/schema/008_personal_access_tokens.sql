@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE personal_access_tokens(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT not null,
+    token_hash TEXT unique not null,
+    scopes TEXT[] not null,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE personal_access_tokens;
@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TABLE oauth_clients(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT not null,
+    secret_hash TEXT,
+    redirect_uris TEXT[] not null
+);
+
+CREATE TABLE oauth_authorization_codes(
+    code_hash TEXT primary key,
+    created_at TIMESTAMP not null,
+    client_id UUID not null REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT not null,
+    scopes TEXT[] not null,
+    code_challenge TEXT not null,
+    expires_at TIMESTAMP not null
+);
+
+CREATE TABLE oauth_revoked_access_tokens(
+    id UUID primary key,
+    expires_at TIMESTAMP not null
+);
+
+ALTER TABLE refresh_tokens
+ADD COLUMN client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE,
+ADD COLUMN scopes TEXT[];
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN scopes,
+DROP COLUMN client_id;
+
+DROP TABLE oauth_revoked_access_tokens;
+DROP TABLE oauth_authorization_codes;
+DROP TABLE oauth_clients;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE oidc_login_states(
+    state TEXT primary key,
+    created_at TIMESTAMP not null,
+    nonce TEXT not null,
+    code_verifier TEXT not null,
+    expires_at TIMESTAMP not null
+);
+
+CREATE TABLE user_identities(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    issuer TEXT not null,
+    subject TEXT not null,
+    email TEXT not null,
+    UNIQUE(issuer, subject)
+);
+
+-- +goose Down
+DROP TABLE user_identities;
+DROP TABLE oidc_login_states;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE webhook_events(
+    id UUID primary key,
+    received_at TIMESTAMP not null,
+    provider TEXT not null,
+    event_id TEXT not null,
+    event_type TEXT not null,
+    payload JSONB not null,
+    status TEXT not null DEFAULT 'pending',
+    error TEXT,
+    attempts INTEGER not null DEFAULT 1,
+    processed_at TIMESTAMP,
+    UNIQUE(provider, event_id)
+);
+
+ALTER TABLE users
+ADD COLUMN role TEXT not null DEFAULT 'user';
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN role;
+
+DROP TABLE webhook_events;
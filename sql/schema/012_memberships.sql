@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE memberships(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    plan TEXT not null,
+    status TEXT not null,
+    started_at TIMESTAMP not null,
+    ends_at TIMESTAMP,
+    cancelled_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX memberships_one_active_per_user
+ON memberships(user_id) WHERE status = 'active';
+
+INSERT INTO memberships (id, created_at, updated_at, user_id, plan, status, started_at)
+SELECT gen_random_uuid(), NOW(), NOW(), id, 'chirpy_red', 'active', updated_at
+FROM users
+WHERE is_chirpy_red;
+
+ALTER TABLE users
+DROP COLUMN is_chirpy_red;
+
+-- +goose Down
+ALTER TABLE users
+ADD COLUMN is_chirpy_red BOOLEAN NOT NULL
+DEFAULT FALSE;
+
+UPDATE users
+SET is_chirpy_red = TRUE
+WHERE id IN (
+    SELECT user_id FROM memberships
+    WHERE status = 'active' AND (ends_at IS NULL OR ends_at > NOW())
+);
+
+DROP TABLE memberships;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE plan_entitlements(
+    plan TEXT primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    max_chirp_length INTEGER not null,
+    max_attachments INTEGER not null,
+    edit_window_seconds INTEGER not null,
+    chirps_per_minute INTEGER not null,
+    can_schedule BOOLEAN not null
+);
+
+INSERT INTO plan_entitlements (plan, created_at, updated_at, max_chirp_length, max_attachments, edit_window_seconds, chirps_per_minute, can_schedule)
+VALUES
+    ('free', NOW(), NOW(), 140, 1, 0, 5, FALSE),
+    ('chirpy_red', NOW(), NOW(), 500, 4, 900, 30, TRUE);
+
+-- +goose Down
+DROP TABLE plan_entitlements;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE content_rules(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    kind TEXT not null,
+    pattern TEXT not null,
+    action TEXT not null
+);
+
+INSERT INTO content_rules (id, created_at, updated_at, kind, pattern, action)
+VALUES
+    (gen_random_uuid(), NOW(), NOW(), 'word', 'kerfuffle', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'word', 'sharbert', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'word', 'fornax', 'mask');
+
+-- +goose Down
+DROP TABLE content_rules;
@@ -0,0 +1,57 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN hidden_at TIMESTAMP;
+
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP;
+
+CREATE TABLE moderation_cases(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    chirp_id UUID not null REFERENCES chirps ON DELETE CASCADE,
+    status TEXT not null DEFAULT 'open',
+    claimed_by UUID REFERENCES users ON DELETE SET NULL,
+    claimed_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    resolution TEXT
+);
+
+-- A chirp has at most one case waiting for a decision; reports made after
+-- it's resolved open a new one.
+CREATE UNIQUE INDEX moderation_cases_unresolved_chirp
+ON moderation_cases (chirp_id)
+WHERE status <> 'resolved';
+
+CREATE TABLE reports(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    case_id UUID not null REFERENCES moderation_cases ON DELETE CASCADE,
+    chirp_id UUID not null REFERENCES chirps ON DELETE CASCADE,
+    reporter_id UUID not null REFERENCES users ON DELETE CASCADE,
+    reason TEXT not null,
+    details TEXT not null DEFAULT '',
+    UNIQUE(case_id, reporter_id)
+);
+
+CREATE TABLE moderation_actions(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    case_id UUID REFERENCES moderation_cases ON DELETE SET NULL,
+    moderator_id UUID REFERENCES users ON DELETE SET NULL,
+    action TEXT not null,
+    target_user_id UUID REFERENCES users ON DELETE SET NULL,
+    chirp_id UUID,
+    note TEXT not null DEFAULT ''
+);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+DROP TABLE moderation_cases;
+
+ALTER TABLE users
+DROP COLUMN suspended_at;
+
+ALTER TABLE chirps
+DROP COLUMN hidden_at;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE blocks(
+    blocker_id UUID not null REFERENCES users ON DELETE CASCADE,
+    blocked_id UUID not null REFERENCES users ON DELETE CASCADE,
+    created_at TIMESTAMP not null,
+    primary key (blocker_id, blocked_id)
+);
+
+CREATE INDEX blocks_blocked_id ON blocks (blocked_id);
+
+CREATE TABLE mutes(
+    muter_id UUID not null REFERENCES users ON DELETE CASCADE,
+    muted_id UUID not null REFERENCES users ON DELETE CASCADE,
+    created_at TIMESTAMP not null,
+    primary key (muter_id, muted_id)
+);
+
+-- +goose Down
+DROP TABLE mutes;
+DROP TABLE blocks;
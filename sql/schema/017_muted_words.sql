@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE muted_words(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users ON DELETE CASCADE,
+    phrase TEXT not null,
+    whole_word BOOLEAN not null DEFAULT FALSE,
+    action TEXT not null,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX muted_words_user_id ON muted_words (user_id);
+
+-- +goose Down
+DROP TABLE muted_words;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE exports(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT not null DEFAULT 'pending',
+    blob_key TEXT,
+    error TEXT,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+-- Users can only have one export in progress at a time.
+CREATE UNIQUE INDEX exports_in_progress_user_id
+ON exports (user_id)
+WHERE status IN ('pending', 'processing');
+
+-- +goose Down
+DROP TABLE exports;
@@ -0,0 +1,77 @@
+-- +goose Up
+CREATE TABLE actor_keys(
+    user_id UUID primary key REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP not null,
+    public_key_pem TEXT not null,
+    private_key_pem TEXT not null
+);
+
+-- Actors on other servers, cached so verifying their signatures doesn't
+-- need a fetch for every activity.
+CREATE TABLE remote_actors(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    uri TEXT not null UNIQUE,
+    preferred_username TEXT not null,
+    inbox TEXT not null,
+    shared_inbox TEXT not null,
+    key_id TEXT not null UNIQUE,
+    public_key_pem TEXT not null,
+    fetched_at TIMESTAMP not null
+);
+
+CREATE TABLE remote_followers(
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    actor_id UUID not null REFERENCES remote_actors(id) ON DELETE CASCADE,
+    created_at TIMESTAMP not null,
+    follow_uri TEXT not null,
+    primary key (user_id, actor_id)
+);
+
+CREATE INDEX remote_followers_actor_id ON remote_followers (actor_id);
+
+CREATE TABLE remote_notes(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    uri TEXT not null UNIQUE,
+    actor_id UUID not null REFERENCES remote_actors(id) ON DELETE CASCADE,
+    content TEXT not null,
+    in_reply_to TEXT,
+    published_at TIMESTAMP not null
+);
+
+-- The local users a remote note was addressed to.
+CREATE TABLE remote_note_recipients(
+    note_id UUID not null REFERENCES remote_notes(id) ON DELETE CASCADE,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    primary key (note_id, user_id)
+);
+
+CREATE INDEX remote_note_recipients_user_id ON remote_note_recipients (user_id);
+
+CREATE TABLE federation_deliveries(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    inbox TEXT not null,
+    activity JSONB not null,
+    status TEXT not null DEFAULT 'pending',
+    attempts INTEGER not null DEFAULT 0,
+    next_attempt_at TIMESTAMP not null,
+    error TEXT
+);
+
+CREATE INDEX federation_deliveries_pending
+ON federation_deliveries (next_attempt_at)
+WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE federation_deliveries;
+DROP TABLE remote_note_recipients;
+DROP TABLE remote_notes;
+DROP TABLE remote_followers;
+DROP TABLE remote_actors;
+DROP TABLE actor_keys;
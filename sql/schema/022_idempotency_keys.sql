@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE idempotency_keys(
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT not null,
+    created_at TIMESTAMP not null,
+    fingerprint TEXT not null,
+    -- The response is null while the original request is in progress.
+    response_status INTEGER,
+    response_headers JSONB not null DEFAULT '{}',
+    response_body BYTEA,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idempotency_keys_created_at ON idempotency_keys (created_at);
+
+-- +goose Down
+DROP TABLE idempotency_keys;
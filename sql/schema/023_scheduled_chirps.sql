@@ -0,0 +1,12 @@
+-- +goose Up
+-- A chirp with a publish_at is scheduled: only its author sees it until the
+-- publisher makes it live and clears publish_at.
+ALTER TABLE chirps
+ADD COLUMN publish_at TIMESTAMP;
+
+CREATE INDEX chirps_publish_at ON chirps (publish_at)
+WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN publish_at;
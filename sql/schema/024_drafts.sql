@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE drafts(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    updated_at TIMESTAMP not null,
+    body TEXT not null,
+    user_id UUID not null REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX drafts_user_id ON drafts (user_id, updated_at);
+
+-- +goose Down
+DROP TABLE drafts;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- A membership is either billed by the payment provider or granted by an
+-- operator. Reconciliation with the provider leaves granted ones alone.
+ALTER TABLE memberships
+ADD COLUMN source TEXT NOT NULL DEFAULT 'provider';
+
+-- +goose Down
+ALTER TABLE memberships
+DROP COLUMN source;
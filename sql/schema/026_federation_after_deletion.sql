@@ -0,0 +1,16 @@
+-- +goose Up
+-- Deleting an account tells other servers with a Delete activity, so the
+-- account's queued deliveries and the key that signs them have to outlive
+-- it. Keys of deleted users are dropped once nothing is left to deliver.
+ALTER TABLE federation_deliveries DROP CONSTRAINT federation_deliveries_user_id_fkey;
+ALTER TABLE actor_keys DROP CONSTRAINT actor_keys_user_id_fkey;
+
+-- +goose Down
+DELETE FROM federation_deliveries
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE users.id = federation_deliveries.user_id);
+DELETE FROM actor_keys
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE users.id = actor_keys.user_id);
+ALTER TABLE federation_deliveries
+ADD CONSTRAINT federation_deliveries_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE actor_keys
+ADD CONSTRAINT actor_keys_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
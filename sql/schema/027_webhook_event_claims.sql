@@ -0,0 +1,9 @@
+-- +goose Up
+-- An event is claimed before it's processed, so concurrent deliveries of
+-- the same event don't both apply it. Claims of a crashed process expire.
+ALTER TABLE webhook_events
+ADD COLUMN claimed_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE webhook_events
+DROP COLUMN claimed_at;
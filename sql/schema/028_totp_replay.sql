@@ -0,0 +1,14 @@
+-- +goose Up
+-- The time step of the last accepted TOTP code, so that a code can't be
+-- used twice, and the run of wrong second factors that locks them for a
+-- while.
+ALTER TABLE users
+ADD COLUMN totp_last_counter BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN mfa_failed_attempts INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN mfa_locked_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN totp_last_counter,
+DROP COLUMN mfa_failed_attempts,
+DROP COLUMN mfa_locked_until;
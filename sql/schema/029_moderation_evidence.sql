@@ -0,0 +1,40 @@
+-- +goose Up
+-- Cases and reports outlive the chirp they're about, so an author deleting
+-- a reported chirp doesn't make the case go away. The case keeps the chirp
+-- as it was first reported, and who wrote it.
+ALTER TABLE moderation_cases
+ADD COLUMN author_id UUID REFERENCES users ON DELETE SET NULL,
+ADD COLUMN chirp_body TEXT not null DEFAULT '';
+
+UPDATE moderation_cases
+SET author_id = chirps.user_id, chirp_body = chirps.body
+FROM chirps
+WHERE chirps.id = moderation_cases.chirp_id;
+
+ALTER TABLE moderation_cases
+ALTER COLUMN chirp_id DROP NOT NULL,
+DROP CONSTRAINT moderation_cases_chirp_id_fkey,
+ADD CONSTRAINT moderation_cases_chirp_id_fkey FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE SET NULL;
+
+ALTER TABLE reports
+ALTER COLUMN chirp_id DROP NOT NULL,
+DROP CONSTRAINT reports_chirp_id_fkey,
+ADD CONSTRAINT reports_chirp_id_fkey FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE SET NULL;
+
+-- +goose Down
+DELETE FROM reports
+WHERE chirp_id IS NULL;
+DELETE FROM moderation_cases
+WHERE chirp_id IS NULL;
+
+ALTER TABLE reports
+ALTER COLUMN chirp_id SET NOT NULL,
+DROP CONSTRAINT reports_chirp_id_fkey,
+ADD CONSTRAINT reports_chirp_id_fkey FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE;
+
+ALTER TABLE moderation_cases
+ALTER COLUMN chirp_id SET NOT NULL,
+DROP CONSTRAINT moderation_cases_chirp_id_fkey,
+ADD CONSTRAINT moderation_cases_chirp_id_fkey FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+DROP COLUMN chirp_body,
+DROP COLUMN author_id;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- A warning is what a user sees when a moderator resolves a case about
+-- their chirp with "warn". It keeps the chirp's body, since the chirp may be
+-- deleted later.
+CREATE TABLE warnings(
+    id UUID primary key,
+    created_at TIMESTAMP not null,
+    user_id UUID not null REFERENCES users ON DELETE CASCADE,
+    case_id UUID REFERENCES moderation_cases ON DELETE SET NULL,
+    chirp_id UUID REFERENCES chirps ON DELETE SET NULL,
+    chirp_body TEXT not null,
+    note TEXT not null DEFAULT ''
+);
+
+CREATE INDEX warnings_user_id ON warnings (user_id, created_at);
+
+-- +goose Down
+DROP TABLE warnings;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Chirps have no attachments, so there was nothing for this limit to apply
+-- to.
+ALTER TABLE plan_entitlements
+DROP COLUMN max_attachments;
+
+-- +goose Down
+ALTER TABLE plan_entitlements
+ADD COLUMN max_attachments INTEGER not null DEFAULT 0;